@@ -59,6 +59,20 @@ func TestDefaults(t *testing.T) {
 	}
 }
 
+func TestACMERequiresStatusUpdate(t *testing.T) {
+	resetForTesting(func() { t.Fatal("bad parse") })
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"cmd", "--default-backend-service", "namespace/test", "--http-port", "0", "--https-port", "0",
+		"--acme-directory-url", "https://acme.example.com/directory", "--update-status=false"}
+
+	_, _, err := parseFlags()
+	if err == nil {
+		t.Fatal("expected an error using ACME without the status update")
+	}
+}
+
 func TestSetupSSLProxy(t *testing.T) {
 	// TODO
 }
@@ -31,7 +31,9 @@ import (
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
 	"k8s.io/ingress-nginx/internal/ingress/controller"
 	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
+	"k8s.io/ingress-nginx/internal/k8s"
 	ing_net "k8s.io/ingress-nginx/internal/net"
+	"k8s.io/ingress-nginx/internal/net/ssl"
 )
 
 func parseFlags() (bool, *controller.Configuration, error) {
@@ -125,6 +127,10 @@ func parseFlags() (bool, *controller.Configuration, error) {
 		If the certificate contain issues chain issues is not possible to enable OCSP.
 		Default is true.`)
 
+		enableOCSPStapling = flags.Bool("enable-ocsp-stapling", true,
+			`Defines if the nginx ingress controller should fetch the OCSP responses of the SSL certificates
+		and staple them in the TLS handshakes. Default is true.`)
+
 		syncRateLimit = flags.Float32("sync-rate-limit", 0.3,
 			`Define the sync frequency upper limit`)
 
@@ -134,6 +140,51 @@ func parseFlags() (bool, *controller.Configuration, error) {
 
 		dynamicConfigurationEnabled = flags.Bool("enable-dynamic-configuration", false,
 			`When enabled controller will try to avoid Nginx reloads as much as possible by using Lua. Disabled by default.`)
+
+		sslExpireWarningDays = flags.Int("ssl-expire-warning-days", 10,
+			`Number of days before the expiration of a SSL certificate used in Ingress rules to report it
+		using a Warning event in the Ingress and the Secret`)
+
+		acmeDirectoryURL = flags.String("acme-directory-url", "",
+			`URL of the directory of an ACME server (RFC 8555), e.g. https://acme-v02.api.letsencrypt.org/directory.
+		When set, the certificates of Ingresses with the annotation tls-acme are issued using HTTP-01 challenges.
+		Requires --update-status: only the elected leader requests certificates.`)
+
+		acmeEmail = flags.String("acme-email", "",
+			`Contact email address of the ACME account (optional)`)
+
+		acmeAccountSecret = flags.String("acme-account-secret", "",
+			`Secret used to store the private key of the ACME account. Takes the form namespace/name.
+		The secret is created if it does not exist. Without it a new account is registered on each start.`)
+
+		acmeRenewBefore = flags.Duration("acme-renew-before", 30*24*time.Hour,
+			`Renew ACME certificates this long before they expire. Default is 30 days`)
+
+		sslIntermediateBundle = flags.String("ssl-intermediate-bundle", "",
+			`ConfigMap or Secret with PEM encoded intermediate CA certificates used to complete the chain of
+		the SSL certificates before fetching the missing certificates using the AIA extension (--enable-ssl-chain-completion).
+		Takes the form namespace/name. A ConfigMap with the name takes precedence over a Secret.`)
+
+		localCASecret = flags.String("local-ca-secret", "",
+			`Secret with the certificate authority (tls.crt and tls.key) used to issue certificates for the hosts
+		in the TLS section of Ingress rules without a usable certificate. Takes the form namespace/name.
+		A new certificate authority is created in the secret if it does not exist.`)
+
+		sslSessionTicketKeysSecret = flags.String("ssl-session-ticket-keys-secret", "",
+			`Secret used to store the keys that encrypt and decrypt TLS session tickets, shared by all the replicas
+		of the controller. Takes the form namespace/name. The secret is created if it does not exist and
+		takes precedence over the ssl-session-ticket-key setting in the configuration ConfigMap.`)
+
+		sslDHParamSecret = flags.String("ssl-dh-param-secret", "",
+			`Secret used to store the DH parameters generated by the controller, shared by all the replicas.
+		Takes the form namespace/name. The parameters are generated in the background when the secret does not exist
+		or does not contain valid parameters. The ssl-dh-param setting in the configuration ConfigMap takes precedence.`)
+
+		sslDHParamSize = flags.Int("ssl-dh-param-size", 2048,
+			`Size in bits of the DH parameters generated in --ssl-dh-param-secret`)
+
+		sslSessionTicketKeysRotation = flags.Duration("ssl-session-ticket-keys-rotation", 12*time.Hour,
+			`Interval between rotations of the keys in --ssl-session-ticket-keys-secret. Default is 12 hours`)
 	)
 
 	flag.Set("logtostderr", "true")
@@ -190,34 +241,91 @@ func parseFlags() (bool, *controller.Configuration, error) {
 		return false, nil, fmt.Errorf("Port %v is already in use. Please check the flag --ssl-passtrough-proxy-port", *sslProxyPort)
 	}
 
+	if *sslIntermediateBundle != "" {
+		_, _, err := k8s.ParseNameNS(*sslIntermediateBundle)
+		if err != nil {
+			return false, nil, fmt.Errorf("Please specify a valid ConfigMap or Secret for --ssl-intermediate-bundle: %v", err)
+		}
+	}
+
+	if *localCASecret != "" {
+		_, _, err := k8s.ParseNameNS(*localCASecret)
+		if err != nil {
+			return false, nil, fmt.Errorf("Please specify a valid secret for --local-ca-secret: %v", err)
+		}
+	}
+
+	if *sslDHParamSecret != "" {
+		_, _, err := k8s.ParseNameNS(*sslDHParamSecret)
+		if err != nil {
+			return false, nil, fmt.Errorf("Please specify a valid secret for --ssl-dh-param-secret: %v", err)
+		}
+
+		if *sslDHParamSize < ssl.MinDHParamSize || *sslDHParamSize > 8192 {
+			return false, nil, fmt.Errorf("Please specify a size between %v and 8192 bits for --ssl-dh-param-size", ssl.MinDHParamSize)
+		}
+	}
+
+	if *sslSessionTicketKeysSecret != "" {
+		_, _, err := k8s.ParseNameNS(*sslSessionTicketKeysSecret)
+		if err != nil {
+			return false, nil, fmt.Errorf("Please specify a valid secret for --ssl-session-ticket-keys-secret: %v", err)
+		}
+
+		if *sslSessionTicketKeysRotation <= 0 {
+			return false, nil, fmt.Errorf("Please specify a positive interval for --ssl-session-ticket-keys-rotation")
+		}
+	}
+
+	if *acmeRenewBefore <= 0 {
+		return false, nil, fmt.Errorf("Please specify a positive duration for --acme-renew-before")
+	}
+
+	// the leader election of the status update avoids orders from every replica
+	if *acmeDirectoryURL != "" && !*updateStatus {
+		return false, nil, fmt.Errorf("Flag --acme-directory-url requires --update-status to elect the replica that requests the certificates")
+	}
+
 	if !*enableSSLChainCompletion {
 		glog.Warningf("Check of SSL certificate chain is disabled (--enable-ssl-chain-completion=false)")
 	}
 
 	config := &controller.Configuration{
-		APIServerHost:               *apiserverHost,
-		KubeConfigFile:              *kubeConfigFile,
-		UpdateStatus:                *updateStatus,
-		ElectionID:                  *electionID,
-		EnableProfiling:             *profiling,
-		EnableSSLPassthrough:        *enableSSLPassthrough,
-		EnableSSLChainCompletion:    *enableSSLChainCompletion,
-		ResyncPeriod:                *resyncPeriod,
-		DefaultService:              *defaultSvc,
-		Namespace:                   *watchNamespace,
-		ConfigMapName:               *configMap,
-		TCPConfigMapName:            *tcpConfigMapName,
-		UDPConfigMapName:            *udpConfigMapName,
-		DefaultSSLCertificate:       *defSSLCertificate,
-		DefaultHealthzURL:           *defHealthzURL,
-		PublishService:              *publishSvc,
-		PublishStatusAddress:        *publishStatusAddress,
-		ForceNamespaceIsolation:     *forceIsolation,
-		UpdateStatusOnShutdown:      *updateStatusOnShutdown,
-		SortBackends:                *sortBackends,
-		UseNodeInternalIP:           *useNodeInternalIP,
-		SyncRateLimit:               *syncRateLimit,
-		DynamicConfigurationEnabled: *dynamicConfigurationEnabled,
+		APIServerHost:                *apiserverHost,
+		KubeConfigFile:               *kubeConfigFile,
+		UpdateStatus:                 *updateStatus,
+		ElectionID:                   *electionID,
+		EnableProfiling:              *profiling,
+		EnableSSLPassthrough:         *enableSSLPassthrough,
+		EnableSSLChainCompletion:     *enableSSLChainCompletion,
+		EnableOCSPStapling:           *enableOCSPStapling,
+		ResyncPeriod:                 *resyncPeriod,
+		DefaultService:               *defaultSvc,
+		Namespace:                    *watchNamespace,
+		ConfigMapName:                *configMap,
+		TCPConfigMapName:             *tcpConfigMapName,
+		UDPConfigMapName:             *udpConfigMapName,
+		DefaultSSLCertificate:        *defSSLCertificate,
+		DefaultHealthzURL:            *defHealthzURL,
+		PublishService:               *publishSvc,
+		PublishStatusAddress:         *publishStatusAddress,
+		ForceNamespaceIsolation:      *forceIsolation,
+		UpdateStatusOnShutdown:       *updateStatusOnShutdown,
+		SortBackends:                 *sortBackends,
+		UseNodeInternalIP:            *useNodeInternalIP,
+		SyncRateLimit:                *syncRateLimit,
+		DynamicConfigurationEnabled:  *dynamicConfigurationEnabled,
+		ACMEDirectoryURL:             *acmeDirectoryURL,
+		ACMEEmail:                    *acmeEmail,
+		ACMEAccountSecret:            *acmeAccountSecret,
+		ACMERenewBefore:              *acmeRenewBefore,
+		LocalCASecret:                *localCASecret,
+		SSLSessionTicketKeysSecret:   *sslSessionTicketKeysSecret,
+		SSLSessionTicketKeysRotation: *sslSessionTicketKeysRotation,
+		SSLDHParamSecret:             *sslDHParamSecret,
+		SSLDHParamSize:               *sslDHParamSize,
+		SSLIntermediateBundle:        *sslIntermediateBundle,
+		SSLExpireWarningDays:         *sslExpireWarningDays,
 		ListenPorts: &ngx_config.ListenPorts{
 			Default:  *defServerPort,
 			Health:   *healthzPort,
@@ -41,6 +41,7 @@ import (
 	"k8s.io/ingress-nginx/internal/file"
 	"k8s.io/ingress-nginx/internal/ingress/controller"
 	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/internal/net/acme"
 	"k8s.io/ingress-nginx/internal/net/ssl"
 	"k8s.io/ingress-nginx/version"
 )
@@ -260,12 +261,28 @@ func registerHandlers(enableProfiling bool, port int, ic *controller.NGINXContro
 
 	mux.Handle("/metrics", promhttp.Handler())
 
+	if h := ic.ACMEChallengeHandler(); h != nil {
+		mux.Handle(acme.ChallengePath, h)
+	}
+
 	mux.HandleFunc("/build", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		b, _ := json.Marshal(version.String())
 		w.Write(b)
 	})
 
+	mux.HandleFunc("/debug/certs", func(w http.ResponseWriter, r *http.Request) {
+		b, err := json.MarshalIndent(ic.CertificateStatus(), "", "  ")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(b)
+	})
+
 	mux.HandleFunc("/stop", func(w http.ResponseWriter, r *http.Request) {
 		err := syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
 		if err != nil {
@@ -17,6 +17,7 @@ limitations under the License.
 package annotations
 
 import (
+	"strings"
 	"testing"
 
 	apiv1 "k8s.io/api/core/v1"
@@ -317,7 +318,7 @@ func TestCors(t *testing.T) {
 			t.Errorf("Returned %v but expected %v for Cors Methods", r.CorsAllowMethods, foo.methods)
 		}
 
-		if r.CorsAllowOrigin != foo.origin {
+		if strings.Join(r.CorsAllowOrigin, ",") != foo.origin {
 			t.Errorf("Returned %v but expected %v for Cors Methods", r.CorsAllowOrigin, foo.origin)
 		}
 
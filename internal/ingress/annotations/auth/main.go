@@ -17,11 +17,15 @@ limitations under the License.
 package auth
 
 import (
+	"bytes"
+	"encoding/base64"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
 	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/pkg/errors"
 	api "k8s.io/api/core/v1"
@@ -33,8 +37,33 @@ import (
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
+const (
+	// AuthFile is the secret type with an htpasswd file in the key auth
+	AuthFile = "auth-file"
+	// AuthMap is the secret type with a key per user and the hash of the
+	// password as value
+	AuthMap = "auth-map"
+)
+
 var (
 	authTypeRegex = regexp.MustCompile(`basic|digest`)
+	// basicHashRegexps contains the password hashes supported in basic
+	// authentication: MD5 (apr1 and crypt), SHA-256 and SHA-512 crypt.
+	// Unsalted SHA-1 hashes ({SHA}) are rejected
+	basicHashRegexps = []*regexp.Regexp{
+		regexp.MustCompile(`^\$apr1\$[./0-9A-Za-z]{1,8}\$[./0-9A-Za-z]{22}$`),
+		regexp.MustCompile(`^\$1\$[./0-9A-Za-z]{1,8}\$[./0-9A-Za-z]{22}$`),
+		regexp.MustCompile(`^\$5\$(rounds=\d+\$)?[./0-9A-Za-z]{1,16}\$[./0-9A-Za-z]{43}$`),
+		regexp.MustCompile(`^\$6\$(rounds=\d+\$)?[./0-9A-Za-z]{1,16}\$[./0-9A-Za-z]{86}$`),
+	}
+	// desHashRegex matches the DES crypt hashes. nginx supports them but
+	// the salt has only two characters and only the first eight characters
+	// of the password are used
+	desHashRegex = regexp.MustCompile(`^[./0-9A-Za-z]{13}$`)
+	// errInvalidFormat is returned for the entries without a valid user
+	errInvalidFormat = errors.New("invalid format")
+	// digestHashRegex matches the MD5 hash of htdigest files
+	digestHashRegex = regexp.MustCompile(`^[0-9a-fA-F]{32}$`)
 	// AuthDirectory default directory used to store files
 	// to authenticate request
 	AuthDirectory = "/etc/ingress-controller/auth"
@@ -48,6 +77,12 @@ type Config struct {
 	Secured bool   `json:"secured"`
 	FileSHA string `json:"fileSha"`
 	Secret  string `json:"secret"`
+	// InvalidEntries contains the rejected entries of the secret (only the
+	// user and the reason)
+	InvalidEntries []string `json:"invalidEntries,omitempty"`
+	// WeakEntries contains the entries of the secret with weak password
+	// hashes (only the user and the hash type)
+	WeakEntries []string `json:"weakEntries,omitempty"`
 }
 
 // Equal tests for equality between two Config types
@@ -114,6 +149,14 @@ func (a auth) Parse(ing *extensions.Ingress) (interface{}, error) {
 		return nil, ing_errors.NewLocationDenied("invalid authentication type")
 	}
 
+	secretType, err := parser.GetStringAnnotation("auth-secret-type", ing)
+	if err != nil {
+		secretType = AuthFile
+	}
+	if secretType != AuthFile && secretType != AuthMap {
+		return nil, ing_errors.NewLocationDenied("invalid authentication secret type")
+	}
+
 	s, err := parser.GetStringAnnotation("auth-secret", ing)
 	if err != nil {
 		return nil, ing_errors.LocationDenied{
@@ -132,38 +175,153 @@ func (a auth) Parse(ing *extensions.Ingress) (interface{}, error) {
 	realm, _ := parser.GetStringAnnotation("auth-realm", ing)
 
 	passFile := fmt.Sprintf("%v/%v-%v.passwd", a.authDirectory, ing.GetNamespace(), ing.GetName())
-	err = dumpSecret(passFile, secret)
+	invalid, weak, err := dumpSecret(passFile, secret, secretType, at)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Config{
-		Type:    at,
-		Realm:   realm,
-		File:    passFile,
-		Secured: true,
-		FileSHA: file.SHA1(passFile),
-		Secret:  name,
+		Type:           at,
+		Realm:          realm,
+		File:           passFile,
+		Secured:        true,
+		FileSHA:        file.SHA1(passFile),
+		Secret:         name,
+		InvalidEntries: invalid,
+		WeakEntries:    weak,
 	}, nil
 }
 
-// dumpSecret dumps the content of a secret into a file
-// in the expected format for the specified authorization
-func dumpSecret(filename string, secret *api.Secret) error {
+// secretEntries returns the <user>:<hash> entries of a secret. Secrets of
+// type auth-file contain an htpasswd file in the key auth and secrets of
+// type auth-map a key per user with the hash as value. Empty lines and
+// comments are ignored.
+func secretEntries(secret *api.Secret, secretType string) ([]string, error) {
+	entries := []string{}
+
+	if secretType == AuthMap {
+		users := []string{}
+		for user := range secret.Data {
+			users = append(users, user)
+		}
+		sort.Strings(users)
+
+		for _, user := range users {
+			entries = append(entries, fmt.Sprintf("%v:%v", user, strings.TrimSpace(string(secret.Data[user]))))
+		}
+		return entries, nil
+	}
+
 	val, ok := secret.Data["auth"]
 	if !ok {
-		return ing_errors.LocationDenied{
+		return nil, ing_errors.LocationDenied{
 			Reason: errors.Errorf("the secret %v does not contain a key with value auth", secret.Name),
 		}
 	}
 
+	for _, line := range strings.Split(string(val), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+
+	return entries, nil
+}
+
+// validateEntry checks the format and the hash algorithm of an entry:
+// <user>:<hash> for basic and <user>:<realm>:<hash> for digest authentication.
+// Returns the type of the hash when the entry is valid but the hash is weak
+func validateEntry(authType, entry string) (string, error) {
+	parts := strings.SplitN(entry, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || strings.ContainsAny(parts[0], " \t") {
+		return "", errInvalidFormat
+	}
+
+	if authType == "digest" {
+		i := strings.LastIndex(parts[1], ":")
+		if i == -1 || !digestHashRegex.MatchString(parts[1][i+1:]) {
+			return "", fmt.Errorf("invalid digest format")
+		}
+		return "", nil
+	}
+
+	hash := parts[1]
+	for _, re := range basicHashRegexps {
+		if re.MatchString(hash) {
+			return "", nil
+		}
+	}
+
+	if strings.HasPrefix(hash, "{SSHA}") {
+		b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(hash, "{SSHA}"))
+		// SHA1 hash (20 bytes) followed by the salt
+		if err == nil && len(b) > 20 {
+			return "", nil
+		}
+	}
+
+	if desHashRegex.MatchString(hash) {
+		return "DES crypt", nil
+	}
+	if strings.HasPrefix(hash, "{PLAIN}") && len(hash) > len("{PLAIN}") {
+		return "plain text", nil
+	}
+
+	return "", fmt.Errorf("unsupported password hash")
+}
+
+// dumpSecret dumps the entries of a secret into a file in the expected
+// format for the specified authorization. The hash of the rejected entries
+// is removed, so nginx fails the requests of these users instead of
+// validating an unsupported hash. Returns the rejected entries (user and
+// reason) and the entries with weak hashes (user and hash type). Secrets
+// without entries are rejected.
+func dumpSecret(filename string, secret *api.Secret, secretType, authType string) ([]string, []string, error) {
+	entries, err := secretEntries(secret, secretType)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	invalid := []string{}
+	weak := []string{}
+	content := bytes.Buffer{}
+	for i, entry := range entries {
+		user := strings.SplitN(entry, ":", 2)[0]
+		hashType, err := validateEntry(authType, entry)
+		if err == errInvalidFormat {
+			if user == entry {
+				user = fmt.Sprintf("entry %v", i+1)
+			}
+			invalid = append(invalid, fmt.Sprintf("%v: %v", user, err))
+			continue
+		}
+		if err != nil {
+			invalid = append(invalid, fmt.Sprintf("%v: %v", user, err))
+			entry = fmt.Sprintf("%v:", user)
+		}
+		if hashType != "" {
+			weak = append(weak, fmt.Sprintf("%v: %v", user, hashType))
+		}
+
+		content.WriteString(entry)
+		content.WriteString("\n")
+	}
+
+	if content.Len() == 0 {
+		return invalid, weak, ing_errors.LocationDenied{
+			Reason: errors.Errorf("the secret %v does not contain users", secret.Name),
+		}
+	}
+
 	// TODO: check permissions required
-	err := ioutil.WriteFile(filename, val, 0777)
+	err = ioutil.WriteFile(filename, content.Bytes(), 0777)
 	if err != nil {
-		return ing_errors.LocationDenied{
+		return nil, nil, ing_errors.LocationDenied{
 			Reason: errors.Wrap(err, "unexpected error creating password file"),
 		}
 	}
 
-	return nil
+	return invalid, weak, nil
 }
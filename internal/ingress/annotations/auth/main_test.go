@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"reflect"
 	"testing"
 	"time"
 
@@ -127,6 +128,24 @@ func TestIngressAuth(t *testing.T) {
 	}
 }
 
+func TestIngressAuthInvalidSecretType(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("auth-type")] = "basic"
+	data[parser.GetAnnotationWithPrefix("auth-secret")] = "demo-secret"
+	data[parser.GetAnnotationWithPrefix("auth-secret-type")] = "invalid"
+	ing.SetAnnotations(data)
+
+	_, dir, _ := dummySecretContent(t)
+	defer os.RemoveAll(dir)
+
+	_, err := NewParser(dir, mockSecret{}).Parse(ing)
+	if err == nil {
+		t.Errorf("expected an error with invalid secret type")
+	}
+}
+
 func TestIngressAuthWithoutSecret(t *testing.T) {
 	ing := buildIngress()
 
@@ -167,14 +186,136 @@ func TestDumpSecret(t *testing.T) {
 	sd := s.Data
 	s.Data = nil
 
-	err := dumpSecret(tmpfile, s)
+	_, _, err := dumpSecret(tmpfile, s, AuthFile, "basic")
 	if err == nil {
 		t.Errorf("Expected error with secret without auth")
 	}
 
 	s.Data = sd
-	err = dumpSecret(tmpfile, s)
+	_, _, err = dumpSecret(tmpfile, s, AuthFile, "basic")
 	if err != nil {
 		t.Errorf("Unexpected error creating htpasswd file %v: %v", tmpfile, err)
 	}
 }
+
+func TestDumpSecretInvalidEntries(t *testing.T) {
+	tmpfile, dir, s := dummySecretContent(t)
+	defer os.RemoveAll(dir)
+
+	s.Data = map[string][]byte{"auth": []byte(`# comment
+foo:$apr1$OFG3Xybp$ckL0FHDAkoXYIlH9.cysT0
+
+bar:{SHA}Ys23Ag/5IOWqZCw9QGaVDdHwH00=
+baz:{PLAIN}password
+des:rl0Ac1VBDvBBE
+invalid
+`)}
+
+	invalid, weak, err := dumpSecret(tmpfile, s, AuthFile, "basic")
+	if err != nil {
+		t.Fatalf("Unexpected error creating htpasswd file %v: %v", tmpfile, err)
+	}
+
+	expected := []string{
+		"bar: unsupported password hash",
+		"entry 5: invalid format",
+	}
+	if !reflect.DeepEqual(invalid, expected) {
+		t.Errorf("Expected %v as invalid entries but returned %v", expected, invalid)
+	}
+
+	expected = []string{
+		"baz: plain text",
+		"des: DES crypt",
+	}
+	if !reflect.DeepEqual(weak, expected) {
+		t.Errorf("Expected %v as weak entries but returned %v", expected, weak)
+	}
+
+	content, err := ioutil.ReadFile(tmpfile)
+	if err != nil {
+		t.Fatalf("Unexpected error reading htpasswd file %v: %v", tmpfile, err)
+	}
+	// the hash of the rejected entries is removed
+	if string(content) != "foo:$apr1$OFG3Xybp$ckL0FHDAkoXYIlH9.cysT0\nbar:\nbaz:{PLAIN}password\ndes:rl0Ac1VBDvBBE\n" {
+		t.Errorf("Expected the valid entries and the users of the rejected entries in the htpasswd file but returned %q", content)
+	}
+
+	s.Data = map[string][]byte{"auth": []byte("# comment\ninvalid\n")}
+	_, _, err = dumpSecret(tmpfile, s, AuthFile, "basic")
+	if err == nil {
+		t.Errorf("Expected error with secret without users")
+	}
+}
+
+func TestDumpSecretAuthMap(t *testing.T) {
+	tmpfile, dir, s := dummySecretContent(t)
+	defer os.RemoveAll(dir)
+
+	s.Data = map[string][]byte{
+		"foo": []byte("$apr1$OFG3Xybp$ckL0FHDAkoXYIlH9.cysT0\n"),
+		"bar": []byte("$6$rounds=5000$saltsalt$aGCpqCxvL1kn1OGbWDyRMgyoyNCLoe7FMfQd1GmqhO6jVUPcmIsHXqK6mFSmOlF4I9iTnN8wlZUXWN0NS/ImZ."),
+		"baz": []byte("password"),
+	}
+
+	invalid, _, err := dumpSecret(tmpfile, s, AuthMap, "basic")
+	if err != nil {
+		t.Fatalf("Unexpected error creating htpasswd file %v: %v", tmpfile, err)
+	}
+	if len(invalid) != 1 || invalid[0] != "baz: unsupported password hash" {
+		t.Errorf("Expected baz as invalid entry but returned %v", invalid)
+	}
+
+	content, _ := ioutil.ReadFile(tmpfile)
+	expected := "bar:$6$rounds=5000$saltsalt$aGCpqCxvL1kn1OGbWDyRMgyoyNCLoe7FMfQd1GmqhO6jVUPcmIsHXqK6mFSmOlF4I9iTnN8wlZUXWN0NS/ImZ.\nbaz:\nfoo:$apr1$OFG3Xybp$ckL0FHDAkoXYIlH9.cysT0\n"
+	if string(content) != expected {
+		t.Errorf("Expected \n%v\nbut returned \n%v", expected, string(content))
+	}
+}
+
+func TestValidateEntry(t *testing.T) {
+	valid := map[string]string{
+		"foo:$apr1$OFG3Xybp$ckL0FHDAkoXYIlH9.cysT0":                   "basic",
+		"foo:$1$saltsalt$qjXMvbEw8oaL.CzflDugX/":                      "basic",
+		"foo:$5$saltsalt$JxGXP4.cbTbDjbTOVEUXnFHYXQeLsnQBfFOF8ZP7xF3": "basic",
+		"foo:{SSHA}sWbrqUSXxc2Vn8EnwAnD+7AGFSlzYWx0":                  "basic",
+		"foo:realm:939e7578ed9e3c518a452acee763bce9":                  "digest",
+	}
+	for entry, authType := range valid {
+		hashType, err := validateEntry(authType, entry)
+		if err != nil {
+			t.Errorf("Unexpected error validating %v: %v", entry, err)
+		}
+		if hashType != "" {
+			t.Errorf("Unexpected weak hash %v validating %v", hashType, entry)
+		}
+	}
+
+	weak := map[string]string{
+		"foo:rl0Ac1VBDvBBE":   "DES crypt",
+		"foo:{PLAIN}password": "plain text",
+	}
+	for entry, expected := range weak {
+		hashType, err := validateEntry("basic", entry)
+		if err != nil {
+			t.Errorf("Unexpected error validating %v: %v", entry, err)
+		}
+		if hashType != expected {
+			t.Errorf("Expected %v validating %v but returned %v", expected, entry, hashType)
+		}
+	}
+
+	invalid := map[string]string{
+		"foo:{SHA}Ys23Ag/5IOWqZCw9QGaVDdHwH00=":            "basic",
+		"foo:$2y$05$c4WoMPo3SXsafkva.HHa6uXQZWr7oboPiC2bT": "basic",
+		"foo:password":                           "basic",
+		":$apr1$OFG3Xybp$ckL0FHDAkoXYIlH9.cysT0": "basic",
+		"foo:939e7578ed9e3c518a452acee763bce9":   "digest",
+		"foo:realm:password":                     "digest",
+	}
+	for entry, authType := range invalid {
+		if _, err := validateEntry(authType, entry); err == nil {
+			t.Errorf("Expected error validating %v", entry)
+		}
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package authreq
 
 import (
+	"fmt"
 	"net/url"
 	"regexp"
 	"strings"
@@ -37,6 +38,12 @@ type Config struct {
 	Method          string   `json:"method"`
 	ResponseHeaders []string `json:"responseHeaders,omitempty"`
 	RequestRedirect string   `json:"requestRedirect"`
+	// AuthCacheKey is the nginx expression used as key to cache the
+	// responses of the authentication service. Empty disables the cache
+	AuthCacheKey string `json:"authCacheKey"`
+	// AuthCacheDuration contains the proxy_cache_valid entries of the cache,
+	// i.e. "200 202 10m"
+	AuthCacheDuration []string `json:"authCacheDuration"`
 }
 
 // Equal tests for equality between two Config types
@@ -74,13 +81,33 @@ func (e1 *Config) Equal(e2 *Config) bool {
 	if e1.RequestRedirect != e2.RequestRedirect {
 		return false
 	}
+	if e1.AuthCacheKey != e2.AuthCacheKey {
+		return false
+	}
+	if len(e1.AuthCacheDuration) != len(e2.AuthCacheDuration) {
+		return false
+	}
+	for i := range e1.AuthCacheDuration {
+		if e1.AuthCacheDuration[i] != e2.AuthCacheDuration[i] {
+			return false
+		}
+	}
 
 	return true
 }
 
+// DefaultCacheDuration is the caching time of the responses of the
+// authentication service when the annotation auth-cache-duration is not set
+const DefaultCacheDuration = "200 202 401 5m"
+
 var (
 	methods      = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "CONNECT", "OPTIONS", "TRACE"}
 	headerRegexp = regexp.MustCompile(`^[a-zA-Z\d\-_]+$`)
+	// cacheKeyRegexp rejects characters that would allow to inject
+	// directives in the quoted proxy_cache_key value
+	cacheKeyRegexp = regexp.MustCompile(`^[^"';{}\\\s]+$`)
+	// cacheDurationRegexp matches the arguments of proxy_cache_valid
+	cacheDurationRegexp = regexp.MustCompile(`^(?:(?:[1-5]\d{2}|any)\s+)*\d+(?:ms|s|m|h|d|w|M|y)?$`)
 )
 
 func validMethod(method string) bool {
@@ -100,6 +127,24 @@ func validHeader(header string) bool {
 	return headerRegexp.Match([]byte(header))
 }
 
+// parseCacheDuration splits a comma separated list of proxy_cache_valid
+// arguments, i.e. "200 202 10m, 401 30s"
+func parseCacheDuration(input string) ([]string, error) {
+	durations := []string{}
+	for _, d := range strings.Split(input, ",") {
+		d = strings.Join(strings.Fields(d), " ")
+		if d == "" {
+			continue
+		}
+		if !cacheDurationRegexp.MatchString(d) {
+			return nil, ing_errors.NewLocationDenied("invalid cache duration")
+		}
+		durations = append(durations, d)
+	}
+
+	return durations, nil
+}
+
 type authReq struct {
 	r resolver.Resolver
 }
@@ -109,30 +154,101 @@ func NewParser(r resolver.Resolver) parser.IngressAnnotation {
 	return authReq{r}
 }
 
-// ParseAnnotations parses the annotations contained in the ingress
-// rule used to use an Config URL as source for authentication
-func (a authReq) Parse(ing *extensions.Ingress) (interface{}, error) {
-	// Required Parameters
-	urlString, err := parser.GetStringAnnotation("auth-url", ing)
-	if err != nil {
-		return nil, err
-	}
+// parseURL checks the URL of the authentication service and returns its host
+func parseURL(urlString string) (string, error) {
 	if urlString == "" {
-		return nil, ing_errors.NewLocationDenied("an empty string is not a valid URL")
+		return "", ing_errors.NewLocationDenied("an empty string is not a valid URL")
 	}
 
 	authUrl, err := url.Parse(urlString)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 	if authUrl.Scheme == "" {
-		return nil, ing_errors.NewLocationDenied("url scheme is empty")
+		return "", ing_errors.NewLocationDenied("url scheme is empty")
 	}
 	if authUrl.Host == "" {
-		return nil, ing_errors.NewLocationDenied("url host is empty")
+		return "", ing_errors.NewLocationDenied("url host is empty")
 	}
 	if strings.Contains(authUrl.Host, "..") {
-		return nil, ing_errors.NewLocationDenied("invalid url host")
+		return "", ing_errors.NewLocationDenied("invalid url host")
+	}
+
+	return authUrl.Hostname(), nil
+}
+
+// parseResponseHeaders checks the headers copied from the response of the
+// authentication service to the request sent to the backend
+func parseResponseHeaders(headers []string) ([]string, error) {
+	responseHeaders := []string{}
+	for _, header := range headers {
+		header = strings.TrimSpace(header)
+		if len(header) > 0 {
+			if !validHeader(header) {
+				return nil, ing_errors.NewLocationDenied("invalid headers list")
+			}
+			responseHeaders = append(responseHeaders, header)
+		}
+	}
+
+	return responseHeaders, nil
+}
+
+// parseGlobalAuth returns the external authentication configured in the
+// configuration ConfigMap. The annotation enable-global-auth disables it.
+func (a authReq) parseGlobalAuth(ing *extensions.Ingress) (interface{}, error) {
+	defBackend := a.r.GetDefaultBackend()
+	if defBackend.GlobalExternalAuthURL == "" {
+		return nil, ing_errors.ErrMissingAnnotations
+	}
+
+	enabled, err := parser.GetBoolAnnotation("enable-global-auth", ing)
+	if err == nil && !enabled {
+		return nil, ing_errors.ErrMissingAnnotations
+	}
+
+	// an invalid global configuration denies the access instead of
+	// exposing the locations without authentication
+	host, err := parseURL(defBackend.GlobalExternalAuthURL)
+	if err != nil {
+		return nil, ing_errors.NewLocationDenied(fmt.Sprintf("invalid global-auth-url: %v", err))
+	}
+
+	if len(defBackend.GlobalExternalAuthMethod) != 0 && !validMethod(defBackend.GlobalExternalAuthMethod) {
+		return nil, ing_errors.NewLocationDenied("invalid HTTP method in global-auth-method")
+	}
+
+	responseHeaders, err := parseResponseHeaders(defBackend.GlobalExternalAuthResponseHeaders)
+	if err != nil {
+		return nil, ing_errors.NewLocationDenied("invalid headers list in global-auth-response-headers")
+	}
+
+	return &Config{
+		URL:               defBackend.GlobalExternalAuthURL,
+		Host:              host,
+		SigninURL:         defBackend.GlobalExternalAuthSigninURL,
+		Method:            defBackend.GlobalExternalAuthMethod,
+		ResponseHeaders:   responseHeaders,
+		AuthCacheDuration: []string{},
+	}, nil
+}
+
+// ParseAnnotations parses the annotations contained in the ingress
+// rule used to use an Config URL as source for authentication.
+// Without the annotation auth-url the global external authentication is used.
+func (a authReq) Parse(ing *extensions.Ingress) (interface{}, error) {
+	// Required Parameters
+	urlString, err := parser.GetStringAnnotation("auth-url", ing)
+	if err == ing_errors.ErrMissingAnnotations {
+		return a.parseGlobalAuth(ing)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	host, err := parseURL(urlString)
+	if err != nil {
+		return nil, err
 	}
 
 	authMethod, _ := parser.GetStringAnnotation("auth-method", ing)
@@ -143,29 +259,40 @@ func (a authReq) Parse(ing *extensions.Ingress) (interface{}, error) {
 	// Optional Parameters
 	signIn, _ := parser.GetStringAnnotation("auth-signin", ing)
 
-	responseHeaders := []string{}
 	hstr, _ := parser.GetStringAnnotation("auth-response-headers", ing)
-	if len(hstr) != 0 {
-		harr := strings.Split(hstr, ",")
-		for _, header := range harr {
-			header = strings.TrimSpace(header)
-			if len(header) > 0 {
-				if !validHeader(header) {
-					return nil, ing_errors.NewLocationDenied("invalid headers list")
-				}
-				responseHeaders = append(responseHeaders, header)
-			}
-		}
+	responseHeaders, err := parseResponseHeaders(strings.Split(hstr, ","))
+	if err != nil {
+		return nil, err
 	}
 
 	requestRedirect, _ := parser.GetStringAnnotation("auth-request-redirect", ing)
 
+	cacheKey, _ := parser.GetStringAnnotation("auth-cache-key", ing)
+	if len(cacheKey) != 0 && !cacheKeyRegexp.MatchString(cacheKey) {
+		return nil, ing_errors.NewLocationDenied("invalid cache key")
+	}
+
+	cacheDuration := []string{}
+	if len(cacheKey) != 0 {
+		dstr, _ := parser.GetStringAnnotation("auth-cache-duration", ing)
+		if len(dstr) == 0 {
+			dstr = DefaultCacheDuration
+		}
+
+		cacheDuration, err = parseCacheDuration(dstr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &Config{
-		URL:             urlString,
-		Host:            authUrl.Hostname(),
-		SigninURL:       signIn,
-		Method:          authMethod,
-		ResponseHeaders: responseHeaders,
-		RequestRedirect: requestRedirect,
+		URL:               urlString,
+		Host:              host,
+		SigninURL:         signIn,
+		Method:            authMethod,
+		ResponseHeaders:   responseHeaders,
+		RequestRedirect:   requestRedirect,
+		AuthCacheKey:      cacheKey,
+		AuthCacheDuration: cacheDuration,
 	}, nil
 }
@@ -25,6 +25,8 @@ import (
 	extensions "k8s.io/api/extensions/v1beta1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/defaults"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -168,3 +170,136 @@ func TestHeaderAnnotations(t *testing.T) {
 		}
 	}
 }
+
+func TestCacheAnnotations(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	ing.SetAnnotations(data)
+
+	tests := []struct {
+		title          string
+		key            string
+		duration       string
+		parsedDuration []string
+		expErr         bool
+	}{
+		{"no cache", "", "200 10m", []string{}, false},
+		{"default duration", "$http_authorization", "", []string{DefaultCacheDuration}, false},
+		{"single duration", "$http_authorization", "200 202 10m", []string{"200 202 10m"}, false},
+		{"multiple durations", "$cookie_session$http_authorization", "200 202 10m, 401  30s,", []string{"200 202 10m", "401 30s"}, false},
+		{"any status", "$http_authorization", "any 1m", []string{"any 1m"}, false},
+		{"invalid duration", "$http_authorization", "200 ten minutes", []string{}, true},
+		{"key with quotes", `$http_authorization"; return 200; "`, "", []string{}, true},
+		{"key with braces", "${http_authorization}", "", []string{}, true},
+	}
+
+	for _, test := range tests {
+		data[parser.GetAnnotationWithPrefix("auth-url")] = "http://goog.url"
+		data[parser.GetAnnotationWithPrefix("auth-cache-key")] = test.key
+		data[parser.GetAnnotationWithPrefix("auth-cache-duration")] = test.duration
+
+		i, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if test.expErr {
+			if err == nil {
+				t.Errorf("%v: expected error but retuned nil", test.title)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.title, err)
+			continue
+		}
+
+		u, ok := i.(*Config)
+		if !ok {
+			t.Errorf("%v: expected an External type", test.title)
+			continue
+		}
+
+		if u.AuthCacheKey != test.key {
+			t.Errorf("%v: expected \"%v\" but \"%v\" was returned", test.title, test.key, u.AuthCacheKey)
+		}
+		if !reflect.DeepEqual(u.AuthCacheDuration, test.parsedDuration) {
+			t.Errorf("%v: expected \"%v\" but \"%v\" was returned", test.title, test.parsedDuration, u.AuthCacheDuration)
+		}
+	}
+}
+
+type mockBackend struct {
+	resolver.Mock
+	backend defaults.Backend
+}
+
+func (m mockBackend) GetDefaultBackend() defaults.Backend {
+	return m.backend
+}
+
+func TestGlobalAuth(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	ing.SetAnnotations(data)
+
+	r := mockBackend{
+		backend: defaults.Backend{
+			GlobalExternalAuthURL:             "http://auth.svc.cluster.local/check",
+			GlobalExternalAuthSigninURL:       "http://auth.example.com/signin",
+			GlobalExternalAuthMethod:          "GET",
+			GlobalExternalAuthResponseHeaders: []string{"X-User", " X-Email"},
+		},
+	}
+
+	i, err := NewParser(r).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	u, ok := i.(*Config)
+	if !ok {
+		t.Fatalf("expected an External type")
+	}
+	if u.URL != r.backend.GlobalExternalAuthURL {
+		t.Errorf("expected \"%v\" but \"%v\" was returned", r.backend.GlobalExternalAuthURL, u.URL)
+	}
+	if u.Host != "auth.svc.cluster.local" {
+		t.Errorf("expected \"auth.svc.cluster.local\" but \"%v\" was returned", u.Host)
+	}
+	if u.SigninURL != r.backend.GlobalExternalAuthSigninURL {
+		t.Errorf("expected \"%v\" but \"%v\" was returned", r.backend.GlobalExternalAuthSigninURL, u.SigninURL)
+	}
+	if !reflect.DeepEqual(u.ResponseHeaders, []string{"X-User", "X-Email"}) {
+		t.Errorf("expected response headers X-User and X-Email but \"%v\" was returned", u.ResponseHeaders)
+	}
+
+	// the annotation auth-url takes precedence
+	data[parser.GetAnnotationWithPrefix("auth-url")] = "http://other.svc/auth"
+	i, err = NewParser(r).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u := i.(*Config); u.URL != "http://other.svc/auth" || u.SigninURL != "" {
+		t.Errorf("expected the configuration of the annotations but %v was returned", u)
+	}
+	delete(data, parser.GetAnnotationWithPrefix("auth-url"))
+
+	// opt-out
+	data[parser.GetAnnotationWithPrefix("enable-global-auth")] = "false"
+	_, err = NewParser(r).Parse(ing)
+	if err != ing_errors.ErrMissingAnnotations {
+		t.Errorf("expected missing annotations error but %v was returned", err)
+	}
+	delete(data, parser.GetAnnotationWithPrefix("enable-global-auth"))
+
+	// an invalid global configuration denies the access
+	r.backend.GlobalExternalAuthURL = "auth.svc"
+	_, err = NewParser(r).Parse(ing)
+	if !ing_errors.IsLocationDenied(err) {
+		t.Errorf("expected location denied error but %v was returned", err)
+	}
+
+	// disabled global authentication
+	_, err = NewParser(mockBackend{}).Parse(ing)
+	if err != ing_errors.ErrMissingAnnotations {
+		t.Errorf("expected missing annotations error but %v was returned", err)
+	}
+}
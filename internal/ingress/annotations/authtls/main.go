@@ -17,6 +17,8 @@ limitations under the License.
 package authtls
 
 import (
+	"fmt"
+
 	"github.com/pkg/errors"
 	extensions "k8s.io/api/extensions/v1beta1"
 
@@ -45,6 +47,7 @@ type Config struct {
 	ValidationDepth    int    `json:"validationDepth"`
 	ErrorPage          string `json:"errorPage"`
 	PassCertToUpstream bool   `json:"passCertToUpstream"`
+	CRLSecret          string `json:"crlSecret,omitempty"`
 	AuthTLSError       string
 }
 
@@ -71,6 +74,9 @@ func (assl1 *Config) Equal(assl2 *Config) bool {
 	if assl1.PassCertToUpstream != assl2.PassCertToUpstream {
 		return false
 	}
+	if assl1.CRLSecret != assl2.CRLSecret {
+		return false
+	}
 
 	return true
 }
@@ -128,11 +134,33 @@ func (a authTLS) Parse(ing *extensions.Ingress) (interface{}, error) {
 		passCert = false
 	}
 
+	crlSecret, _ := parser.GetStringAnnotation("auth-tls-crl-secret", ing)
+	if crlSecret != "" {
+		_, _, err = k8s.ParseNameNS(crlSecret)
+		if err != nil {
+			return &Config{}, ing_errors.NewLocationDenied(err.Error())
+		}
+
+		crl, err := a.r.GetAuthCertificate(crlSecret)
+		if err != nil {
+			e := errors.Wrap(err, "error obtaining certificate revocation list")
+			return &Config{}, ing_errors.LocationDenied{Reason: e}
+		}
+
+		if crl.CRLFileName == "" {
+			return &Config{}, ing_errors.NewLocationDenied(fmt.Sprintf("secret %v does not contain 'ca.crl'", crlSecret))
+		}
+
+		authCert.CRLFileName = crl.CRLFileName
+		authCert.CRLSHA = crl.CRLSHA
+	}
+
 	return &Config{
 		AuthSSLCert:        *authCert,
 		VerifyClient:       tlsVerifyClient,
 		ValidationDepth:    tlsdepth,
 		ErrorPage:          errorpage,
 		PassCertToUpstream: passCert,
+		CRLSecret:          crlSecret,
 	}, nil
 }
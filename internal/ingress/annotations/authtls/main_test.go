@@ -19,10 +19,14 @@ package authtls
 import (
 	"testing"
 
+	"github.com/pkg/errors"
 	api "k8s.io/api/core/v1"
 	extensions "k8s.io/api/extensions/v1beta1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
 func buildIngress() *extensions.Ingress {
@@ -106,3 +110,57 @@ func TestAnnotations(t *testing.T) {
 				}
 		}*/
 }
+
+type mockSecret struct {
+	resolver.Mock
+}
+
+func (m mockSecret) GetAuthCertificate(name string) (*resolver.AuthSSLCert, error) {
+	switch name {
+	case "default/demo-ca":
+		return &resolver.AuthSSLCert{
+			Secret:     name,
+			CAFileName: "/ssl/ca-default-demo-ca.pem",
+			PemSHA:     "1",
+		}, nil
+	case "default/demo-crl":
+		return &resolver.AuthSSLCert{
+			Secret:      name,
+			CRLFileName: "/ssl/ca-default-demo-crl.crl",
+			CRLSHA:      "2",
+		}, nil
+	}
+
+	return nil, errors.Errorf("there is no secret with name %v", name)
+}
+
+func TestCRLSecret(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{
+		parser.GetAnnotationWithPrefix("auth-tls-secret"):     "default/demo-ca",
+		parser.GetAnnotationWithPrefix("auth-tls-crl-secret"): "default/demo-crl",
+	}
+	ing.SetAnnotations(data)
+
+	i, err := NewParser(mockSecret{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cfg := i.(*Config)
+	if cfg.CAFileName != "/ssl/ca-default-demo-ca.pem" || cfg.CRLFileName != "/ssl/ca-default-demo-crl.crl" || cfg.CRLSHA != "2" {
+		t.Errorf("unexpected configuration %+v", cfg)
+	}
+
+	// a CRL that cannot be read must deny the access
+	for _, crlSecret := range []string{"default/demo-ca", "default/missing", "missing"} {
+		data[parser.GetAnnotationWithPrefix("auth-tls-crl-secret")] = crlSecret
+		ing.SetAnnotations(data)
+
+		_, err = NewParser(mockSecret{}).Parse(ing)
+		if err == nil {
+			t.Errorf("%v: expected an error but none returned", crlSecret)
+		}
+	}
+}
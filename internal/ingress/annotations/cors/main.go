@@ -18,10 +18,12 @@ package cors
 
 import (
 	"regexp"
+	"strings"
 
 	extensions "k8s.io/api/extensions/v1beta1"
 
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
@@ -35,8 +37,8 @@ const (
 var (
 	// Regex are defined here to prevent information leak, if user tries to set anything not valid
 	// that could cause the Response to contain some internal value/variable (like returning $pid, $upstream_addr, etc)
-	// Origin must contain a http/s Origin (including or not the port) or the value '*'
-	corsOriginRegex = regexp.MustCompile(`^(https?://[A-Za-z0-9\-\.]*(:[0-9]+)?|\*)?$`)
+	// Origin must contain a http/s Origin (including or not the port)
+	corsOriginRegex = regexp.MustCompile(`^https?://[A-Za-z0-9\-\.]+(:[0-9]+)?$`)
 	// Method must contain valid methods list (PUT, GET, POST, BLA)
 	// May contain or not spaces between each verb
 	corsMethodsRegex = regexp.MustCompile(`^([A-Za-z]+,?\s?)+$`)
@@ -45,18 +47,27 @@ var (
 	corsHeadersRegex = regexp.MustCompile(`^([A-Za-z0-9\-\_]+,?\s?)+$`)
 )
 
+const (
+	// corsAnyOrigin allows the requests from any origin
+	corsAnyOrigin = "*"
+	// corsRegexPrefix is the prefix of the origins that are regular
+	// expressions. The prefix ~* is used for case insensitive matching
+	corsRegexPrefix = "~"
+)
+
 type cors struct {
 	r resolver.Resolver
 }
 
 // Config contains the Cors configuration to be used in the Ingress
 type Config struct {
-	CorsEnabled          bool   `json:"corsEnabled"`
-	CorsAllowOrigin      string `json:"corsAllowOrigin"`
-	CorsAllowMethods     string `json:"corsAllowMethods"`
-	CorsAllowHeaders     string `json:"corsAllowHeaders"`
-	CorsAllowCredentials bool   `json:"corsAllowCredentials"`
-	CorsMaxAge           int    `json:"corsMaxAge"`
+	CorsEnabled          bool     `json:"corsEnabled"`
+	CorsAllowOrigin      []string `json:"corsAllowOrigin"`
+	CorsAllowMethods     string   `json:"corsAllowMethods"`
+	CorsAllowHeaders     string   `json:"corsAllowHeaders"`
+	CorsAllowCredentials bool     `json:"corsAllowCredentials"`
+	CorsExposeHeaders    string   `json:"corsExposeHeaders"`
+	CorsMaxAge           int      `json:"corsMaxAge"`
 }
 
 // NewParser creates a new CORS annotation parser
@@ -84,7 +95,15 @@ func (c1 *Config) Equal(c2 *Config) bool {
 	if c1.CorsAllowMethods != c2.CorsAllowMethods {
 		return false
 	}
-	if c1.CorsAllowOrigin != c2.CorsAllowOrigin {
+	if len(c1.CorsAllowOrigin) != len(c2.CorsAllowOrigin) {
+		return false
+	}
+	for i := range c1.CorsAllowOrigin {
+		if c1.CorsAllowOrigin[i] != c2.CorsAllowOrigin[i] {
+			return false
+		}
+	}
+	if c1.CorsExposeHeaders != c2.CorsExposeHeaders {
 		return false
 	}
 	if c1.CorsEnabled != c2.CorsEnabled {
@@ -102,9 +121,13 @@ func (c cors) Parse(ing *extensions.Ingress) (interface{}, error) {
 		corsenabled = false
 	}
 
-	corsalloworigin, err := parser.GetStringAnnotation("cors-allow-origin", ing)
-	if err != nil || corsalloworigin == "" || !corsOriginRegex.MatchString(corsalloworigin) {
-		corsalloworigin = "*"
+	corsalloworigin := []string{corsAnyOrigin}
+	val, err := parser.GetStringAnnotation("cors-allow-origin", ing)
+	if err == nil && strings.TrimSpace(val) != "" {
+		corsalloworigin, err = parseOrigins(val)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	corsallowheaders, err := parser.GetStringAnnotation("cors-allow-headers", ing)
@@ -122,6 +145,11 @@ func (c cors) Parse(ing *extensions.Ingress) (interface{}, error) {
 		corsallowcredentials = true
 	}
 
+	corsexposeheaders, err := parser.GetStringAnnotation("cors-expose-headers", ing)
+	if err == nil && corsexposeheaders != "" && !corsHeadersRegex.MatchString(corsexposeheaders) {
+		return nil, ing_errors.NewInvalidAnnotationContent("cors-expose-headers", corsexposeheaders)
+	}
+
 	corsmaxage, err := parser.GetIntAnnotation("cors-max-age", ing)
 	if err != nil {
 		corsmaxage = defaultCorsMaxAge
@@ -133,7 +161,60 @@ func (c cors) Parse(ing *extensions.Ingress) (interface{}, error) {
 		CorsAllowHeaders:     corsallowheaders,
 		CorsAllowMethods:     corsallowmethods,
 		CorsAllowCredentials: corsallowcredentials,
+		CorsExposeHeaders:    corsexposeheaders,
 		CorsMaxAge:           corsmaxage,
 	}, nil
 
 }
+
+// parseOrigins returns the origins of a comma separated list. An origin is
+// a http/s origin, a regular expression with the prefix ~ (~* for case
+// insensitive matching) or the value '*', that cannot be combined with
+// other origins
+func parseOrigins(val string) ([]string, error) {
+	origins := []string{}
+	for _, origin := range strings.Split(val, ",") {
+		origin = strings.TrimSpace(origin)
+		if origin == "" {
+			continue
+		}
+
+		if !isValidOrigin(origin) {
+			return nil, ing_errors.NewInvalidAnnotationContent("cors-allow-origin", origin)
+		}
+
+		origins = append(origins, origin)
+	}
+
+	if len(origins) == 0 {
+		return []string{corsAnyOrigin}, nil
+	}
+
+	for _, origin := range origins {
+		if origin == corsAnyOrigin && len(origins) > 1 {
+			return nil, ing_errors.NewInvalidAnnotationContent("cors-allow-origin", val)
+		}
+	}
+
+	return origins, nil
+}
+
+// isValidOrigin checks an origin of the list of allowed origins. Regular
+// expressions cannot contain characters that modify the nginx configuration
+func isValidOrigin(origin string) bool {
+	if origin == corsAnyOrigin {
+		return true
+	}
+
+	if !strings.HasPrefix(origin, corsRegexPrefix) {
+		return corsOriginRegex.MatchString(origin)
+	}
+
+	expr := strings.TrimPrefix(strings.TrimPrefix(origin, corsRegexPrefix), "*")
+	if expr == "" || strings.ContainsAny(expr, " \t\r\n\"'") {
+		return false
+	}
+
+	_, err := regexp.Compile(expr)
+	return err == nil
+}
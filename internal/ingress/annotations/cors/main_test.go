@@ -17,6 +17,7 @@ limitations under the License.
 package cors
 
 import (
+	"reflect"
 	"testing"
 
 	api "k8s.io/api/core/v1"
@@ -24,6 +25,7 @@ import (
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
@@ -92,7 +94,7 @@ func TestIngressCorsConfig(t *testing.T) {
 		t.Errorf("expected default methods, but got  %v", nginxCors.CorsAllowMethods)
 	}
 
-	if nginxCors.CorsAllowOrigin != "https://origin123.test.com:4443" {
+	if !reflect.DeepEqual(nginxCors.CorsAllowOrigin, []string{"https://origin123.test.com:4443"}) {
 		t.Errorf("expected origin https://origin123.test.com:4443, but got  %v", nginxCors.CorsAllowOrigin)
 	}
 
@@ -100,3 +102,93 @@ func TestIngressCorsConfig(t *testing.T) {
 		t.Errorf("expected max age 600, but got  %v", nginxCors.CorsMaxAge)
 	}
 }
+
+func TestIngressCorsOrigins(t *testing.T) {
+	tests := map[string]struct {
+		origin    string
+		expected  []string
+		expectErr bool
+	}{
+		"default origin": {
+			origin:   "",
+			expected: []string{"*"},
+		},
+		"multiple origins": {
+			origin:   "https://a.test.com, http://b.test.com:8080,",
+			expected: []string{"https://a.test.com", "http://b.test.com:8080"},
+		},
+		"regular expressions": {
+			origin:   `https://a.test.com,~^https://[a-z]+\.test\.com$,~*^https://.+\.example\.com$`,
+			expected: []string{"https://a.test.com", `~^https://[a-z]+\.test\.com$`, `~*^https://.+\.example\.com$`},
+		},
+		"any origin with other origins": {
+			origin:    "*,https://a.test.com",
+			expectErr: true,
+		},
+		"invalid origin": {
+			origin:    "https://a.test.com,a.test.com",
+			expectErr: true,
+		},
+		"origin with variables": {
+			origin:    "https://$host",
+			expectErr: true,
+		},
+		"invalid regular expression": {
+			origin:    "~^https://(a.test.com$",
+			expectErr: true,
+		},
+		"regular expression with quotes": {
+			origin:    `~^https://a.test.com';$`,
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		ing := buildIngress()
+		data := map[string]string{}
+		data[parser.GetAnnotationWithPrefix("enable-cors")] = "true"
+		data[parser.GetAnnotationWithPrefix("cors-allow-origin")] = test.origin
+		ing.SetAnnotations(data)
+
+		i, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if test.expectErr {
+			if !ing_errors.IsInvalidContent(err) {
+				t.Errorf("%v: expected an invalid content error but returned %v", name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", name, err)
+			continue
+		}
+
+		origins := i.(*Config).CorsAllowOrigin
+		if !reflect.DeepEqual(origins, test.expected) {
+			t.Errorf("%v: expected %v but returned %v", name, test.expected, origins)
+		}
+	}
+}
+
+func TestIngressCorsExposeHeaders(t *testing.T) {
+	ing := buildIngress()
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("enable-cors")] = "true"
+	data[parser.GetAnnotationWithPrefix("cors-expose-headers")] = "X-Request-Id, X-Total-Count"
+	ing.SetAnnotations(data)
+
+	i, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if headers := i.(*Config).CorsExposeHeaders; headers != "X-Request-Id, X-Total-Count" {
+		t.Errorf("expected expose headers X-Request-Id, X-Total-Count but returned %v", headers)
+	}
+
+	data[parser.GetAnnotationWithPrefix("cors-expose-headers")] = "X-Request-Id, $nginx_version"
+	ing.SetAnnotations(data)
+
+	_, err = NewParser(&resolver.Mock{}).Parse(ing)
+	if !ing_errors.IsInvalidContent(err) {
+		t.Errorf("expected an invalid content error but returned %v", err)
+	}
+}
@@ -0,0 +1,270 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package customheaders
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/golang/glog"
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+var (
+	headerRegexp = regexp.MustCompile(`^[a-zA-Z\d\-_]+$`)
+
+	// reservedRequestHeaders contains the headers always sent to the
+	// upstream servers by the template. Defining them again sends the
+	// header twice
+	reservedRequestHeaders = map[string]bool{
+		"host":                     true,
+		"upgrade":                  true,
+		"connection":               true,
+		"proxy":                    true,
+		"x-real-ip":                true,
+		"x-forwarded-for":          true,
+		"x-forwarded-host":         true,
+		"x-forwarded-port":         true,
+		"x-forwarded-proto":        true,
+		"x-original-uri":           true,
+		"x-original-forwarded-for": true,
+		"x-scheme":                 true,
+		"ssl-client-cert":          true,
+		"ssl-client-verify":        true,
+		"ssl-client-subject-dn":    true,
+		"ssl-client-issuer-dn":     true,
+	}
+)
+
+// Config contains the headers added, replaced or removed in the requests
+// to the upstream servers and in the responses to the clients
+type Config struct {
+	// RequestHeadersName is the name of the ConfigMap (<namespace>/<name>)
+	// with the headers of the requests
+	RequestHeadersName string `json:"requestHeadersName,omitempty"`
+	// RequestHeaders are the headers sent to the upstream servers. An
+	// empty value removes the header
+	RequestHeaders map[string]string `json:"requestHeaders,omitempty"`
+	// ResponseHeadersName is the name of the ConfigMap (<namespace>/<name>)
+	// with the headers of the responses
+	ResponseHeadersName string `json:"responseHeadersName,omitempty"`
+	// ResponseHeaders are the headers sent to the clients. An empty value
+	// removes the header
+	ResponseHeaders map[string]string `json:"responseHeaders,omitempty"`
+	// HideHeaders are the headers of the upstream servers not sent to the
+	// clients
+	HideHeaders []string `json:"hideHeaders,omitempty"`
+	// InvalidHeaders contains the headers that were not loaded and the reason
+	InvalidHeaders []string `json:"invalidHeaders,omitempty"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if c1.RequestHeadersName != c2.RequestHeadersName {
+		return false
+	}
+	if !equalHeaders(c1.RequestHeaders, c2.RequestHeaders) {
+		return false
+	}
+	if c1.ResponseHeadersName != c2.ResponseHeadersName {
+		return false
+	}
+	if !equalHeaders(c1.ResponseHeaders, c2.ResponseHeaders) {
+		return false
+	}
+	if !equalStrings(c1.HideHeaders, c2.HideHeaders) {
+		return false
+	}
+	if !equalStrings(c1.InvalidHeaders, c2.InvalidHeaders) {
+		return false
+	}
+
+	return true
+}
+
+func equalHeaders(h1, h2 map[string]string) bool {
+	if len(h1) != len(h2) {
+		return false
+	}
+	for k, v := range h1 {
+		v2, ok := h2[k]
+		if !ok || v != v2 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func equalStrings(s1, s2 []string) bool {
+	if len(s1) != len(s2) {
+		return false
+	}
+	for i := range s1 {
+		if s1[i] != s2[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+type customHeaders struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new custom headers annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return customHeaders{r}
+}
+
+// Parse parses the annotations contained in the ingress rule used to
+// manipulate the headers of the requests and responses. The request and
+// response headers are located in ConfigMaps in the namespace of the Ingress
+// and the hidden headers are a comma separated list
+func (a customHeaders) Parse(ing *extensions.Ingress) (interface{}, error) {
+	requestName, _ := parser.GetStringAnnotation("request-headers", ing)
+	responseName, _ := parser.GetStringAnnotation("response-headers", ing)
+	hideHeaders, _ := parser.GetStringAnnotation("hide-headers", ing)
+
+	requestName = strings.TrimSpace(requestName)
+	responseName = strings.TrimSpace(responseName)
+	if requestName == "" && responseName == "" && strings.TrimSpace(hideHeaders) == "" {
+		return nil, ing_errors.ErrMissingAnnotations
+	}
+
+	cfg := &Config{
+		InvalidHeaders: []string{},
+	}
+
+	if requestName != "" {
+		name, data, err := a.getHeaders("request-headers", requestName, ing)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RequestHeadersName = name
+		cfg.RequestHeaders = parseHeaders(name, data, reservedRequestHeaders, cfg)
+	}
+
+	if responseName != "" {
+		name, data, err := a.getHeaders("response-headers", responseName, ing)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ResponseHeadersName = name
+		cfg.ResponseHeaders = parseHeaders(name, data, nil, cfg)
+	}
+
+	seen := map[string]bool{}
+	for _, header := range strings.Split(hideHeaders, ",") {
+		header = strings.TrimSpace(header)
+		if header == "" || seen[strings.ToLower(header)] {
+			continue
+		}
+		if !headerRegexp.MatchString(header) {
+			return nil, ing_errors.NewInvalidAnnotationContent("hide-headers", hideHeaders)
+		}
+		seen[strings.ToLower(header)] = true
+		cfg.HideHeaders = append(cfg.HideHeaders, header)
+	}
+
+	return cfg, nil
+}
+
+// getHeaders returns the name and the entries of the ConfigMap referenced
+// in an annotation. The ConfigMap must be located in the namespace of the
+// Ingress to avoid exposing the ConfigMaps of other namespaces
+func (a customHeaders) getHeaders(annotation, name string, ing *extensions.Ingress) (string, map[string]string, error) {
+	if strings.Contains(name, "/") {
+		return "", nil, ing_errors.NewInvalidAnnotationContent(annotation, name)
+	}
+	name = fmt.Sprintf("%v/%v", ing.Namespace, name)
+
+	cm, err := a.r.GetConfigMap(name)
+	if err != nil {
+		glog.Warningf("error reading headers %v of Ingress %v/%v: %v", name, ing.Namespace, ing.Name, err)
+		return "", nil, ing_errors.NewInvalidAnnotationContent(annotation, name)
+	}
+
+	return name, cm.Data, nil
+}
+
+// parseHeaders validates the entries of a ConfigMap. The keys are the
+// names of the headers and the values can contain nginx variables. The
+// invalid entries are added to the InvalidHeaders of the configuration
+func parseHeaders(name string, data map[string]string, reserved map[string]bool, cfg *Config) map[string]string {
+	keys := []string{}
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	headers := map[string]string{}
+	seen := map[string]bool{}
+	for _, k := range keys {
+		value := strings.TrimSpace(data[k])
+		err := validateHeader(k, value)
+		if err == nil && reserved[strings.ToLower(k)] {
+			err = fmt.Errorf("the header is set by the ingress controller")
+		}
+		if err == nil && seen[strings.ToLower(k)] {
+			err = fmt.Errorf("duplicated header")
+		}
+		if err != nil {
+			cfg.InvalidHeaders = append(cfg.InvalidHeaders, fmt.Sprintf("%v in %v (%v)", k, name, err))
+			continue
+		}
+
+		seen[strings.ToLower(k)] = true
+		headers[k] = value
+	}
+
+	return headers
+}
+
+// validateHeader checks the name of a header and that the value can be
+// used in a quoted string of the nginx configuration
+func validateHeader(name, value string) error {
+	if !headerRegexp.MatchString(name) {
+		return fmt.Errorf("invalid header name")
+	}
+
+	if strings.ContainsAny(value, "\"\\") {
+		return fmt.Errorf("quotes and backslashes are not allowed")
+	}
+
+	for _, r := range value {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("control characters are not allowed")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package customheaders
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func buildIngress() *extensions.Ingress {
+	return &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{
+			Backend: &extensions.IngressBackend{
+				ServiceName: "default-backend",
+				ServicePort: intstr.FromInt(80),
+			},
+		},
+	}
+}
+
+type mockConfigMap struct {
+	resolver.Mock
+}
+
+func (m mockConfigMap) GetConfigMap(name string) (*api.ConfigMap, error) {
+	switch name {
+	case "default/request":
+		return &api.ConfigMap{
+			Data: map[string]string{
+				"X-Request-Id": "$request_id",
+				"X-Remove":     "",
+				"X-Real-IP":    "1.1.1.1",
+				"X-Invalid":    "\"",
+				"X Space":      "value",
+			},
+		}, nil
+	case "default/response", "other/response":
+		return &api.ConfigMap{
+			Data: map[string]string{
+				"Cache-Control": "no-cache\n",
+				"X-Powered-By":  "",
+			},
+		}, nil
+	}
+
+	return nil, fmt.Errorf("configmap %v not found", name)
+}
+
+func TestParseAnnotations(t *testing.T) {
+	ing := buildIngress()
+
+	_, err := NewParser(mockConfigMap{}).Parse(ing)
+	if !ing_errors.IsMissingAnnotations(err) {
+		t.Errorf("expected a missing annotations error but returned %v", err)
+	}
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("request-headers")] = "request"
+	data[parser.GetAnnotationWithPrefix("response-headers")] = "response"
+	data[parser.GetAnnotationWithPrefix("hide-headers")] = "X-Debug, Server,x-debug"
+	ing.SetAnnotations(data)
+
+	i, err := NewParser(mockConfigMap{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg, ok := i.(*Config)
+	if !ok {
+		t.Fatalf("expected a Config type")
+	}
+
+	expected := &Config{
+		RequestHeadersName: "default/request",
+		RequestHeaders: map[string]string{
+			"X-Request-Id": "$request_id",
+			"X-Remove":     "",
+		},
+		ResponseHeadersName: "default/response",
+		ResponseHeaders: map[string]string{
+			"Cache-Control": "no-cache",
+			"X-Powered-By":  "",
+		},
+		HideHeaders: []string{"X-Debug", "Server"},
+		InvalidHeaders: []string{
+			"X Space in default/request (invalid header name)",
+			"X-Invalid in default/request (quotes and backslashes are not allowed)",
+			"X-Real-IP in default/request (the header is set by the ingress controller)",
+		},
+	}
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("expected %v but returned %v", expected, cfg)
+	}
+	if !cfg.Equal(expected) {
+		t.Errorf("expected equal configurations")
+	}
+}
+
+func TestParseInvalidAnnotations(t *testing.T) {
+	ing := buildIngress()
+
+	tests := []struct {
+		annotation string
+		value      string
+	}{
+		{"request-headers", "missing"},
+		{"response-headers", "default/missing"},
+		// the configmaps of other namespaces cannot be used
+		{"response-headers", "other/response"},
+		{"request-headers", "default/request"},
+		{"hide-headers", "X-Debug,X$Debug"},
+	}
+
+	for _, test := range tests {
+		data := map[string]string{}
+		data[parser.GetAnnotationWithPrefix(test.annotation)] = test.value
+		ing.SetAnnotations(data)
+
+		_, err := NewParser(mockConfigMap{}).Parse(ing)
+		if !ing_errors.IsInvalidContent(err) {
+			t.Errorf("%v: %v: expected an invalid content error but returned %v", test.annotation, test.value, err)
+		}
+	}
+}
+
+func TestValidateHeader(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		valid bool
+	}{
+		{"X-Custom", "value", true},
+		{"X-Custom", "", true},
+		{"X_Custom", "$host:$server_port", true},
+		{"X-Custom", "a; b", true},
+		{"X-Custom:", "value", false},
+		{"", "value", false},
+		{"X-Custom", "\"value", false},
+		{"X-Custom", "value\\", false},
+		{"X-Custom", "a\r\nX-Other: b", false},
+	}
+
+	for _, test := range tests {
+		err := validateHeader(test.name, test.value)
+		if test.valid && err != nil {
+			t.Errorf("%v: %v: unexpected error: %v", test.name, test.value, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("%v: %v: expected an error", test.name, test.value)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package geoip
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+)
+
+const (
+	// types of the GeoIP legacy databases that contain country codes
+	countryEdition   = 1
+	countryEditionV6 = 12
+
+	// the structure info of the database is located in the last bytes
+	// of the file, after the delimiter 0xFFFFFF
+	structureInfoMaxSize = 20
+
+	// the records of the nodes of the country databases with a value
+	// greater or equal than countryBegin contain a country
+	countryBegin = 16776960
+	recordLength = 3
+	maxCountries = 256
+)
+
+// CountryDatabase is the GeoIP legacy country database used by nginx
+var CountryDatabase = "/etc/nginx/geoip/GeoIP.dat"
+
+var (
+	// databaseChecks caches the verification of the country databases by
+	// path. The verification reads the whole file, so it is only repeated
+	// when the size or the modification time of the file change
+	databaseChecks   = map[string]databaseCheck{}
+	databaseChecksMu = &sync.Mutex{}
+)
+
+// databaseCheck is the result of the verification of a country database
+type databaseCheck struct {
+	size    int64
+	modTime time.Time
+	err     error
+}
+
+// DatabaseError is returned when the GeoIP databases required by the
+// country access control are not available
+type DatabaseError struct {
+	Path   string
+	Reason string
+}
+
+func (e DatabaseError) Error() string {
+	if e.Path == "" {
+		return e.Reason
+	}
+	return fmt.Sprintf("GeoIP database %v %v", e.Path, e.Reason)
+}
+
+// IsDatabaseError returns the DatabaseError that denies a location
+func IsDatabaseError(err error) (DatabaseError, bool) {
+	ld, ok := err.(ing_errors.LocationDenied)
+	if !ok {
+		return DatabaseError{}, false
+	}
+
+	dbErr, ok := ld.Reason.(DatabaseError)
+	return dbErr, ok
+}
+
+// readDatabase returns the content of the GeoIP database in path. Empty,
+// truncated or compressed files are not valid databases
+func readDatabase(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, DatabaseError{Path: path, Reason: "does not exist"}
+		}
+		return nil, DatabaseError{Path: path, Reason: fmt.Sprintf("cannot be read: %v", err)}
+	}
+
+	if len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b {
+		return nil, DatabaseError{Path: path, Reason: "is corrupt: the file is gzip compressed"}
+	}
+
+	if len(data) < 2*recordLength {
+		return nil, DatabaseError{Path: path, Reason: "is corrupt: the file is truncated"}
+	}
+
+	return data, nil
+}
+
+// CheckDatabaseFile verifies the file in path contains a GeoIP database
+// that can be loaded by nginx. The country database is verified using
+// CheckDatabase
+func CheckDatabaseFile(path string) error {
+	if path == CountryDatabase {
+		return CheckDatabase(path)
+	}
+
+	_, err := readDatabase(path)
+	return err
+}
+
+// checkCountryDatabase verifies the country database in path using
+// CheckDatabase. The result is cached until the file changes
+func checkCountryDatabase(path string) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return CheckDatabase(path)
+	}
+
+	databaseChecksMu.Lock()
+	defer databaseChecksMu.Unlock()
+
+	if check, ok := databaseChecks[path]; ok && check.size == fi.Size() && check.modTime.Equal(fi.ModTime()) {
+		return check.err
+	}
+
+	err = CheckDatabase(path)
+	databaseChecks[path] = databaseCheck{
+		size:    fi.Size(),
+		modTime: fi.ModTime(),
+		err:     err,
+	}
+
+	return err
+}
+
+// InvalidateDatabaseCheck removes the cached verification of the database
+// in path. A database replaced with a file of the same size in the same
+// second is not detected using the size and modification time
+func InvalidateDatabaseCheck(path string) {
+	databaseChecksMu.Lock()
+	defer databaseChecksMu.Unlock()
+
+	delete(databaseChecks, path)
+}
+
+// CheckDatabase verifies the file in path contains a GeoIP legacy country
+// database (IPv4 or IPv6). nginx fails to start with missing or corrupt
+// databases, so the checks detect empty, truncated or compressed files
+// and databases of other types
+func CheckDatabase(path string) error {
+	data, err := readDatabase(path)
+	if err != nil {
+		return err
+	}
+
+	// databases without structure info are country databases
+	dbType := countryEdition
+	for i := 0; i < structureInfoMaxSize && len(data)-recordLength-i >= 0; i++ {
+		offset := len(data) - recordLength - i
+		if data[offset] != 0xff || data[offset+1] != 0xff || data[offset+2] != 0xff {
+			continue
+		}
+
+		if offset+recordLength < len(data) {
+			dbType = int(data[offset+recordLength])
+			if dbType >= 106 {
+				dbType -= 105
+			}
+		}
+		break
+	}
+
+	if dbType != countryEdition && dbType != countryEditionV6 {
+		return DatabaseError{Path: path, Reason: fmt.Sprintf("is not a country database (type %v)", dbType)}
+	}
+
+	// the records of the root node must point to another node of the
+	// file or to a country
+	nodes := len(data) / (2 * recordLength)
+	for i := 0; i < 2; i++ {
+		b := data[i*recordLength : (i+1)*recordLength]
+		record := int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+
+		if record >= countryBegin {
+			if record-countryBegin >= maxCountries {
+				return DatabaseError{Path: path, Reason: "is corrupt: invalid country index"}
+			}
+			continue
+		}
+
+		if record == 0 || record >= nodes {
+			return DatabaseError{Path: path, Reason: "is corrupt: invalid node index"}
+		}
+	}
+
+	return nil
+}
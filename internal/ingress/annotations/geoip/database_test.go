@@ -0,0 +1,118 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package geoip
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestCheckDatabase(t *testing.T) {
+	tests := map[string]struct {
+		content   []byte
+		expectErr bool
+	}{
+		"country database": {
+			content: countryDatabase,
+		},
+		"IPv6 country database": {
+			content: append(append([]byte{}, countryDatabase...), 0xff, 0xff, 0xff, 12),
+		},
+		"missing database": {
+			content:   nil,
+			expectErr: true,
+		},
+		"empty database": {
+			content:   []byte{},
+			expectErr: true,
+		},
+		"compressed database": {
+			content:   []byte{0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00},
+			expectErr: true,
+		},
+		"city database": {
+			content:   append(append([]byte{}, countryDatabase...), 0xff, 0xff, 0xff, 2),
+			expectErr: true,
+		},
+		"invalid node": {
+			content:   []byte{0x10, 0x00, 0x00, 0x00, 0xff, 0xff},
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		func() {
+			defer writeDatabase(t, test.content)()
+
+			err := CheckDatabase(CountryDatabase)
+			if test.expectErr && err == nil {
+				t.Errorf("%v: expected an error but none returned", name)
+			}
+			if !test.expectErr && err != nil {
+				t.Errorf("%v: unexpected error: %v", name, err)
+			}
+		}()
+	}
+}
+
+func TestCheckDatabaseFile(t *testing.T) {
+	city := append(append([]byte{}, countryDatabase...), 0xff, 0xff, 0xff, 2)
+	defer writeDatabase(t, city)()
+
+	if err := CheckDatabase(CountryDatabase); err == nil {
+		t.Errorf("expected an error with a city database as country database")
+	}
+
+	// other files are only read when they are not the country database
+	path := CountryDatabase
+	defer func() { CountryDatabase = path }()
+	CountryDatabase = "/etc/nginx/geoip/GeoIP.dat"
+	if err := CheckDatabaseFile(path); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestCheckCountryDatabase(t *testing.T) {
+	defer writeDatabase(t, countryDatabase)()
+
+	if err := checkCountryDatabase(CountryDatabase); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// a city database with the same size and modification time
+	fi, err := os.Stat(CountryDatabase)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	city := append(append([]byte{}, countryDatabase[:len(countryDatabase)-4]...), 0xff, 0xff, 0xff, 2)
+	if err := ioutil.WriteFile(CountryDatabase, city, 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.Chtimes(CountryDatabase, fi.ModTime(), fi.ModTime()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := checkCountryDatabase(CountryDatabase); err != nil {
+		t.Errorf("expected the cached result but returned %v", err)
+	}
+
+	InvalidateDatabaseCheck(CountryDatabase)
+	if err := checkCountryDatabase(CountryDatabase); err == nil {
+		t.Errorf("expected an error with a city database after the invalidation")
+	}
+}
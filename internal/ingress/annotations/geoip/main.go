@@ -0,0 +1,178 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package geoip
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+// countryCodeRegex matches the ISO 3166-1 alpha-2 codes and the special
+// codes of the GeoIP databases (i.e. A1 for anonymous proxies)
+var countryCodeRegex = regexp.MustCompile(`^[A-Z0-9]{2}$`)
+
+// Config contains the countries allowed or denied to access a location
+type Config struct {
+	AllowCountries []string `json:"allowCountries,omitempty"`
+	DenyCountries  []string `json:"denyCountries,omitempty"`
+	RedirectURL    string   `json:"redirectURL,omitempty"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+
+	if !equalCountries(c1.AllowCountries, c2.AllowCountries) {
+		return false
+	}
+	if !equalCountries(c1.DenyCountries, c2.DenyCountries) {
+		return false
+	}
+	if c1.RedirectURL != c2.RedirectURL {
+		return false
+	}
+
+	return true
+}
+
+func equalCountries(l1, l2 []string) bool {
+	if len(l1) != len(l2) {
+		return false
+	}
+
+	for i := range l1 {
+		if l1[i] != l2[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+type geoip struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new GeoIP country access control annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return geoip{r}
+}
+
+// Parse parses the annotations contained in the ingress rule used to allow
+// or deny the access to a location based on the country of the client.
+// The countries are ISO 3166-1 alpha-2 codes separated by commas.
+// The location is denied if a country code is invalid or the GeoIP country
+// database is not available.
+func (a geoip) Parse(ing *extensions.Ingress) (interface{}, error) {
+	allow, err := parseCountries("geoip-allow-countries", ing)
+	if err != nil {
+		return nil, err
+	}
+
+	deny, err := parseCountries("geoip-deny-countries", ing)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(allow) == 0 && len(deny) == 0 {
+		return nil, ing_errors.ErrMissingAnnotations
+	}
+
+	redirectURL, _ := parser.GetStringAnnotation("geoip-redirect-url", ing)
+	redirectURL = strings.TrimSpace(redirectURL)
+	if redirectURL != "" && !isValidRedirectURL(redirectURL) {
+		return nil, ing_errors.LocationDenied{
+			Reason: fmt.Errorf("invalid GeoIP redirect URL %v", redirectURL),
+		}
+	}
+
+	if !a.r.GetDefaultBackend().UseGeoIP {
+		return nil, ing_errors.LocationDenied{
+			Reason: DatabaseError{Reason: "GeoIP country access control requires use-geoip to be enabled"},
+		}
+	}
+
+	err = checkCountryDatabase(CountryDatabase)
+	if err != nil {
+		return nil, ing_errors.LocationDenied{Reason: err}
+	}
+
+	return &Config{
+		AllowCountries: allow,
+		DenyCountries:  deny,
+		RedirectURL:    redirectURL,
+	}, nil
+}
+
+// parseCountries returns the sorted list of country codes of an annotation
+func parseCountries(name string, ing *extensions.Ingress) ([]string, error) {
+	val, err := parser.GetStringAnnotation(name, ing)
+	if err != nil {
+		return nil, nil
+	}
+
+	countries := []string{}
+	seen := map[string]bool{}
+	for _, v := range strings.Split(val, ",") {
+		v = strings.ToUpper(strings.TrimSpace(v))
+		if v == "" || seen[v] {
+			continue
+		}
+
+		if !countryCodeRegex.MatchString(v) {
+			return nil, ing_errors.LocationDenied{
+				Reason: fmt.Errorf("invalid country code %q in annotation %v", v, name),
+			}
+		}
+
+		seen[v] = true
+		countries = append(countries, v)
+	}
+
+	sort.Strings(countries)
+
+	return countries, nil
+}
+
+// isValidRedirectURL checks the URL is absolute and can be used in a
+// return directive without quoting
+func isValidRedirectURL(u string) bool {
+	if strings.ContainsAny(u, " \t\r\n\"';{}$") {
+		return false
+	}
+
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return false
+	}
+
+	return (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package geoip
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/defaults"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+// countryDatabase contains two nodes: the root node points to a country
+// and to the second node, that points to the first country of the list
+var countryDatabase = []byte{
+	0x01, 0xff, 0xff, 0x01, 0x00, 0x00,
+	0x00, 0xff, 0xff, 0x00, 0xff, 0xff,
+}
+
+func buildIngress() *extensions.Ingress {
+	return &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{
+			Backend: &extensions.IngressBackend{
+				ServiceName: "default-backend",
+				ServicePort: intstr.FromInt(80),
+			},
+		},
+	}
+}
+
+type mockBackend struct {
+	resolver.Mock
+	useGeoIP bool
+}
+
+func (m mockBackend) GetDefaultBackend() defaults.Backend {
+	return defaults.Backend{
+		UseGeoIP: m.useGeoIP,
+	}
+}
+
+// writeDatabase writes a database in a temporary directory and sets the
+// path of the country database
+func writeDatabase(t *testing.T, content []byte) func() {
+	dir, err := ioutil.TempDir("", "geoip")
+	if err != nil {
+		t.Fatalf("unexpected error creating temporary directory: %v", err)
+	}
+
+	path := filepath.Join(dir, "GeoIP.dat")
+	if content != nil {
+		err = ioutil.WriteFile(path, content, 0644)
+		if err != nil {
+			t.Fatalf("unexpected error writing database: %v", err)
+		}
+	}
+
+	old := CountryDatabase
+	CountryDatabase = path
+
+	return func() {
+		CountryDatabase = old
+		os.RemoveAll(dir)
+	}
+}
+
+func TestParseWithoutAnnotations(t *testing.T) {
+	ing := buildIngress()
+
+	_, err := NewParser(mockBackend{useGeoIP: true}).Parse(ing)
+	if !ing_errors.IsMissingAnnotations(err) {
+		t.Errorf("expected a missing annotations error but returned %v", err)
+	}
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("geoip-redirect-url")] = "https://example.com"
+	ing.SetAnnotations(data)
+
+	_, err = NewParser(mockBackend{useGeoIP: true}).Parse(ing)
+	if !ing_errors.IsMissingAnnotations(err) {
+		t.Errorf("expected a missing annotations error but returned %v", err)
+	}
+}
+
+func TestParseAnnotations(t *testing.T) {
+	defer writeDatabase(t, countryDatabase)()
+
+	tests := map[string]struct {
+		allow     string
+		deny      string
+		redirect  string
+		expected  *Config
+		expectErr bool
+	}{
+		"allowed countries": {
+			allow:    "us, ca,,us",
+			expected: &Config{AllowCountries: []string{"CA", "US"}, DenyCountries: []string{}},
+		},
+		"denied countries with redirect": {
+			deny:     "KP,ir",
+			redirect: "https://example.com/unavailable",
+			expected: &Config{
+				AllowCountries: []string{},
+				DenyCountries:  []string{"IR", "KP"},
+				RedirectURL:    "https://example.com/unavailable",
+			},
+		},
+		"allowed and denied countries": {
+			allow:    "DE",
+			deny:     "A1",
+			expected: &Config{AllowCountries: []string{"DE"}, DenyCountries: []string{"A1"}},
+		},
+		"invalid country code": {
+			allow:     "US,USA",
+			expectErr: true,
+		},
+		"relative redirect URL": {
+			deny:      "US",
+			redirect:  "/unavailable",
+			expectErr: true,
+		},
+		"redirect URL with invalid characters": {
+			deny:      "US",
+			redirect:  "https://example.com/;return 200",
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		data := map[string]string{}
+		data[parser.GetAnnotationWithPrefix("geoip-allow-countries")] = test.allow
+		data[parser.GetAnnotationWithPrefix("geoip-deny-countries")] = test.deny
+		data[parser.GetAnnotationWithPrefix("geoip-redirect-url")] = test.redirect
+		ing := buildIngress()
+		ing.SetAnnotations(data)
+
+		i, err := NewParser(mockBackend{useGeoIP: true}).Parse(ing)
+		if test.expectErr {
+			if !ing_errors.IsLocationDenied(err) {
+				t.Errorf("%v: expected a location denied error but returned %v", name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(i, test.expected) {
+			t.Errorf("%v: expected %+v but returned %+v", name, test.expected, i)
+		}
+	}
+}
+
+func TestParseWithoutDatabase(t *testing.T) {
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("geoip-deny-countries")] = "US"
+	ing := buildIngress()
+	ing.SetAnnotations(data)
+
+	defer writeDatabase(t, countryDatabase)()
+
+	_, err := NewParser(mockBackend{useGeoIP: false}).Parse(ing)
+	if _, ok := IsDatabaseError(err); !ok {
+		t.Errorf("expected a database error with use-geoip disabled but returned %v", err)
+	}
+
+	defer writeDatabase(t, nil)()
+
+	_, err = NewParser(mockBackend{useGeoIP: true}).Parse(ing)
+	dbErr, ok := IsDatabaseError(err)
+	if !ok {
+		t.Fatalf("expected a database error with a missing database but returned %v", err)
+	}
+	if dbErr.Path != CountryDatabase {
+		t.Errorf("expected the path %v in the error but returned %v", CountryDatabase, dbErr.Path)
+	}
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ipdenylist
+
+import (
+	"reflect"
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func buildIngress() *extensions.Ingress {
+	return &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{
+			Backend: &extensions.IngressBackend{
+				ServiceName: "default-backend",
+				ServicePort: intstr.FromInt(80),
+			},
+		},
+	}
+}
+
+func TestParseAnnotations(t *testing.T) {
+	ing := buildIngress()
+	tests := map[string]struct {
+		net        string
+		expectCidr []string
+		expectErr  bool
+	}{
+		"test parse a valid net": {
+			net:        "10.0.0.0/24",
+			expectCidr: []string{"10.0.0.0/24"},
+		},
+		"test parse multiple valid cidr": {
+			net:        "2.2.2.2/32, 1.1.1.1/32,3.3.3.0/24 ,",
+			expectCidr: []string{"1.1.1.1/32", "2.2.2.2/32", "3.3.3.0/24"},
+		},
+		"test parse an ip address": {
+			net:        "10.0.0.1",
+			expectCidr: []string{"10.0.0.1"},
+		},
+		"test parse an invalid net": {
+			net:       "10.0.0.0/24,ww",
+			expectErr: true,
+		},
+	}
+
+	for testName, test := range tests {
+		data := map[string]string{}
+		data[parser.GetAnnotationWithPrefix("denylist-source-range")] = test.net
+		ing.SetAnnotations(data)
+
+		p := NewParser(&resolver.Mock{})
+		i, err := p.Parse(ing)
+		if test.expectErr {
+			if !ing_errors.IsLocationDenied(err) {
+				t.Errorf("%v: expected a location denied error but returned %v", testName, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", testName, err)
+			continue
+		}
+
+		sr, ok := i.(*SourceRange)
+		if !ok {
+			t.Errorf("%v: expected a SourceRange type", testName)
+			continue
+		}
+		if !reflect.DeepEqual(sr.CIDR, test.expectCidr) {
+			t.Errorf("%v: expected %v but returned %v", testName, test.expectCidr, sr.CIDR)
+		}
+	}
+}
+
+func TestParseAnnotationsWithoutDenylist(t *testing.T) {
+	ing := buildIngress()
+	_, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if !ing_errors.IsMissingAnnotations(err) {
+		t.Errorf("expected missing annotations error but returned %v", err)
+	}
+}
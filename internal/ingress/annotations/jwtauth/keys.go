@@ -0,0 +1,292 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jwtauth
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// publicKey is a key used to verify the signature of tokens. The kid is
+// the key ID of JWKS keys or the name of the entry that contains PEM keys
+type publicKey struct {
+	kid string
+	key crypto.PublicKey
+}
+
+// KeySet contains the RSA and ECDSA public keys used to verify the
+// signature of tokens
+type KeySet struct {
+	keys []publicKey
+}
+
+// Len returns the number of keys in the set
+func (ks *KeySet) Len() int {
+	if ks == nil {
+		return 0
+	}
+	return len(ks.keys)
+}
+
+// Key is a public key in the format used by nginx to verify the signature
+// of tokens
+type Key struct {
+	// ID is the key ID of JWKS keys or the name of the entry of PEM keys
+	ID string `json:"kid"`
+	// Type is the type of the key: RSA or EC
+	Type string `json:"kty"`
+	// PEM is the PEM encoded public key (PKIX)
+	PEM string `json:"pem"`
+}
+
+// Keys returns the keys of the set PEM encoded
+func (ks *KeySet) Keys() []Key {
+	keys := []Key{}
+	if ks == nil {
+		return keys
+	}
+
+	for _, k := range ks.keys {
+		der, err := x509.MarshalPKIXPublicKey(k.key)
+		if err != nil {
+			continue
+		}
+
+		kty := "RSA"
+		if _, ok := k.key.(*ecdsa.PublicKey); ok {
+			kty = "EC"
+		}
+
+		keys = append(keys, Key{
+			ID:   k.kid,
+			Type: kty,
+			PEM:  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+		})
+	}
+
+	return keys
+}
+
+// ParseKeys extracts the public keys of the entries of a Secret or ConfigMap.
+// Entries that start with '{' must contain a JSON Web Key Set (or a single
+// JSON Web Key) and the rest PEM encoded public keys or certificates.
+// Symmetric keys are not supported.
+func ParseKeys(data map[string][]byte) (*KeySet, error) {
+	names := []string{}
+	for name := range data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ks := &KeySet{keys: []publicKey{}}
+	for _, name := range names {
+		content := bytes.TrimSpace(data[name])
+		if len(content) == 0 {
+			continue
+		}
+
+		var keys []publicKey
+		var err error
+		if content[0] == '{' {
+			keys, err = parseJWKS(content)
+		} else {
+			keys, err = parsePEM(name, content)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid keys in %v: %v", name, err)
+		}
+
+		ks.keys = append(ks.keys, keys...)
+	}
+
+	if len(ks.keys) == 0 {
+		return nil, fmt.Errorf("no valid public keys found")
+	}
+
+	return ks, nil
+}
+
+// jwk contains the fields of the RSA and EC JSON Web Keys (RFC 7517)
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWKS parses a JSON Web Key Set or a single JSON Web Key. Keys with
+// an use different than signature and symmetric keys are ignored
+func parseJWKS(content []byte) ([]publicKey, error) {
+	set := struct {
+		Keys []jwk `json:"keys"`
+		jwk
+	}{}
+	err := json.Unmarshal(content, &set)
+	if err != nil {
+		return nil, err
+	}
+
+	jwks := set.Keys
+	if set.Kty != "" {
+		jwks = append(jwks, set.jwk)
+	}
+
+	keys := []publicKey{}
+	for _, k := range jwks {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		var key crypto.PublicKey
+		switch k.Kty {
+		case "RSA":
+			key, err = parseRSAJWK(k)
+		case "EC":
+			key, err = parseECJWK(k)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %v", k.Kid, err)
+		}
+
+		keys = append(keys, publicKey{kid: k.Kid, key: key})
+	}
+
+	return keys, nil
+}
+
+// decodeBase64URL decodes the base64url encoded values of JSON Web Keys
+func decodeBase64URL(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, fmt.Errorf("empty value")
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
+
+func parseRSAJWK(k jwk) (*rsa.PublicKey, error) {
+	n, err := decodeBase64URL(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %v", err)
+	}
+
+	e, err := decodeBase64URL(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %v", err)
+	}
+	if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("invalid exponent")
+	}
+
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+func parseECJWK(k jwk) (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch k.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+	}
+
+	x, err := decodeBase64URL(k.X)
+	if err != nil {
+		return nil, fmt.Errorf("invalid x coordinate: %v", err)
+	}
+
+	y, err := decodeBase64URL(k.Y)
+	if err != nil {
+		return nil, fmt.Errorf("invalid y coordinate: %v", err)
+	}
+
+	if !curve.IsOnCurve(x, y) {
+		return nil, fmt.Errorf("the point is not on the curve %v", k.Crv)
+	}
+
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+// parsePEM parses the PEM encoded public keys (PKIX or PKCS #1) and
+// certificates of an entry
+func parsePEM(name string, content []byte) ([]publicKey, error) {
+	keys := []publicKey{}
+	for {
+		var block *pem.Block
+		block, content = pem.Decode(content)
+		if block == nil {
+			break
+		}
+
+		var key interface{}
+		var err error
+		switch block.Type {
+		case "PUBLIC KEY":
+			key, err = x509.ParsePKIXPublicKey(block.Bytes)
+		case "RSA PUBLIC KEY":
+			key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+		case "CERTIFICATE":
+			var cert *x509.Certificate
+			cert, err = x509.ParseCertificate(block.Bytes)
+			if err == nil {
+				key = cert.PublicKey
+			}
+		default:
+			return nil, fmt.Errorf("unexpected PEM block type %v", block.Type)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch key.(type) {
+		case *rsa.PublicKey, *ecdsa.PublicKey:
+			keys = append(keys, publicKey{kid: name, key: key})
+		default:
+			return nil, fmt.Errorf("unsupported public key type %T", key)
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no valid PEM formatted block found")
+	}
+
+	return keys, nil
+}
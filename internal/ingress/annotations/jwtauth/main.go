@@ -0,0 +1,223 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jwtauth
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+var headerRegexp = regexp.MustCompile(`^[a-zA-Z\d\-_]+$`)
+
+// ClaimHeader defines a claim of the token sent to the backend in a header
+type ClaimHeader struct {
+	Claim  string `json:"claim"`
+	Header string `json:"header"`
+}
+
+// Config returns the JWT validation configuration for an Ingress rule
+type Config struct {
+	// Keys contains the name (<namespace>/<name>) of the Secret or
+	// ConfigMap with the public keys
+	Keys string `json:"keys"`
+	// Issuer is the required value of the claim iss
+	Issuer string `json:"issuer"`
+	// Audience contains the accepted values of the claim aud
+	Audience []string `json:"audience,omitempty"`
+	// ClaimHeaders contains the claims sent to the backend
+	ClaimHeaders []ClaimHeader `json:"claimHeaders,omitempty"`
+	// Realm is the realm of the WWW-Authenticate header of rejected requests
+	Realm string `json:"realm"`
+	// KeySet contains the parsed public keys. The keys are not compared in
+	// Equal because they are sent to nginx without a reload
+	KeySet *KeySet `json:"-"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if c1.Keys != c2.Keys {
+		return false
+	}
+	if c1.Issuer != c2.Issuer {
+		return false
+	}
+	if len(c1.Audience) != len(c2.Audience) {
+		return false
+	}
+	for i := range c1.Audience {
+		if c1.Audience[i] != c2.Audience[i] {
+			return false
+		}
+	}
+	if len(c1.ClaimHeaders) != len(c2.ClaimHeaders) {
+		return false
+	}
+	for i := range c1.ClaimHeaders {
+		if c1.ClaimHeaders[i] != c2.ClaimHeaders[i] {
+			return false
+		}
+	}
+	if c1.Realm != c2.Realm {
+		return false
+	}
+
+	return true
+}
+
+type jwtAuth struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new JWT validation annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return jwtAuth{r}
+}
+
+// keysData returns the content of the Secret or ConfigMap with the keys.
+// The Secret takes precedence when both exist
+func (a jwtAuth) keysData(name string) (map[string][]byte, error) {
+	secret, err := a.r.GetSecret(name)
+	if err == nil && secret != nil {
+		return secret.Data, nil
+	}
+
+	cm, cmErr := a.r.GetConfigMap(name)
+	if cmErr == nil && cm != nil {
+		data := map[string][]byte{}
+		for k, v := range cm.Data {
+			data[k] = []byte(v)
+		}
+		return data, nil
+	}
+
+	if err == nil {
+		err = cmErr
+	}
+	if err == nil {
+		err = fmt.Errorf("not found")
+	}
+
+	return nil, err
+}
+
+// parseClaimHeaders parses a comma separated list of <claim>:<header>
+// pairs. The claim is split from the header using the last ':' so claims
+// named with URIs are supported
+func parseClaimHeaders(input string) ([]ClaimHeader, error) {
+	headers := []ClaimHeader{}
+	for _, ch := range strings.Split(input, ",") {
+		ch = strings.TrimSpace(ch)
+		if ch == "" {
+			continue
+		}
+
+		i := strings.LastIndex(ch, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid claim header %q", ch)
+		}
+
+		claim := strings.TrimSpace(ch[:i])
+		header := strings.TrimSpace(ch[i+1:])
+		if claim == "" || !headerRegexp.MatchString(header) {
+			return nil, fmt.Errorf("invalid claim header %q", ch)
+		}
+
+		headers = append(headers, ClaimHeader{Claim: claim, Header: header})
+	}
+
+	return headers, nil
+}
+
+// Parse parses the annotations contained in the ingress rule used to
+// validate JWT bearer tokens with the public keys of a Secret or ConfigMap
+// located in the namespace of the Ingress
+func (a jwtAuth) Parse(ing *extensions.Ingress) (interface{}, error) {
+	s, err := parser.GetStringAnnotation("jwt-keys", ing)
+	if err != nil {
+		return nil, err
+	}
+
+	// the external authentication and the JWT validation are mutually
+	// exclusive: denying the access avoids exposing the locations without
+	// one of them
+	if _, err := parser.GetStringAnnotation("auth-url", ing); err == nil {
+		return nil, ing_errors.NewLocationDenied("jwt-keys cannot be used with auth-url")
+	}
+	if a.r.GetDefaultBackend().GlobalExternalAuthURL != "" {
+		enabled, err := parser.GetBoolAnnotation("enable-global-auth", ing)
+		if err != nil || enabled {
+			return nil, ing_errors.NewLocationDenied("jwt-keys cannot be used with the global external authentication (enable-global-auth must be false)")
+		}
+	}
+
+	name := fmt.Sprintf("%v/%v", ing.Namespace, s)
+	data, err := a.keysData(name)
+	if err != nil {
+		return nil, ing_errors.LocationDenied{
+			Reason: errors.Wrapf(err, "unexpected error reading JWT keys %v", name),
+		}
+	}
+
+	ks, err := ParseKeys(data)
+	if err != nil {
+		return nil, ing_errors.LocationDenied{
+			Reason: errors.Wrapf(err, "invalid JWT keys %v", name),
+		}
+	}
+
+	issuer, _ := parser.GetStringAnnotation("jwt-issuer", ing)
+
+	audience := []string{}
+	aud, _ := parser.GetStringAnnotation("jwt-audience", ing)
+	for _, a := range strings.Split(aud, ",") {
+		a = strings.TrimSpace(a)
+		if a != "" {
+			audience = append(audience, a)
+		}
+	}
+
+	ch, _ := parser.GetStringAnnotation("jwt-claims-headers", ing)
+	claimHeaders, err := parseClaimHeaders(ch)
+	if err != nil {
+		return nil, ing_errors.NewLocationDenied(err.Error())
+	}
+
+	realm, _ := parser.GetStringAnnotation("jwt-realm", ing)
+
+	return &Config{
+		Keys:         name,
+		Issuer:       issuer,
+		Audience:     audience,
+		ClaimHeaders: claimHeaders,
+		Realm:        realm,
+		KeySet:       ks,
+	}, nil
+}
@@ -0,0 +1,264 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jwtauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/defaults"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func buildIngress() *extensions.Ingress {
+	return &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{
+			Backend: &extensions.IngressBackend{
+				ServiceName: "default-backend",
+				ServicePort: intstr.FromInt(80),
+			},
+		},
+	}
+}
+
+type mockKeys struct {
+	resolver.Mock
+	pem           []byte
+	globalAuthURL string
+}
+
+func (m mockKeys) GetDefaultBackend() defaults.Backend {
+	return defaults.Backend{GlobalExternalAuthURL: m.globalAuthURL}
+}
+
+func (m mockKeys) GetSecret(name string) (*api.Secret, error) {
+	if name != "default/jwt-secret" {
+		return nil, errors.Errorf("there is no secret with name %v", name)
+	}
+
+	return &api.Secret{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Namespace: api.NamespaceDefault,
+			Name:      "jwt-secret",
+		},
+		Data: map[string][]byte{"key.pem": m.pem},
+	}, nil
+}
+
+func (m mockKeys) GetConfigMap(name string) (*api.ConfigMap, error) {
+	if name != "default/jwt-configmap" {
+		return nil, errors.Errorf("there is no configmap with name %v", name)
+	}
+
+	return &api.ConfigMap{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Namespace: api.NamespaceDefault,
+			Name:      "jwt-configmap",
+		},
+		Data: map[string]string{"key.pem": string(m.pem)},
+	}, nil
+}
+
+func newMockKeys(t *testing.T) mockKeys {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error generating key: %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("unexpected error encoding key: %v", err)
+	}
+
+	return mockKeys{pem: pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})}
+}
+
+func TestIngressWithoutJWTAuth(t *testing.T) {
+	ing := buildIngress()
+	_, err := NewParser(newMockKeys(t)).Parse(ing)
+	if !ing_errors.IsMissingAnnotations(err) {
+		t.Errorf("expected missing annotations error but returned %v", err)
+	}
+}
+
+func TestIngressJWTAuth(t *testing.T) {
+	m := newMockKeys(t)
+
+	for _, keys := range []string{"jwt-secret", "jwt-configmap"} {
+		ing := buildIngress()
+		data := map[string]string{}
+		data[parser.GetAnnotationWithPrefix("jwt-keys")] = keys
+		data[parser.GetAnnotationWithPrefix("jwt-issuer")] = "https://issuer.example.com"
+		data[parser.GetAnnotationWithPrefix("jwt-audience")] = "api, web"
+		data[parser.GetAnnotationWithPrefix("jwt-claims-headers")] = "sub:X-User, https://example.com/roles:X-Roles"
+		data[parser.GetAnnotationWithPrefix("jwt-realm")] = "api"
+		ing.SetAnnotations(data)
+
+		i, err := NewParser(m).Parse(ing)
+		if err != nil {
+			t.Fatalf("unexpected error with keys %v: %v", keys, err)
+		}
+		cfg, ok := i.(*Config)
+		if !ok {
+			t.Fatalf("expected a Config type")
+		}
+
+		if cfg.Keys != "default/"+keys {
+			t.Errorf("expected default/%v as keys but returned %v", keys, cfg.Keys)
+		}
+		if cfg.KeySet.Len() != 1 {
+			t.Errorf("expected one key but returned %v", cfg.KeySet.Len())
+		}
+		if cfg.Issuer != "https://issuer.example.com" {
+			t.Errorf("unexpected issuer %v", cfg.Issuer)
+		}
+		if len(cfg.Audience) != 2 || cfg.Audience[0] != "api" || cfg.Audience[1] != "web" {
+			t.Errorf("unexpected audience %v", cfg.Audience)
+		}
+		expected := []ClaimHeader{
+			{Claim: "sub", Header: "X-User"},
+			{Claim: "https://example.com/roles", Header: "X-Roles"},
+		}
+		if len(cfg.ClaimHeaders) != len(expected) {
+			t.Fatalf("expected %v claim headers but returned %v", len(expected), cfg.ClaimHeaders)
+		}
+		for i := range expected {
+			if cfg.ClaimHeaders[i] != expected[i] {
+				t.Errorf("expected claim header %v but returned %v", expected[i], cfg.ClaimHeaders[i])
+			}
+		}
+		if cfg.Realm != "api" {
+			t.Errorf("expected api as realm but returned %v", cfg.Realm)
+		}
+	}
+}
+
+func TestIngressJWTAuthInvalid(t *testing.T) {
+	m := newMockKeys(t)
+
+	tests := map[string]map[string]string{
+		"missing keys": {
+			"jwt-keys": "does-not-exist",
+		},
+		"invalid keys": {
+			"jwt-keys": "jwt-secret",
+		},
+		"invalid claim header": {
+			"jwt-keys":           "jwt-configmap",
+			"jwt-claims-headers": "sub:X User",
+		},
+		"claim header without claim": {
+			"jwt-keys":           "jwt-configmap",
+			"jwt-claims-headers": ":X-User",
+		},
+		"external authentication": {
+			"jwt-keys": "jwt-configmap",
+			"auth-url": "http://auth.example.com/check",
+		},
+	}
+
+	for name, annotations := range tests {
+		ing := buildIngress()
+		data := map[string]string{}
+		for k, v := range annotations {
+			data[parser.GetAnnotationWithPrefix(k)] = v
+		}
+		ing.SetAnnotations(data)
+
+		r := m
+		if name == "invalid keys" {
+			r = mockKeys{pem: []byte("-----BEGIN PUBLIC KEY-----\nZm9v\n-----END PUBLIC KEY-----\n")}
+		}
+
+		_, err := NewParser(r).Parse(ing)
+		if !ing_errors.IsLocationDenied(err) {
+			t.Errorf("%v: expected a location denied error but returned %v", name, err)
+		}
+	}
+}
+
+func TestIngressJWTAuthWithGlobalAuth(t *testing.T) {
+	m := newMockKeys(t)
+	m.globalAuthURL = "http://auth.example.com/check"
+
+	tests := map[string]bool{
+		"":      false,
+		"true":  false,
+		"false": true,
+	}
+
+	for enabled, valid := range tests {
+		ing := buildIngress()
+		data := map[string]string{}
+		data[parser.GetAnnotationWithPrefix("jwt-keys")] = "jwt-configmap"
+		if enabled != "" {
+			data[parser.GetAnnotationWithPrefix("enable-global-auth")] = enabled
+		}
+		ing.SetAnnotations(data)
+
+		_, err := NewParser(m).Parse(ing)
+		if valid && err != nil {
+			t.Errorf("enable-global-auth %q: unexpected error: %v", enabled, err)
+		}
+		if !valid && !ing_errors.IsLocationDenied(err) {
+			t.Errorf("enable-global-auth %q: expected a location denied error but returned %v", enabled, err)
+		}
+	}
+}
+
+func TestConfigEqual(t *testing.T) {
+	c1 := &Config{
+		Keys:         "default/jwt",
+		Audience:     []string{"api"},
+		ClaimHeaders: []ClaimHeader{{Claim: "sub", Header: "X-User"}},
+	}
+	c2 := &Config{
+		Keys:         "default/jwt",
+		Audience:     []string{"api"},
+		ClaimHeaders: []ClaimHeader{{Claim: "sub", Header: "X-User"}},
+	}
+	if !c1.Equal(c2) {
+		t.Errorf("expected equal configurations")
+	}
+
+	c2.Keys = "default/other"
+	if c1.Equal(c2) {
+		t.Errorf("expected different configurations when the keys change")
+	}
+
+	c2.Keys = "default/jwt"
+	c2.ClaimHeaders = []ClaimHeader{{Claim: "email", Header: "X-User"}}
+	if c1.Equal(c2) {
+		t.Errorf("expected different configurations when the claim headers change")
+	}
+}
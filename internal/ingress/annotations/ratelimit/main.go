@@ -17,14 +17,17 @@ limitations under the License.
 package ratelimit
 
 import (
+	"crypto/sha1"
 	"encoding/base64"
 	"fmt"
+	"regexp"
 	"sort"
 	"strings"
 
 	extensions "k8s.io/api/extensions/v1beta1"
 
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 	"k8s.io/ingress-nginx/internal/net"
 )
@@ -38,6 +41,13 @@ const (
 	defSharedSize = 5
 )
 
+var (
+	headerRegexp   = regexp.MustCompile(`^[a-zA-Z\d\-_]+$`)
+	cookieRegexp   = regexp.MustCompile(`^[a-zA-Z\d_]+$`)
+	claimRegexp    = regexp.MustCompile(`^[a-zA-Z\d_\-.:/]+$`)
+	variableRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z\d_]*$`)
+)
+
 // Config returns rate limit configuration for an Ingress rule limiting the
 // number of connections per IP address and/or connections per second.
 // If you both annotations are specified in a single Ingress rule, RPS limits
@@ -59,6 +69,25 @@ type Config struct {
 	ID string `json:"id"`
 
 	Whitelist []string `json:"whitelist"`
+
+	// Key is the nginx variable used as key of the limits instead of the
+	// client address. Requests with an empty key use the client address
+	Key string `json:"key,omitempty"`
+
+	// JWTClaim is the claim of the bearer token used as key. The claim is
+	// extracted by the location in the variable of Key
+	JWTClaim string `json:"jwtClaim,omitempty"`
+
+	// DryRun indicates the requests over the limits are only logged and
+	// counted instead of rejected
+	DryRun bool `json:"dryRun"`
+
+	// StatusCode is the status code of the responses to rejected requests
+	StatusCode int `json:"statusCode"`
+
+	// ResponseBody is the body of the responses to rejected requests.
+	// An empty value returns the default nginx error page
+	ResponseBody string `json:"responseBody,omitempty"`
 }
 
 // Equal tests for equality between two RateLimit types
@@ -90,6 +119,21 @@ func (rt1 *Config) Equal(rt2 *Config) bool {
 	if rt1.Name != rt2.Name {
 		return false
 	}
+	if rt1.Key != rt2.Key {
+		return false
+	}
+	if rt1.JWTClaim != rt2.JWTClaim {
+		return false
+	}
+	if rt1.DryRun != rt2.DryRun {
+		return false
+	}
+	if rt1.StatusCode != rt2.StatusCode {
+		return false
+	}
+	if rt1.ResponseBody != rt2.ResponseBody {
+		return false
+	}
 	if len(rt1.Whitelist) != len(rt2.Whitelist) {
 		return false
 	}
@@ -187,7 +231,42 @@ func (a ratelimit) Parse(ing *extensions.Ingress) (interface{}, error) {
 		}, nil
 	}
 
+	keySpec, _ := parser.GetStringAnnotation("limit-key", ing)
+	key, claim, err := parseKey(keySpec)
+	if err != nil {
+		return nil, err
+	}
+	// the claim is read before the validation of the token. Without the
+	// validation a client can send a different claim in each request to
+	// avoid the limits
+	if claim != "" {
+		if _, err := parser.GetStringAnnotation("jwt-keys", ing); err != nil {
+			return nil, ing_errors.NewLocationDenied("the rate limit key jwt-claim requires the annotation jwt-keys")
+		}
+	}
+
 	zoneName := fmt.Sprintf("%v_%v", ing.GetNamespace(), ing.GetName())
+	if keySpec != "" {
+		// zones with a different key must not share the state
+		hash := fmt.Sprintf("%x", sha1.Sum([]byte(keySpec)))
+		zoneName = fmt.Sprintf("%v_%v", zoneName, hash[:8])
+	}
+	id := encode(zoneName)
+	if claim != "" {
+		key = fmt.Sprintf("$limit_claim_%v", id)
+	}
+
+	dryRun, _ := parser.GetBoolAnnotation("limit-dry-run", ing)
+
+	statusCode, err := parser.GetIntAnnotation("limit-status-code", ing)
+	if err != nil {
+		statusCode = defBackend.LimitReqStatusCode
+	}
+	if statusCode < 400 || statusCode > 599 {
+		return nil, ing_errors.NewLocationDenied(fmt.Sprintf("invalid rate limit status code %v", statusCode))
+	}
+
+	body, _ := parser.GetStringAnnotation("limit-response-body", ing)
 
 	return &Config{
 		Connections: Zone{
@@ -211,11 +290,45 @@ func (a ratelimit) Parse(ing *extensions.Ingress) (interface{}, error) {
 		LimitRate:      lr,
 		LimitRateAfter: lra,
 		Name:           zoneName,
-		ID:             encode(zoneName),
+		ID:             id,
 		Whitelist:      cidrs,
+		Key:            key,
+		JWTClaim:       claim,
+		DryRun:         dryRun,
+		StatusCode:     statusCode,
+		ResponseBody:   body,
 	}, nil
 }
 
+// parseKey returns the nginx variable or the JWT claim used as key of the
+// limits. The source of the key is a header (header:<name>), a cookie
+// (cookie:<name>), a claim of the bearer token (jwt-claim:<name>) or an
+// nginx variable (variable:<name>). An empty value uses the client address.
+func parseKey(s string) (string, string, error) {
+	if s == "" {
+		return "", "", nil
+	}
+
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return "", "", ing_errors.NewLocationDenied("invalid rate limit key")
+	}
+
+	source, name := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+	switch {
+	case source == "header" && headerRegexp.MatchString(name):
+		return fmt.Sprintf("$http_%v", strings.Replace(strings.ToLower(name), "-", "_", -1)), "", nil
+	case source == "cookie" && cookieRegexp.MatchString(name):
+		return fmt.Sprintf("$cookie_%v", name), "", nil
+	case source == "jwt-claim" && claimRegexp.MatchString(name):
+		return "", name, nil
+	case source == "variable" && variableRegexp.MatchString(name):
+		return fmt.Sprintf("$%v", name), "", nil
+	}
+
+	return "", "", ing_errors.NewLocationDenied("invalid rate limit key")
+}
+
 func parseCIDRs(s string) ([]string, error) {
 	if s == "" {
 		return []string{}, nil
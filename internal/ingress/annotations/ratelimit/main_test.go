@@ -17,6 +17,7 @@ limitations under the License.
 package ratelimit
 
 import (
+	"strings"
 	"testing"
 
 	api "k8s.io/api/core/v1"
@@ -26,6 +27,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
 	"k8s.io/ingress-nginx/internal/ingress/defaults"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
@@ -70,8 +72,9 @@ type mockBackend struct {
 
 func (m mockBackend) GetDefaultBackend() defaults.Backend {
 	return defaults.Backend{
-		LimitRateAfter: 0,
-		LimitRate:      0,
+		LimitRateAfter:     0,
+		LimitRate:          0,
+		LimitReqStatusCode: 503,
 	}
 }
 
@@ -130,3 +133,121 @@ func TestBadRateLimiting(t *testing.T) {
 		t.Errorf("expected 10 in limit by limitrate but %v was returend", rateLimit.LimitRate)
 	}
 }
+
+func TestRateLimitKey(t *testing.T) {
+	ing := buildIngress()
+
+	tests := []struct {
+		key   string
+		nginx string
+		claim string
+	}{
+		{"header:X-API-Key", "$http_x_api_key", ""},
+		{"cookie:session_id", "$cookie_session_id", ""},
+		{"variable:remote_user", "$remote_user", ""},
+		{"jwt-claim:tenant", "", "tenant"},
+	}
+
+	names := map[string]bool{}
+	for _, test := range tests {
+		data := map[string]string{}
+		data[parser.GetAnnotationWithPrefix("limit-rps")] = "10"
+		data[parser.GetAnnotationWithPrefix("limit-key")] = test.key
+		data[parser.GetAnnotationWithPrefix("jwt-keys")] = "keys"
+		ing.SetAnnotations(data)
+
+		i, err := NewParser(mockBackend{}).Parse(ing)
+		if err != nil {
+			t.Fatalf("unexpected error with key %v: %v", test.key, err)
+		}
+		rateLimit := i.(*Config)
+
+		if test.claim != "" {
+			test.nginx = "$limit_claim_" + rateLimit.ID
+		}
+		if rateLimit.Key != test.nginx {
+			t.Errorf("expected %v as key of %v but returned %v", test.nginx, test.key, rateLimit.Key)
+		}
+		if rateLimit.JWTClaim != test.claim {
+			t.Errorf("expected %v as claim of %v but returned %v", test.claim, test.key, rateLimit.JWTClaim)
+		}
+
+		if !strings.HasPrefix(rateLimit.Name, "default_foo_") || names[rateLimit.Name] {
+			t.Errorf("expected a zone name for the key %v but returned %v", test.key, rateLimit.Name)
+		}
+		names[rateLimit.Name] = true
+	}
+
+	for _, key := range []string{"header", "header:", "header:X API", "cookie:a-b", "jwt-claim:a\"b", "variable:1a", "query:foo"} {
+		data := map[string]string{}
+		data[parser.GetAnnotationWithPrefix("limit-rps")] = "10"
+		data[parser.GetAnnotationWithPrefix("limit-key")] = key
+		ing.SetAnnotations(data)
+
+		_, err := NewParser(mockBackend{}).Parse(ing)
+		if !ing_errors.IsLocationDenied(err) {
+			t.Errorf("expected a location denied error with key %v but returned %v", key, err)
+		}
+	}
+
+	// the claims of tokens that are not validated cannot be used as key
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("limit-rps")] = "10"
+	data[parser.GetAnnotationWithPrefix("limit-key")] = "jwt-claim:tenant"
+	ing.SetAnnotations(data)
+
+	_, err := NewParser(mockBackend{}).Parse(ing)
+	if !ing_errors.IsLocationDenied(err) {
+		t.Errorf("expected a location denied error with a jwt-claim key without jwt-keys but returned %v", err)
+	}
+}
+
+func TestRateLimitResponse(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("limit-rps")] = "10"
+	ing.SetAnnotations(data)
+
+	i, err := NewParser(mockBackend{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rateLimit := i.(*Config)
+	if rateLimit.DryRun {
+		t.Errorf("expected dry-run disabled by default")
+	}
+	if rateLimit.StatusCode != 503 {
+		t.Errorf("expected the default status code 503 but %v was returned", rateLimit.StatusCode)
+	}
+
+	data[parser.GetAnnotationWithPrefix("limit-dry-run")] = "true"
+	data[parser.GetAnnotationWithPrefix("limit-status-code")] = "429"
+	data[parser.GetAnnotationWithPrefix("limit-response-body")] = `{"error":"too many requests"}`
+	ing.SetAnnotations(data)
+
+	i, err = NewParser(mockBackend{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rateLimit = i.(*Config)
+	if !rateLimit.DryRun {
+		t.Errorf("expected dry-run enabled")
+	}
+	if rateLimit.StatusCode != 429 {
+		t.Errorf("expected 429 as status code but %v was returned", rateLimit.StatusCode)
+	}
+	if rateLimit.ResponseBody != `{"error":"too many requests"}` {
+		t.Errorf("unexpected response body %v", rateLimit.ResponseBody)
+	}
+
+	for _, code := range []string{"200", "302", "600"} {
+		data[parser.GetAnnotationWithPrefix("limit-status-code")] = code
+		ing.SetAnnotations(data)
+
+		_, err := NewParser(mockBackend{}).Parse(ing)
+		if !ing_errors.IsLocationDenied(err) {
+			t.Errorf("expected a location denied error with status code %v but returned %v", code, err)
+		}
+	}
+}
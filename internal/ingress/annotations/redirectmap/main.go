@@ -0,0 +1,310 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redirectmap
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+const (
+	// regexPrefix is the prefix of the sources that are regular
+	// expressions. The prefix ~* is used for case insensitive matching
+	regexPrefix = "~"
+
+	preservePath  = "preserve-path"
+	preserveQuery = "preserve-query"
+)
+
+var (
+	// variableRegex matches the references to the captures of the regular
+	// expression of the source in the target ($1, $name or ${name})
+	variableRegex = regexp.MustCompile(`\$(\{[A-Za-z0-9_]+\}|[A-Za-z0-9_]+)`)
+
+	validCodes = map[int]bool{
+		http.StatusMovedPermanently:  true,
+		http.StatusFound:             true,
+		http.StatusSeeOther:          true,
+		http.StatusTemporaryRedirect: true,
+		http.StatusPermanentRedirect: true,
+	}
+)
+
+// Rule is a redirect of a redirect map
+type Rule struct {
+	// Source is the path of the requests. An exact path or a regular
+	// expression with the prefix ~ (~* for case insensitive matching)
+	Source string `json:"source"`
+	// Target is the URL or path of the redirect. It can contain the
+	// captures of the regular expression of the source
+	Target string `json:"target"`
+	// Code is the status code of the redirect
+	Code int `json:"code"`
+	// PreservePath appends the path of the request to the target
+	PreservePath bool `json:"preservePath"`
+	// PreserveQuery appends the query string of the request to the target
+	PreserveQuery bool `json:"preserveQuery"`
+}
+
+// IsRegex checks if the source of the rule is a regular expression
+func (r Rule) IsRegex() bool {
+	return strings.HasPrefix(r.Source, regexPrefix)
+}
+
+// Config contains the redirect rules of a ConfigMap
+type Config struct {
+	// Name of the ConfigMap (<namespace>/<name>)
+	Name  string `json:"name"`
+	Rules []Rule `json:"rules,omitempty"`
+	// InvalidRules contains the rules that were not loaded and the reason
+	InvalidRules []string `json:"invalidRules,omitempty"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if c1.Name != c2.Name {
+		return false
+	}
+
+	if len(c1.Rules) != len(c2.Rules) {
+		return false
+	}
+	for i := range c1.Rules {
+		if c1.Rules[i] != c2.Rules[i] {
+			return false
+		}
+	}
+
+	if len(c1.InvalidRules) != len(c2.InvalidRules) {
+		return false
+	}
+	for i := range c1.InvalidRules {
+		if c1.InvalidRules[i] != c2.InvalidRules[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+type redirectMap struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new redirect map annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return redirectMap{r}
+}
+
+// Parse parses the annotation contained in the ingress rule used to
+// redirect the requests using the rules of a ConfigMap. The ConfigMap must
+// be located in the namespace of the Ingress to avoid exposing the
+// ConfigMaps of other namespaces
+func (a redirectMap) Parse(ing *extensions.Ingress) (interface{}, error) {
+	name, err := parser.GetStringAnnotation("redirect-map", ing)
+	if err != nil {
+		return nil, err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ing_errors.ErrMissingAnnotations
+	}
+	if strings.Contains(name, "/") {
+		return nil, ing_errors.NewInvalidAnnotationContent("redirect-map", name)
+	}
+	name = fmt.Sprintf("%v/%v", ing.Namespace, name)
+
+	cm, err := a.r.GetConfigMap(name)
+	if err != nil {
+		glog.Warningf("error reading redirect map %v of Ingress %v/%v: %v", name, ing.Namespace, ing.Name, err)
+		return nil, ing_errors.NewInvalidAnnotationContent("redirect-map", name)
+	}
+
+	return ParseRules(name, cm.Data), nil
+}
+
+// ParseRules parses the redirect rules of the entries of a ConfigMap. Each
+// line of an entry contains a rule with the format:
+//
+//   <source> <target> [code] [preserve-path] [preserve-query]
+//
+// Empty lines and lines starting with # are ignored. The default code is
+// 301. A source can be used only once, the next rules with the same source
+// are invalid
+func ParseRules(name string, data map[string]string) *Config {
+	cfg := &Config{
+		Name:         name,
+		Rules:        []Rule{},
+		InvalidRules: []string{},
+	}
+
+	keys := []string{}
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	sources := map[string]bool{}
+	for _, k := range keys {
+		for _, line := range strings.Split(data[k], "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+
+			rule, err := parseRule(line)
+			if err == nil && sources[strings.ToLower(rule.Source)] {
+				err = fmt.Errorf("duplicated source")
+			}
+			if err != nil {
+				cfg.InvalidRules = append(cfg.InvalidRules, fmt.Sprintf("%v (%v)", line, err))
+				continue
+			}
+
+			sources[strings.ToLower(rule.Source)] = true
+			cfg.Rules = append(cfg.Rules, rule)
+		}
+	}
+
+	return cfg
+}
+
+// parseRule parses and validates a line of a redirect map
+func parseRule(line string) (Rule, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return Rule{}, fmt.Errorf("a source and a target are required")
+	}
+
+	rule := Rule{
+		Source: fields[0],
+		Target: fields[1],
+		Code:   http.StatusMovedPermanently,
+	}
+
+	for _, f := range fields[2:] {
+		switch f {
+		case preservePath:
+			rule.PreservePath = true
+		case preserveQuery:
+			rule.PreserveQuery = true
+		default:
+			code, err := strconv.Atoi(f)
+			if err != nil || !validCodes[code] {
+				return Rule{}, fmt.Errorf("invalid option %v", f)
+			}
+			rule.Code = code
+		}
+	}
+
+	if strings.ContainsAny(rule.Source+rule.Target, "\"'`") {
+		return Rule{}, fmt.Errorf("quotes are not allowed")
+	}
+
+	captures, err := sourceCaptures(rule)
+	if err != nil {
+		return Rule{}, err
+	}
+
+	err = validateTarget(rule.Target, captures)
+	if err != nil {
+		return Rule{}, err
+	}
+
+	return rule, nil
+}
+
+// sourceCaptures validates the source of a rule and returns the names of
+// the captures of the regular expression that can be used in the target
+func sourceCaptures(rule Rule) (map[string]bool, error) {
+	captures := map[string]bool{}
+
+	if !rule.IsRegex() {
+		if !strings.HasPrefix(rule.Source, "/") || strings.ContainsAny(rule.Source, "$\\{};") {
+			return nil, fmt.Errorf("the source must be a path or a regular expression")
+		}
+		return captures, nil
+	}
+
+	expr := strings.TrimPrefix(strings.TrimPrefix(rule.Source, regexPrefix), "*")
+	if expr == "" {
+		return nil, fmt.Errorf("empty regular expression")
+	}
+
+	// named captures use the PCRE syntax (?<name>...)
+	re, err := regexp.Compile(strings.Replace(expr, "(?<", "(?P<", -1))
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %v", err)
+	}
+
+	for i, name := range re.SubexpNames() {
+		if i == 0 {
+			continue
+		}
+		captures[strconv.Itoa(i)] = true
+		if name != "" {
+			captures[name] = true
+		}
+	}
+
+	return captures, nil
+}
+
+// validateTarget checks the target is an absolute http/s URL or a path
+// and that it only references captures of the source
+func validateTarget(target string, captures map[string]bool) error {
+	for _, m := range variableRegex.FindAllStringSubmatch(target, -1) {
+		name := strings.Trim(m[1], "{}")
+		if !captures[name] {
+			return fmt.Errorf("the target references an unknown capture %v", m[0])
+		}
+	}
+
+	if strings.ContainsAny(variableRegex.ReplaceAllString(target, ""), "$\\{};") {
+		return fmt.Errorf("invalid characters in the target")
+	}
+
+	if strings.HasPrefix(target, "/") {
+		return nil
+	}
+
+	u, err := url.Parse(variableRegex.ReplaceAllString(target, "x"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("the target must be a path or an absolute http/https URL")
+	}
+
+	return nil
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redirectmap
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func buildIngress() *extensions.Ingress {
+	return &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{
+			Backend: &extensions.IngressBackend{
+				ServiceName: "default-backend",
+				ServicePort: intstr.FromInt(80),
+			},
+		},
+	}
+}
+
+type mockConfigMap struct {
+	resolver.Mock
+}
+
+func (m mockConfigMap) GetConfigMap(name string) (*api.ConfigMap, error) {
+	if name != "default/redirects" {
+		return nil, fmt.Errorf("configmap %v not found", name)
+	}
+
+	return &api.ConfigMap{
+		Data: map[string]string{
+			"rules": "/old https://example.com/new\n/other /new 302",
+		},
+	}, nil
+}
+
+func TestParseAnnotation(t *testing.T) {
+	ing := buildIngress()
+
+	_, err := NewParser(mockConfigMap{}).Parse(ing)
+	if !ing_errors.IsMissingAnnotations(err) {
+		t.Errorf("expected a missing annotations error but returned %v", err)
+	}
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("redirect-map")] = "redirects"
+	ing.SetAnnotations(data)
+
+	i, err := NewParser(mockConfigMap{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cfg := i.(*Config)
+	if cfg.Name != "default/redirects" {
+		t.Errorf("expected the configmap default/redirects but returned %v", cfg.Name)
+	}
+	if len(cfg.Rules) != 2 {
+		t.Errorf("expected 2 rules but returned %v", len(cfg.Rules))
+	}
+
+	// the namespace cannot be used to read the configmaps of other namespaces
+	for _, name := range []string{"missing", "default/redirects", "other/redirects"} {
+		data[parser.GetAnnotationWithPrefix("redirect-map")] = name
+		ing.SetAnnotations(data)
+
+		_, err = NewParser(mockConfigMap{}).Parse(ing)
+		if !ing_errors.IsInvalidContent(err) {
+			t.Errorf("%v: expected an invalid content error but returned %v", name, err)
+		}
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	data := map[string]string{
+		"b-blog": `
+# blog posts
+~^/blog/(?<slug>[^/]+)/?$ https://blog.example.com/${slug} 308 preserve-query
+~*^/DOCS/(.*)$ https://docs.example.com/$1
+`,
+		"a-pages": `
+/old-page https://www.example.com/new-page
+/shop https://shop.example.com 302 preserve-path preserve-query
+/relative /new
+`,
+	}
+
+	expected := []Rule{
+		{Source: "/old-page", Target: "https://www.example.com/new-page", Code: 301},
+		{Source: "/shop", Target: "https://shop.example.com", Code: 302, PreservePath: true, PreserveQuery: true},
+		{Source: "/relative", Target: "/new", Code: 301},
+		{Source: "~^/blog/(?<slug>[^/]+)/?$", Target: "https://blog.example.com/${slug}", Code: 308, PreserveQuery: true},
+		{Source: "~*^/DOCS/(.*)$", Target: "https://docs.example.com/$1", Code: 301},
+	}
+
+	cfg := ParseRules("default/redirects", data)
+	if !reflect.DeepEqual(cfg.Rules, expected) {
+		t.Errorf("expected %+v but returned %+v", expected, cfg.Rules)
+	}
+	if len(cfg.InvalidRules) != 0 {
+		t.Errorf("unexpected invalid rules: %v", cfg.InvalidRules)
+	}
+}
+
+func TestParseInvalidRules(t *testing.T) {
+	invalid := []string{
+		"/only-source",
+		"old https://example.com",
+		"/old example.com/new",
+		"/old ftp://example.com/new",
+		"/old https://example.com 200",
+		"/old https://example.com 301 preserve-all",
+		"/old https://example.com/$host",
+		"~^/(?<a>.*)$ https://example.com/$b",
+		"~^/(.*)$ https://example.com/$2",
+		"~^/(.*$ https://example.com",
+		"/old https://example.com/\";",
+		"/old-page https://example.com/duplicated",
+		"/OLD-PAGE https://example.com/duplicated",
+	}
+
+	for _, rule := range invalid {
+		cfg := ParseRules("default/redirects", map[string]string{
+			"a": "/old-page https://example.com/new-page",
+			"b": rule,
+		})
+
+		if len(cfg.Rules) != 1 || len(cfg.InvalidRules) != 1 {
+			t.Errorf("expected the rule %q to be invalid but returned %v", rule, cfg.Rules)
+		}
+	}
+}
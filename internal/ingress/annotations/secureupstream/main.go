@@ -18,18 +18,36 @@ package secureupstream
 
 import (
 	"fmt"
+	"regexp"
 
 	"github.com/pkg/errors"
 	extensions "k8s.io/api/extensions/v1beta1"
 
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
+var (
+	protocolsRegex = regexp.MustCompile(`^(SSLv2|SSLv3|TLSv1|TLSv1\.1|TLSv1\.2|TLSv1\.3)( (SSLv2|SSLv3|TLSv1|TLSv1\.1|TLSv1\.2|TLSv1\.3))*$`)
+
+	// serverNameRegex matches a hostname or a nginx variable
+	serverNameRegex = regexp.MustCompile(`^([a-zA-Z\d]([a-zA-Z\d\-]*[a-zA-Z\d])?(\.[a-zA-Z\d]([a-zA-Z\d\-]*[a-zA-Z\d])?)*|\$[a-zA-Z_][a-zA-Z\d_]*)$`)
+)
+
 // Config describes SSL backend configuration
 type Config struct {
 	Secure bool                 `json:"secure"`
 	CACert resolver.AuthSSLCert `json:"caCert"`
+	// ClientCert contains the certificate and key presented to the backend
+	ClientCert resolver.AuthSSLCert `json:"clientCert"`
+	// ServerName overrides the name used to verify the certificate of the
+	// backend and sent in the SNI extension
+	ServerName string `json:"serverName"`
+	// VerifyDepth sets the verification depth of the certificate chain of the backend
+	VerifyDepth int `json:"verifyDepth"`
+	// Protocols restricts the TLS protocols used to connect to the backend
+	Protocols string `json:"protocols"`
 }
 
 type su struct {
@@ -46,6 +64,11 @@ func NewParser(r resolver.Resolver) parser.IngressAnnotation {
 func (a su) Parse(ing *extensions.Ingress) (interface{}, error) {
 	s, _ := parser.GetBoolAnnotation("secure-backends", ing)
 	ca, _ := parser.GetStringAnnotation("secure-verify-ca-secret", ing)
+	cs, _ := parser.GetStringAnnotation("secure-client-secret", ing)
+	sn, _ := parser.GetStringAnnotation("secure-server-name", ing)
+	vd, _ := parser.GetIntAnnotation("secure-verify-depth", ing)
+	p, _ := parser.GetStringAnnotation("secure-protocols", ing)
+
 	secure := &Config{
 		Secure: s,
 		CACert: resolver.AuthSSLCert{},
@@ -54,6 +77,38 @@ func (a su) Parse(ing *extensions.Ingress) (interface{}, error) {
 		return secure,
 			errors.Errorf("trying to use CA from secret %v/%v on a non secure backend", ing.Namespace, ca)
 	}
+	if !s && cs != "" {
+		return secure,
+			errors.Errorf("trying to use client certificate from secret %v/%v on a non secure backend", ing.Namespace, cs)
+	}
+	if !s {
+		return secure, nil
+	}
+
+	if vd < 0 {
+		return secure, ing_errors.NewInvalidAnnotationContent("secure-verify-depth", vd)
+	}
+	if p != "" && !protocolsRegex.MatchString(p) {
+		return secure, ing_errors.NewInvalidAnnotationContent("secure-protocols", p)
+	}
+	if sn != "" && !serverNameRegex.MatchString(sn) {
+		return secure, ing_errors.NewInvalidAnnotationContent("secure-server-name", sn)
+	}
+	secure.ServerName = sn
+	secure.VerifyDepth = vd
+	secure.Protocols = p
+
+	if cs != "" {
+		clientCert, err := a.r.GetAuthCertificate(fmt.Sprintf("%v/%v", ing.Namespace, cs))
+		if err != nil {
+			return secure, errors.Wrap(err, "error obtaining client certificate")
+		}
+		if clientCert == nil || clientCert.PemFileName == "" || clientCert.PemFileName == clientCert.CAFileName {
+			return secure, errors.Errorf("secret %v/%v does not contain a certificate and key", ing.Namespace, cs)
+		}
+		secure.ClientCert = *clientCert
+	}
+
 	if ca == "" {
 		return secure, nil
 	}
@@ -64,8 +119,6 @@ func (a su) Parse(ing *extensions.Ingress) (interface{}, error) {
 	if caCert == nil {
 		return secure, nil
 	}
-	return &Config{
-		Secure: s,
-		CACert: *caCert,
-	}, nil
+	secure.CACert = *caCert
+	return secure, nil
 }
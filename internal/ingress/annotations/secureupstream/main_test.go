@@ -26,6 +26,7 @@ import (
 
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
@@ -120,3 +121,102 @@ func TestSecretOnNonSecure(t *testing.T) {
 		t.Error("Expected CA secret on non secure backend error on ingress")
 	}
 }
+
+func TestClientCertificate(t *testing.T) {
+	ing := buildIngress()
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("secure-backends")] = "true"
+	data[parser.GetAnnotationWithPrefix("secure-client-secret")] = "client"
+	data[parser.GetAnnotationWithPrefix("secure-server-name")] = "internal.example.com"
+	data[parser.GetAnnotationWithPrefix("secure-verify-depth")] = "2"
+	data[parser.GetAnnotationWithPrefix("secure-protocols")] = "TLSv1.2 TLSv1.3"
+	ing.SetAnnotations(data)
+
+	i, err := NewParser(mockCfg{
+		certs: map[string]resolver.AuthSSLCert{
+			"default/client": {Secret: "default/client", PemFileName: "/ssl/default-client.pem"},
+		},
+	}).Parse(ing)
+	if err != nil {
+		t.Fatalf("Unexpected error on ingress: %v", err)
+	}
+	u, ok := i.(*Config)
+	if !ok {
+		t.Fatalf("expected a Config type")
+	}
+	if u.ClientCert.PemFileName != "/ssl/default-client.pem" {
+		t.Errorf("expected client certificate /ssl/default-client.pem but returned %v", u.ClientCert.PemFileName)
+	}
+	if u.ServerName != "internal.example.com" {
+		t.Errorf("expected server name internal.example.com but returned %v", u.ServerName)
+	}
+	if u.VerifyDepth != 2 {
+		t.Errorf("expected verify depth 2 but returned %v", u.VerifyDepth)
+	}
+	if u.Protocols != "TLSv1.2 TLSv1.3" {
+		t.Errorf("expected protocols TLSv1.2 TLSv1.3 but returned %v", u.Protocols)
+	}
+}
+
+func TestClientCertificateWithoutKey(t *testing.T) {
+	ing := buildIngress()
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("secure-backends")] = "true"
+	data[parser.GetAnnotationWithPrefix("secure-client-secret")] = "client"
+	ing.SetAnnotations(data)
+
+	_, err := NewParser(mockCfg{
+		certs: map[string]resolver.AuthSSLCert{
+			"default/client": {CAFileName: "/ssl/ca-default-client.pem", PemFileName: "/ssl/ca-default-client.pem"},
+		},
+	}).Parse(ing)
+	if err == nil {
+		t.Error("Expected error using a secret without certificate and key")
+	}
+}
+
+func TestInvalidProtocols(t *testing.T) {
+	ing := buildIngress()
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("secure-backends")] = "true"
+	data[parser.GetAnnotationWithPrefix("secure-protocols")] = "TLSv1.2; return 200"
+	ing.SetAnnotations(data)
+
+	_, err := NewParser(mockCfg{}).Parse(ing)
+	if err == nil {
+		t.Error("Expected error with invalid protocols")
+	}
+}
+
+func TestServerName(t *testing.T) {
+	tests := map[string]bool{
+		"internal.example.com":              true,
+		"backend-1":                         true,
+		"$host":                             true,
+		"$proxy_host":                       true,
+		"example.com; return 200":           false,
+		"example.com\nproxy_ssl_verify off": false,
+		"-example.com":                      false,
+		"$host$request_uri":                 false,
+		"${host}":                           false,
+	}
+
+	for sn, valid := range tests {
+		ing := buildIngress()
+		data := map[string]string{}
+		data[parser.GetAnnotationWithPrefix("secure-backends")] = "true"
+		data[parser.GetAnnotationWithPrefix("secure-server-name")] = sn
+		ing.SetAnnotations(data)
+
+		i, err := NewParser(mockCfg{}).Parse(ing)
+		if valid && err != nil {
+			t.Errorf("%q: unexpected error: %v", sn, err)
+		}
+		if valid && err == nil && i.(*Config).ServerName != sn {
+			t.Errorf("%q: expected the server name but %v returned", sn, i.(*Config).ServerName)
+		}
+		if !valid && !ing_errors.IsInvalidContent(err) {
+			t.Errorf("%q: expected an invalid content error but %v returned", sn, err)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sslsecondary
+
+import (
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+	"k8s.io/ingress-nginx/internal/k8s"
+)
+
+type sslSecondary struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new secondary SSL certificate annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return sslSecondary{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+// used to configure an additional certificate (with a different key type)
+// in the servers defined in the TLS section of the Ingress
+func (s sslSecondary) Parse(ing *extensions.Ingress) (interface{}, error) {
+	secret, err := parser.GetStringAnnotation("ssl-secondary-secret", ing)
+	if err != nil {
+		return "", err
+	}
+
+	_, _, err = k8s.ParseNameNS(secret)
+	if err != nil {
+		return "", ing_errors.NewInvalidAnnotationContent("ssl-secondary-secret", secret)
+	}
+
+	return secret, nil
+}
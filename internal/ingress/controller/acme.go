@@ -0,0 +1,339 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	apiv1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/internal/net/acme"
+)
+
+const (
+	// acmeAnnotation enables the issuance of the certificates of an Ingress
+	// using ACME
+	acmeAnnotation = "tls-acme"
+
+	// acmeAccountKey is the key of the account private key in the account secret
+	acmeAccountKey = "acme-account.key"
+
+	acmeSyncPeriod = time.Minute
+
+	acmeMinBackoff = time.Minute
+	acmeMaxBackoff = 6 * time.Hour
+)
+
+// acmeIssuer obtains and renews the certificates of the Ingresses with the
+// annotation tls-acme
+type acmeIssuer struct {
+	client *acme.Client
+	solver acmeSolver
+
+	// accountSecret secret (<namespace>/<name>) where the account key is stored
+	accountSecret string
+	renewBefore   time.Duration
+
+	mu sync.Mutex
+	// backoff contains the time of the next attempt of failed certificates
+	backoff map[string]acmeBackoff
+}
+
+type acmeBackoff struct {
+	next  time.Time
+	delay time.Duration
+}
+
+func newACMEIssuer(config *Configuration) *acmeIssuer {
+	// without a secret the challenges can only be answered by the replica
+	// that requests the certificates
+	var solver acmeSolver = acme.NewHTTP01Solver()
+	if config.ACMEAccountSecret != "" {
+		s, err := newACMESecretSolver(config.Client, config.ACMEAccountSecret)
+		if err != nil {
+			glog.Warningf("error creating ACME challenge secret solver, using memory: %v", err)
+		} else {
+			solver = s
+		}
+	}
+
+	return &acmeIssuer{
+		client: &acme.Client{
+			DirectoryURL: config.ACMEDirectoryURL,
+			Email:        config.ACMEEmail,
+			HTTPClient:   &http.Client{Timeout: 30 * time.Second},
+		},
+		solver:        solver,
+		accountSecret: config.ACMEAccountSecret,
+		renewBefore:   config.ACMERenewBefore,
+		backoff:       map[string]acmeBackoff{},
+	}
+}
+
+// ACMEChallengeHandler returns the handler that serves the HTTP-01
+// challenges or nil if ACME is not enabled
+func (n *NGINXController) ACMEChallengeHandler() http.Handler {
+	if n.acme == nil {
+		return nil
+	}
+
+	return n.acme.solver
+}
+
+// syncACMECertificates issues the missing certificates of the Ingresses
+// with the annotation tls-acme and renews the ones about to expire. Only
+// the leader elected to update the status of the Ingresses places orders,
+// so ACME requires the status update
+func (n *NGINXController) syncACMECertificates() {
+	if n.isShuttingDown {
+		return
+	}
+
+	if n.syncStatus == nil || !n.syncStatus.IsLeader() {
+		return
+	}
+
+	for _, ing := range n.store.ListIngresses() {
+		enabled, err := parser.GetBoolAnnotation(acmeAnnotation, ing)
+		if err != nil || !enabled {
+			continue
+		}
+
+		for _, tls := range ing.Spec.TLS {
+			n.syncACMECertificate(ing, tls)
+		}
+	}
+}
+
+// isACMEHost checks if the certificate of a host of an Ingress is issued
+// using ACME
+func isACMEHost(ing *extensions.Ingress, host string) bool {
+	enabled, err := parser.GetBoolAnnotation(acmeAnnotation, ing)
+	if err != nil || !enabled {
+		return false
+	}
+
+	for _, tls := range ing.Spec.TLS {
+		for _, h := range tls.Hosts {
+			if h == host {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (n *NGINXController) syncACMECertificate(ing *extensions.Ingress, tls extensions.IngressTLS) {
+	if tls.SecretName == "" || len(tls.Hosts) == 0 {
+		return
+	}
+
+	key := fmt.Sprintf("%v/%v", ing.Namespace, tls.SecretName)
+	for _, host := range tls.Hosts {
+		if strings.HasPrefix(host, "*.") {
+			glog.Warningf("ACME HTTP-01 challenges cannot be used for wildcard host %v (secret %v)", host, key)
+			return
+		}
+	}
+
+	cert, _ := n.store.GetTLSCertificate(key)
+	reason := acmeRenewalReason(cert, tls.Hosts, n.acme.renewBefore, time.Now())
+	if reason == "" {
+		return
+	}
+
+	if !n.acme.ready(key) {
+		return
+	}
+
+	glog.Infof("requesting ACME certificate for %v (secret %v): %v", strings.Join(tls.Hosts, ", "), key, reason)
+
+	err := n.issueACMECertificate(ing.Namespace, tls)
+	n.acme.done(key, err)
+	if err != nil {
+		glog.Warningf("error obtaining ACME certificate for %v: %v", key, err)
+		n.recorder.Eventf(ing, apiv1.EventTypeWarning, "ACME", "error obtaining certificate for secret %v: %v", key, err)
+		return
+	}
+
+	n.recorder.Eventf(ing, apiv1.EventTypeNormal, "ACME", "certificate for %v stored in secret %v", strings.Join(tls.Hosts, ", "), key)
+}
+
+func (n *NGINXController) issueACMECertificate(namespace string, tls extensions.IngressTLS) error {
+	if n.acme.client.Key == nil {
+		key, err := loadACMEAccountKey(n.cfg.Client, n.acme.accountSecret)
+		if err != nil {
+			return fmt.Errorf("error reading ACME account key: %v", err)
+		}
+		n.acme.client.Key = key
+	}
+
+	certPEM, keyPEM, err := n.acme.client.ObtainCertificate(tls.Hosts, n.acme.solver)
+	if err != nil {
+		return err
+	}
+
+	return storeTLSSecret(n.cfg.Client, namespace, tls.SecretName, certPEM, keyPEM)
+}
+
+// acmeRenewalReason returns why a new certificate is required or an
+// empty string if the current certificate is valid
+func acmeRenewalReason(cert *ingress.SSLCert, hosts []string, renewBefore time.Duration, now time.Time) string {
+	if cert == nil || cert.Certificate == nil {
+		return "certificate not found"
+	}
+
+	if now.Add(renewBefore).After(cert.ExpireTime) {
+		return fmt.Sprintf("certificate expires at %v", cert.ExpireTime)
+	}
+
+	for _, host := range hosts {
+		if err := verifyHostname(host, cert.Certificate); err != nil {
+			return fmt.Sprintf("certificate is not valid for %v", host)
+		}
+	}
+
+	return ""
+}
+
+// ready returns true if the certificate can be requested, i.e. the last
+// attempt did not fail or the backoff period is over
+func (a *acmeIssuer) ready(key string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	b, ok := a.backoff[key]
+	return !ok || time.Now().After(b.next)
+}
+
+// done records the result of an attempt doubling the backoff period after
+// consecutive errors
+func (a *acmeIssuer) done(key string, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err == nil {
+		delete(a.backoff, key)
+		return
+	}
+
+	delay := acmeMinBackoff
+	if b, ok := a.backoff[key]; ok {
+		delay = b.delay * 2
+		if delay > acmeMaxBackoff {
+			delay = acmeMaxBackoff
+		}
+	}
+
+	a.backoff[key] = acmeBackoff{next: time.Now().Add(delay), delay: delay}
+}
+
+// loadACMEAccountKey reads the ACME account key from a secret creating it
+// if it does not exist. Without a secret a new key is generated, which
+// registers a new account each time the controller starts.
+func loadACMEAccountKey(client clientset.Interface, secret string) (*ecdsa.PrivateKey, error) {
+	if secret == "" {
+		glog.Warningf("using a temporary ACME account (flag --acme-account-secret not specified)")
+		return acme.GenerateAccountKey()
+	}
+
+	ns, name, err := k8s.ParseNameNS(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := client.CoreV1().Secrets(ns).Get(name, metav1.GetOptions{})
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return nil, err
+	}
+	exists := err == nil
+
+	if exists {
+		if data, ok := s.Data[acmeAccountKey]; ok {
+			return acme.DecodeAccountKey(data)
+		}
+	}
+
+	key, err := acme.GenerateAccountKey()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := acme.EncodeAccountKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		_, err = client.CoreV1().Secrets(ns).Create(&apiv1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name},
+			Data:       map[string][]byte{acmeAccountKey: data},
+		})
+		return key, err
+	}
+
+	if s.Data == nil {
+		s.Data = map[string][]byte{}
+	}
+	s.Data[acmeAccountKey] = data
+	_, err = client.CoreV1().Secrets(ns).Update(s)
+	return key, err
+}
+
+// storeTLSSecret creates or updates a secret of type kubernetes.io/tls
+func storeTLSSecret(client clientset.Interface, namespace, name string, cert, key []byte) error {
+	s, err := client.CoreV1().Secrets(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		if !k8sErrors.IsNotFound(err) {
+			return err
+		}
+
+		_, err = client.CoreV1().Secrets(namespace).Create(&apiv1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Type:       apiv1.SecretTypeTLS,
+			Data: map[string][]byte{
+				apiv1.TLSCertKey:       cert,
+				apiv1.TLSPrivateKeyKey: key,
+			},
+		})
+		return err
+	}
+
+	if s.Data == nil {
+		s.Data = map[string][]byte{}
+	}
+	s.Data[apiv1.TLSCertKey] = cert
+	s.Data[apiv1.TLSPrivateKeyKey] = key
+
+	_, err = client.CoreV1().Secrets(namespace).Update(s)
+	return err
+}
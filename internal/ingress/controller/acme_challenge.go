@@ -0,0 +1,177 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	apiv1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/internal/net/acme"
+)
+
+const (
+	// acmeChallengeSuffix is appended to the name of the account secret to
+	// obtain the name of the secret with the pending challenges
+	acmeChallengeSuffix = "-challenges"
+
+	// acmeChallengePropagation is the time given to the replicas to receive
+	// a new challenge before the ACME server is asked to validate it
+	acmeChallengePropagation = 5 * time.Second
+)
+
+// acmeSolver presents the HTTP-01 challenges and answers the validation
+// requests of the ACME server
+type acmeSolver interface {
+	acme.Solver
+	http.Handler
+}
+
+// acmeSecretSolver stores the key authorizations of the pending HTTP-01
+// challenges in a secret. Only the leader presents challenges but the ACME
+// server can send the validation requests to any replica, which read the
+// secret using an informer
+type acmeSecretSolver struct {
+	client    clientset.Interface
+	namespace string
+	name      string
+
+	propagation time.Duration
+
+	// mu serializes the updates of the secret
+	mu       sync.Mutex
+	store    cache.Store
+	informer cache.Controller
+}
+
+// newACMESecretSolver creates a solver that stores the challenges in the
+// secret <account secret>-challenges
+func newACMESecretSolver(client clientset.Interface, accountSecret string) (*acmeSecretSolver, error) {
+	ns, name, err := k8s.ParseNameNS(accountSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &acmeSecretSolver{
+		client:      client,
+		namespace:   ns,
+		name:        name + acmeChallengeSuffix,
+		propagation: acmeChallengePropagation,
+	}
+
+	lw := cache.NewListWatchFromClient(client.CoreV1().RESTClient(), "secrets", s.namespace,
+		fields.OneTermEqualSelector("metadata.name", s.name))
+	s.store, s.informer = cache.NewInformer(lw, &apiv1.Secret{}, 0, cache.ResourceEventHandlerFuncs{})
+
+	return s, nil
+}
+
+// Run starts the informer of the secret
+func (s *acmeSecretSolver) Run(stopCh chan struct{}) {
+	s.informer.Run(stopCh)
+}
+
+// Present stores the key authorization of a token in the secret and waits
+// until the replicas receive it
+func (s *acmeSecretSolver) Present(token, keyAuth string) error {
+	err := s.update(func(data map[string][]byte) {
+		data[token] = []byte(keyAuth)
+	})
+	if err != nil {
+		return err
+	}
+
+	time.Sleep(s.propagation)
+	return nil
+}
+
+// CleanUp removes a token from the secret
+func (s *acmeSecretSolver) CleanUp(token string) {
+	err := s.update(func(data map[string][]byte) {
+		delete(data, token)
+	})
+	if err != nil {
+		glog.Warningf("error removing ACME challenge from secret %v/%v: %v", s.namespace, s.name, err)
+	}
+}
+
+// update applies a change to the entries of the secret creating it if it
+// does not exist
+func (s *acmeSecretSolver) update(change func(map[string][]byte)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secret, err := s.client.CoreV1().Secrets(s.namespace).Get(s.name, metav1.GetOptions{})
+	if err != nil {
+		if !k8sErrors.IsNotFound(err) {
+			return err
+		}
+
+		data := map[string][]byte{}
+		change(data)
+		_, err = s.client.CoreV1().Secrets(s.namespace).Create(&apiv1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: s.namespace, Name: s.name},
+			Data:       data,
+		})
+		return err
+	}
+
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	change(secret.Data)
+
+	_, err = s.client.CoreV1().Secrets(s.namespace).Update(secret)
+	return err
+}
+
+// ServeHTTP returns the key authorization of the token in the request path
+func (s *acmeSecretSolver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, acme.ChallengePath) {
+		http.NotFound(w, r)
+		return
+	}
+
+	token := strings.TrimPrefix(r.URL.Path, acme.ChallengePath)
+
+	obj, exists, err := s.store.GetByKey(fmt.Sprintf("%v/%v", s.namespace, s.name))
+	if err != nil || !exists {
+		http.NotFound(w, r)
+		return
+	}
+
+	keyAuth, ok := obj.(*apiv1.Secret).Data[token]
+	if !ok || token == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Write(keyAuth)
+}
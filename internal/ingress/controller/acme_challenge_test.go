@@ -0,0 +1,82 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	"k8s.io/ingress-nginx/internal/net/acme"
+)
+
+func TestACMESecretSolver(t *testing.T) {
+	client := testclient.NewSimpleClientset()
+
+	s := &acmeSecretSolver{
+		client:    client,
+		namespace: "default",
+		name:      "acme-account" + acmeChallengeSuffix,
+		store:     cache.NewStore(cache.MetaNamespaceKeyFunc),
+	}
+
+	// syncs the store as the informer of a replica
+	sync := func() {
+		secret, err := client.CoreV1().Secrets(s.namespace).Get(s.name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error reading challenges: %v", err)
+		}
+		s.store.Add(secret)
+	}
+
+	serve := func(token string) (int, string) {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", acme.ChallengePath+token, nil))
+		body, _ := ioutil.ReadAll(w.Body)
+		return w.Code, string(body)
+	}
+
+	if code, _ := serve("abc"); code != http.StatusNotFound {
+		t.Errorf("expected 404 without secret but %v returned", code)
+	}
+
+	for _, token := range []string{"abc", "def"} {
+		if err := s.Present(token, token+".xyz"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	sync()
+
+	if code, body := serve("abc"); code != http.StatusOK || body != "abc.xyz" {
+		t.Errorf("expected 200 and abc.xyz but %v and %v returned", code, body)
+	}
+
+	s.CleanUp("abc")
+	sync()
+
+	if code, _ := serve("abc"); code != http.StatusNotFound {
+		t.Errorf("expected 404 after the clean up but %v returned", code)
+	}
+	if code, body := serve("def"); code != http.StatusOK || body != "def.xyz" {
+		t.Errorf("expected 200 and def.xyz but %v and %v returned", code, body)
+	}
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"crypto/x509"
+	"fmt"
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+)
+
+func TestACMERenewalReason(t *testing.T) {
+	now := time.Now()
+	cert := &ingress.SSLCert{
+		Certificate: &x509.Certificate{DNSNames: []string{"foo.bar", "www.foo.bar"}},
+		ExpireTime:  now.Add(60 * 24 * time.Hour),
+	}
+	renewBefore := 30 * 24 * time.Hour
+
+	testCases := []struct {
+		title   string
+		cert    *ingress.SSLCert
+		hosts   []string
+		now     time.Time
+		renewal bool
+	}{
+		{"missing certificate", nil, []string{"foo.bar"}, now, true},
+		{"empty certificate", &ingress.SSLCert{}, []string{"foo.bar"}, now, true},
+		{"valid certificate", cert, []string{"foo.bar", "www.foo.bar"}, now, false},
+		{"certificate about to expire", cert, []string{"foo.bar"}, now.Add(31 * 24 * time.Hour), true},
+		{"host not in the certificate", cert, []string{"foo.bar", "new.foo.bar"}, now, true},
+	}
+
+	for _, tc := range testCases {
+		reason := acmeRenewalReason(tc.cert, tc.hosts, renewBefore, tc.now)
+		if (reason != "") != tc.renewal {
+			t.Errorf("%v: expected renewal %v but returned %q", tc.title, tc.renewal, reason)
+		}
+	}
+}
+
+func TestIsACMEHost(t *testing.T) {
+	ing := &extensions.Ingress{
+		Spec: extensions.IngressSpec{
+			TLS: []extensions.IngressTLS{
+				{Hosts: []string{"foo.bar"}, SecretName: "foo-tls"},
+			},
+		},
+	}
+
+	if isACMEHost(ing, "foo.bar") {
+		t.Errorf("expected no ACME host without annotation")
+	}
+
+	ing.SetAnnotations(map[string]string{parser.GetAnnotationWithPrefix(acmeAnnotation): "true"})
+	if !isACMEHost(ing, "foo.bar") {
+		t.Errorf("expected foo.bar to be an ACME host")
+	}
+	if isACMEHost(ing, "other.bar") {
+		t.Errorf("expected other.bar not to be an ACME host")
+	}
+}
+
+func TestACMEBackoff(t *testing.T) {
+	a := &acmeIssuer{backoff: map[string]acmeBackoff{}}
+
+	if !a.ready("default/foo") {
+		t.Fatalf("expected a certificate without previous attempts to be ready")
+	}
+
+	a.done("default/foo", fmt.Errorf("failed"))
+	if a.ready("default/foo") {
+		t.Errorf("expected a failed certificate not to be ready")
+	}
+	if a.backoff["default/foo"].delay != acmeMinBackoff {
+		t.Errorf("expected a delay of %v but %v returned", acmeMinBackoff, a.backoff["default/foo"].delay)
+	}
+
+	a.done("default/foo", fmt.Errorf("failed"))
+	if a.backoff["default/foo"].delay != 2*acmeMinBackoff {
+		t.Errorf("expected a delay of %v but %v returned", 2*acmeMinBackoff, a.backoff["default/foo"].delay)
+	}
+
+	a.done("default/foo", nil)
+	if !a.ready("default/foo") {
+		t.Errorf("expected a certificate to be ready after a successful attempt")
+	}
+}
+
+func TestStoreTLSSecret(t *testing.T) {
+	client := testclient.NewSimpleClientset(&apiv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "existing"},
+		Data:       map[string][]byte{"other": []byte("value")},
+	})
+
+	err := storeTLSSecret(client, "default", "new", []byte("cert"), []byte("key"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s, err := client.CoreV1().Secrets("default").Get("new", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Type != apiv1.SecretTypeTLS || string(s.Data[apiv1.TLSCertKey]) != "cert" || string(s.Data[apiv1.TLSPrivateKeyKey]) != "key" {
+		t.Errorf("unexpected secret %+v", s)
+	}
+
+	err = storeTLSSecret(client, "default", "existing", []byte("cert"), []byte("key"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s, _ = client.CoreV1().Secrets("default").Get("existing", metav1.GetOptions{})
+	if string(s.Data[apiv1.TLSCertKey]) != "cert" || string(s.Data["other"]) != "value" {
+		t.Errorf("unexpected secret %+v", s)
+	}
+}
+
+func TestLoadACMEAccountKey(t *testing.T) {
+	client := testclient.NewSimpleClientset()
+
+	key, err := loadACMEAccountKey(client, "kube-system/acme-account")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	loaded, err := loadACMEAccountKey(client, "kube-system/acme-account")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if key.D.Cmp(loaded.D) != 0 {
+		t.Errorf("expected the account key stored in the secret")
+	}
+
+	_, err = loadACMEAccountKey(client, "invalid")
+	if err == nil {
+		t.Errorf("expected an error but none returned")
+	}
+}
@@ -0,0 +1,267 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	apiv1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/k8s"
+)
+
+// certificateCheckPeriod is the interval between checks of the health of
+// the certificates used in Ingress rules
+const certificateCheckPeriod = time.Minute
+
+// CertificateStatus describes the health of a certificate used in the TLS
+// section of Ingress rules
+type CertificateStatus struct {
+	// Secret is the secret containing the certificate (<namespace>/<name>)
+	Secret string `json:"secret"`
+	// CN contains all the common names defined in the certificate
+	CN []string `json:"cn"`
+	// NotBefore is the start of the validity period of the certificate
+	NotBefore time.Time `json:"notBefore"`
+	// ExpireTime is the end of the validity period of the certificate
+	ExpireTime time.Time `json:"expireTime"`
+	// DaysToExpiry is the number of days until the certificate expires
+	DaysToExpiry float64 `json:"daysToExpiry"`
+	// Valid indicates the certificate is in its validity period and the
+	// intermediate CA certificates chain is not broken
+	Valid bool `json:"valid"`
+	// ChainError is the reason of the failure of the chain completion
+	ChainError string `json:"chainError,omitempty"`
+	// OCSPNextUpdate is the expiration of the OCSP response fetched by the controller
+	OCSPNextUpdate *time.Time `json:"ocspNextUpdate,omitempty"`
+	// Ingresses list of Ingresses (<namespace>/<name>) using the certificate
+	Ingresses []string `json:"ingresses"`
+	// HostMismatches list of hosts of the Ingresses not covered by the certificate
+	HostMismatches []string `json:"hostMismatches,omitempty"`
+}
+
+// certificateIssue is a problem with a certificate reported using an event
+// on the Ingress and the Secret
+type certificateIssue struct {
+	ingress *extensions.Ingress
+	secret  string
+	pemSHA  string
+	message string
+}
+
+func (i certificateIssue) key() string {
+	return fmt.Sprintf("%v/%v|%v|%v|%v", i.ingress.Namespace, i.ingress.Name, i.secret, i.pemSHA, i.message)
+}
+
+// certificateReporter keeps track of the certificate issues already
+// reported to avoid duplicated events
+type certificateReporter struct {
+	mu       sync.Mutex
+	reported sets.String
+}
+
+// CertificateStatus returns the health of the certificates used in the TLS
+// section of the Ingress rules
+func (n *NGINXController) CertificateStatus() []CertificateStatus {
+	statuses, _ := checkCertificates(n.store.ListIngresses(), n.store.GetLocalSecret, n.cfg.SSLExpireWarningDays, time.Now())
+	return statuses
+}
+
+// reportCertificateIssues emits a warning event on the Ingress and the
+// Secret for each new issue found in the certificates and updates the
+// certificate metrics
+func (n *NGINXController) reportCertificateIssues() {
+	now := time.Now()
+	statuses, issues := checkCertificates(n.store.ListIngresses(), n.store.GetLocalSecret, n.cfg.SSLExpireWarningDays, now)
+	setCertificateMetrics(statuses)
+
+	secrets := n.store.ListLocalSecrets()
+	setCRLMetrics(secrets, now)
+	for _, s := range secrets {
+		if s.CRLFileName != "" && !s.CRLNextUpdate.IsZero() && now.After(s.CRLNextUpdate) {
+			// nginx rejects all the client certificates when the CRL expired
+			glog.Warningf("the certificate revocation list of secret %v expired at %v", k8s.MetaNamespaceKey(s), s.CRLNextUpdate)
+		}
+	}
+
+	n.certReporter.mu.Lock()
+	defer n.certReporter.mu.Unlock()
+
+	reported := sets.NewString()
+	for _, issue := range issues {
+		key := issue.key()
+		if reported.Has(key) {
+			continue
+		}
+
+		reported.Insert(key)
+		if n.certReporter.reported.Has(key) {
+			continue
+		}
+
+		glog.Warningf("ingress %v/%v: %v", issue.ingress.Namespace, issue.ingress.Name, issue.message)
+		n.recorder.Event(issue.ingress, apiv1.EventTypeWarning, "CERTIFICATE", issue.message)
+
+	}
+
+	// the secret receives one event per issue, regardless the number of
+	// Ingresses using it
+	for _, issue := range issues {
+		key := fmt.Sprintf("%v|%v|%v", issue.secret, issue.pemSHA, issue.message)
+		if reported.Has(key) {
+			continue
+		}
+
+		reported.Insert(key)
+		if n.certReporter.reported.Has(key) {
+			continue
+		}
+
+		secret, err := n.store.GetSecret(issue.secret)
+		if err == nil {
+			n.recorder.Event(secret, apiv1.EventTypeWarning, "CERTIFICATE", issue.message)
+		}
+	}
+
+	// issues that are not present anymore are reported again if they reappear
+	n.certReporter.reported = reported
+}
+
+// checkCertificates returns the status of the certificates referenced in
+// the TLS section of the Ingress rules and the issues found: hosts not
+// covered by the certificate, certificates about to expire and broken chains
+func checkCertificates(ingresses []*extensions.Ingress, getCertificate func(string) (*ingress.SSLCert, error),
+	warningDays int, now time.Time) ([]CertificateStatus, []certificateIssue) {
+	warning := time.Duration(warningDays) * 24 * time.Hour
+
+	statuses := map[string]*CertificateStatus{}
+	issues := []certificateIssue{}
+
+	for _, ing := range ingresses {
+		for _, tls := range ing.Spec.TLS {
+			if tls.SecretName == "" {
+				continue
+			}
+
+			key := fmt.Sprintf("%v/%v", ing.Namespace, tls.SecretName)
+			cert, err := getCertificate(key)
+			if err != nil || cert.Certificate == nil {
+				continue
+			}
+
+			status, ok := statuses[key]
+			if !ok {
+				status = newCertificateStatus(key, cert, now)
+				statuses[key] = status
+			}
+
+			// certificate issues are reported in all the Ingresses using it
+			if cert.ExpireTime.Before(now.Add(warning)) {
+				issues = append(issues, certificateIssue{ing, key, cert.PemSHA,
+					fmt.Sprintf("certificate of secret %v expires at %v (less than %v days)", key, cert.ExpireTime.UTC().Format(time.RFC3339), warningDays)})
+			}
+
+			if cert.ChainError != "" {
+				issues = append(issues, certificateIssue{ing, key, cert.PemSHA,
+					fmt.Sprintf("completion of the chain of the certificate of secret %v failed: %v", key, cert.ChainError)})
+			}
+
+			status.Ingresses = append(status.Ingresses, fmt.Sprintf("%v/%v", ing.Namespace, ing.Name))
+
+			for _, host := range tls.Hosts {
+				if certificateCoversHost(cert, host) {
+					continue
+				}
+
+				status.HostMismatches = append(status.HostMismatches, host)
+				issues = append(issues, certificateIssue{ing, key, cert.PemSHA,
+					fmt.Sprintf("certificate of secret %v is not valid for host %v", key, host)})
+			}
+		}
+	}
+
+	result := []CertificateStatus{}
+	for _, status := range statuses {
+		status.Ingresses = sets.NewString(status.Ingresses...).List()
+		status.HostMismatches = sets.NewString(status.HostMismatches...).List()
+		result = append(result, *status)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Secret < result[j].Secret
+	})
+
+	return result, issues
+}
+
+func newCertificateStatus(key string, cert *ingress.SSLCert, now time.Time) *CertificateStatus {
+	status := &CertificateStatus{
+		Secret:       key,
+		CN:           cert.CN,
+		NotBefore:    cert.Certificate.NotBefore,
+		ExpireTime:   cert.ExpireTime,
+		DaysToExpiry: cert.ExpireTime.Sub(now).Hours() / 24,
+		ChainError:   cert.ChainError,
+	}
+
+	status.Valid = cert.ChainError == "" && now.After(status.NotBefore) && now.Before(status.ExpireTime)
+
+	if cert.OCSPResponseFileName != "" && !cert.OCSPNextUpdate.IsZero() {
+		next := cert.OCSPNextUpdate
+		status.OCSPNextUpdate = &next
+	}
+
+	return status
+}
+
+// certificateCoversHost returns true if the host is listed in the SAN or
+// common name of the certificate
+func certificateCoversHost(cert *ingress.SSLCert, host string) bool {
+	if cert.Certificate.VerifyHostname(host) == nil {
+		return true
+	}
+
+	return verifyHostname(host, cert.Certificate) == nil
+}
+
+// checkSecondaryCertificate verifies an additional certificate can be
+// served next to the primary certificate of a host: it must cover the host
+// and use a different key type (nginx selects the certificate using the
+// key types supported by the client)
+func checkSecondaryCertificate(host string, primary, secondary *ingress.SSLCert) error {
+	if secondary.Certificate == nil || secondary.KeyType == "" {
+		return fmt.Errorf("the secret does not contain a certificate and key")
+	}
+
+	if secondary.KeyType == primary.KeyType {
+		return fmt.Errorf("the key type %v is the same as the one of the primary certificate", secondary.KeyType)
+	}
+
+	if !certificateCoversHost(secondary, host) {
+		return fmt.Errorf("the certificate is not valid for host %v", host)
+	}
+
+	return nil
+}
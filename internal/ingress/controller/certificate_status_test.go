@@ -0,0 +1,136 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"crypto/x509"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+)
+
+func TestCheckCertificates(t *testing.T) {
+	now := time.Now()
+
+	certs := map[string]*ingress.SSLCert{
+		"default/valid": {
+			Certificate: &x509.Certificate{DNSNames: []string{"foo.bar"}, NotBefore: now.Add(-time.Hour)},
+			ExpireTime:  now.Add(90 * 24 * time.Hour),
+			PemSHA:      "1",
+		},
+		"default/expiring": {
+			Certificate: &x509.Certificate{DNSNames: []string{"*.example.com"}, NotBefore: now.Add(-time.Hour)},
+			ExpireTime:  now.Add(5 * 24 * time.Hour),
+			PemSHA:      "2",
+			ChainError:  "unable to fetch the intermediate certificate",
+		},
+	}
+	getCertificate := func(key string) (*ingress.SSLCert, error) {
+		if cert, ok := certs[key]; ok {
+			return cert, nil
+		}
+		return nil, fmt.Errorf("secret %v not found", key)
+	}
+
+	newIngress := func(name string, tls ...extensions.IngressTLS) *extensions.Ingress {
+		return &extensions.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec:       extensions.IngressSpec{TLS: tls},
+		}
+	}
+
+	ingresses := []*extensions.Ingress{
+		newIngress("foo", extensions.IngressTLS{Hosts: []string{"foo.bar", "www.foo.bar"}, SecretName: "valid"}),
+		newIngress("app", extensions.IngressTLS{Hosts: []string{"app.example.com"}, SecretName: "expiring"}),
+		newIngress("api", extensions.IngressTLS{Hosts: []string{"api.example.com"}, SecretName: "expiring"}),
+		newIngress("missing", extensions.IngressTLS{Hosts: []string{"missing.bar"}, SecretName: "missing"}),
+	}
+
+	statuses, issues := checkCertificates(ingresses, getCertificate, 10, now)
+
+	if len(statuses) != 2 {
+		t.Fatalf("expected 2 certificates but %v returned", len(statuses))
+	}
+
+	expiring := statuses[0]
+	if expiring.Secret != "default/expiring" || expiring.Valid || expiring.DaysToExpiry < 4.9 || expiring.DaysToExpiry > 5.1 {
+		t.Errorf("unexpected status %+v", expiring)
+	}
+	if !reflect.DeepEqual(expiring.Ingresses, []string{"default/api", "default/app"}) {
+		t.Errorf("unexpected ingresses %v", expiring.Ingresses)
+	}
+
+	valid := statuses[1]
+	if valid.Secret != "default/valid" || !valid.Valid || !reflect.DeepEqual(valid.HostMismatches, []string{"www.foo.bar"}) {
+		t.Errorf("unexpected status %+v", valid)
+	}
+
+	// host mismatch in foo, expiration and chain in app and api
+	expected := map[string]int{"default/foo": 1, "default/app": 2, "default/api": 2}
+	found := map[string]int{}
+	for _, issue := range issues {
+		found[fmt.Sprintf("%v/%v", issue.ingress.Namespace, issue.ingress.Name)]++
+	}
+	if !reflect.DeepEqual(expected, found) {
+		t.Errorf("expected issues %v but %v returned", expected, found)
+	}
+}
+
+func TestCheckSecondaryCertificate(t *testing.T) {
+	rsa := &ingress.SSLCert{
+		Certificate: &x509.Certificate{DNSNames: []string{"foo.bar"}},
+		KeyType:     x509.RSA.String(),
+	}
+
+	testCases := []struct {
+		title     string
+		host      string
+		secondary *ingress.SSLCert
+		err       bool
+	}{
+		{"ECDSA certificate", "foo.bar", &ingress.SSLCert{
+			Certificate: &x509.Certificate{DNSNames: []string{"foo.bar"}},
+			KeyType:     x509.ECDSA.String(),
+		}, false},
+		{"wildcard ECDSA certificate", "foo.bar", &ingress.SSLCert{
+			Certificate: &x509.Certificate{DNSNames: []string{"*.bar"}},
+			KeyType:     x509.ECDSA.String(),
+		}, false},
+		{"same key type", "foo.bar", &ingress.SSLCert{
+			Certificate: &x509.Certificate{DNSNames: []string{"foo.bar"}},
+			KeyType:     x509.RSA.String(),
+		}, true},
+		{"different host", "foo.bar", &ingress.SSLCert{
+			Certificate: &x509.Certificate{DNSNames: []string{"bar.baz"}},
+			KeyType:     x509.ECDSA.String(),
+		}, true},
+		{"only CA certificate", "foo.bar", &ingress.SSLCert{CAFileName: "ca.pem"}, true},
+	}
+
+	for _, tc := range testCases {
+		err := checkSecondaryCertificate(tc.host, rsa, tc.secondary)
+		if tc.err != (err != nil) {
+			t.Errorf("%v: expected error %v but returned %v", tc.title, tc.err, err)
+		}
+	}
+}
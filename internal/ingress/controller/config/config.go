@@ -26,6 +26,7 @@ import (
 	apiv1 "k8s.io/api/core/v1"
 
 	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/redirectmap"
 	"k8s.io/ingress-nginx/internal/ingress/defaults"
 )
 
@@ -250,6 +251,11 @@ type Configuration struct {
 	// http://nginx.org/en/docs/http/ngx_http_map_module.html#map_hash_bucket_size
 	MapHashBucketSize int `json:"map-hash-bucket-size,omitempty"`
 
+	// Sets the maximum size of the map variables hash tables.
+	// The value is increased when required by the redirect maps.
+	// http://nginx.org/en/docs/http/ngx_http_map_module.html#map_hash_max_size
+	MapHashMaxSize int `json:"map-hash-max-size,omitempty"`
+
 	// If UseProxyProtocol is enabled ProxyRealIPCIDR defines the default the IP/network address
 	// of your external load balancer
 	ProxyRealIPCIDR []string `json:"proxy-real-ip-cidr,omitempty"`
@@ -257,6 +263,10 @@ type Configuration struct {
 	// Sets the name of the configmap that contains the headers to pass to the backend
 	ProxySetHeaders string `json:"proxy-set-headers,omitempty"`
 
+	// Sets the name of the configmap that contains the redirect rules
+	// applied to the requests of all the servers
+	RedirectMap string `json:"redirect-map,omitempty"`
+
 	// Maximum size of the server names hash tables used in server names, map directive’s values,
 	// MIME types, names of request header strings, etcd.
 	// http://nginx.org/en/docs/hash.html
@@ -340,10 +350,6 @@ type Configuration struct {
 	// http://nginx.org/en/docs/http/ngx_http_gzip_module.html
 	UseGzip bool `json:"use-gzip,omitempty"`
 
-	// Enables or disables the use of the nginx geoip module that creates variables with values depending on the client IP
-	// http://nginx.org/en/docs/http/ngx_http_geoip_module.html
-	UseGeoIP bool `json:"use-geoip,omitempty"`
-
 	// Enables or disables the use of the NGINX Brotli Module for compression
 	// https://github.com/google/ngx_brotli
 	EnableBrotli bool `json:"enable-brotli,omitempty"`
@@ -479,11 +485,6 @@ type Configuration struct {
 	// Default: empty
 	HideHeaders []string `json:"hide-headers"`
 
-	// LimitReqStatusCode Sets the status code to return in response to rejected requests.
-	// http://nginx.org/en/docs/http/ngx_http_limit_req_module.html#limit_req_status
-	// Default: 503
-	LimitReqStatusCode int `json:"limit-req-status-code"`
-
 	// EnableSyslog enables the configuration for remote logging in NGINX
 	EnableSyslog bool `json:"enable-syslog"`
 	// SyslogHost FQDN or IP address where the logs should be sent
@@ -494,6 +495,20 @@ type Configuration struct {
 	// NoTLSRedirectLocations is a comma-separated list of locations
 	// that should not get redirected to TLS
 	NoTLSRedirectLocations string `json:"no-tls-redirect-locations"`
+
+	// DenySourceRange is a list of CIDRs denied in all the servers
+	// Default: empty
+	DenySourceRange []string `json:"deny-source-range"`
+
+	// DenyUserAgents is a list of case-insensitive regular expressions of
+	// User-Agent headers denied in all the servers
+	// Default: empty
+	DenyUserAgents []string `json:"deny-user-agents"`
+
+	// DenyReferers is a list of case-insensitive regular expressions of
+	// Referer headers denied in all the servers
+	// Default: empty
+	DenyReferers []string `json:"deny-referers"`
 }
 
 // NewDefault returns the default nginx configuration
@@ -534,6 +549,7 @@ func NewDefault() Configuration {
 		LogFormatUpstream:          logFormatUpstream,
 		MaxWorkerConnections:       16384,
 		MapHashBucketSize:          64,
+		MapHashMaxSize:             2048,
 		ProxyRealIPCIDR:            defIPCIDR,
 		ServerNameHashMaxSize:      1024,
 		ProxyHeadersHashMaxSize:    512,
@@ -550,7 +566,6 @@ func NewDefault() Configuration {
 		SSLSessionTimeout:          sslSessionTimeout,
 		EnableBrotli:               false,
 		UseGzip:                    true,
-		UseGeoIP:                   true,
 		WorkerProcesses:            strconv.Itoa(runtime.NumCPU()),
 		WorkerShutdownTimeout:      "10s",
 		LoadBalanceAlgorithm:       defaultLoadBalancerAlgorithm,
@@ -578,6 +593,8 @@ func NewDefault() Configuration {
 			SkipAccessLogURLs:      []string{},
 			LimitRate:              0,
 			LimitRateAfter:         0,
+			LimitReqStatusCode:     503,
+			UseGeoIP:               true,
 			ProxyBuffering:         "off",
 		},
 		UpstreamKeepaliveConnections: 32,
@@ -590,7 +607,6 @@ func NewDefault() Configuration {
 		JaegerServiceName:            "nginx",
 		JaegerSamplerType:            "const",
 		JaegerSamplerParam:           "1",
-		LimitReqStatusCode:           503,
 		SyslogPort:                   514,
 		NoTLSRedirectLocations:       "/.well-known/acme-challenge",
 	}
@@ -633,6 +649,8 @@ type TemplateConfig struct {
 	ListenPorts                 *ListenPorts
 	PublishService              *apiv1.Service
 	DynamicConfigurationEnabled bool
+	SSLSessionTicketKeys        []string
+	RedirectMap                 redirectmap.Config
 }
 
 // ListenPorts describe the ports required to run the
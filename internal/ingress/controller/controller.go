@@ -23,7 +23,6 @@ import (
 	"reflect"
 	"sort"
 	"strconv"
-	"strings"
 	"sync/atomic"
 	"time"
 
@@ -90,6 +89,7 @@ type Configuration struct {
 	EnableProfiling bool
 
 	EnableSSLChainCompletion bool
+	EnableOCSPStapling       bool
 
 	FakeCertificatePath string
 	FakeCertificateSHA  string
@@ -97,6 +97,35 @@ type Configuration struct {
 	SyncRateLimit float32
 
 	DynamicConfigurationEnabled bool
+
+	// optional. ACMEDirectoryURL enables the issuance of certificates
+	// using the ACME server with this directory
+	ACMEDirectoryURL  string
+	ACMEEmail         string
+	ACMEAccountSecret string
+	ACMERenewBefore   time.Duration
+
+	// optional. SSLIntermediateBundle ConfigMap or Secret (<namespace>/<name>)
+	// with intermediate CA certificates used to complete certificate chains
+	SSLIntermediateBundle string
+
+	// optional. LocalCASecret secret (<namespace>/<name>) with the certificate
+	// authority used to issue certificates for hosts without a usable one
+	LocalCASecret string
+
+	// optional. SSLSessionTicketKeysSecret secret (<namespace>/<name>) with the
+	// TLS session ticket keys shared by all the replicas
+	SSLSessionTicketKeysSecret   string
+	SSLSessionTicketKeysRotation time.Duration
+
+	// optional. SSLDHParamSecret secret (<namespace>/<name>) used to store the
+	// DH parameters of SSLDHParamSize bits generated by the controller
+	SSLDHParamSecret string
+	SSLDHParamSize   int
+
+	// SSLExpireWarningDays number of days before the expiration of a
+	// certificate to report it
+	SSLExpireWarningDays int
 }
 
 // GetPublishService returns the configured service used to set ingress status
@@ -166,6 +195,13 @@ func (n *NGINXController) syncIngress(item interface{}) error {
 		PassthroughBackends: passUpstreams,
 	}
 
+	// the JWT keys are sent before applying the configuration, so the keys
+	// of the new locations are available when the locations are live
+	jwtKeysErr := n.configureJWTKeys(&pcfg)
+	if jwtKeysErr != nil {
+		glog.Warningf("%v", jwtKeysErr)
+	}
+
 	if !n.isForceReload() && n.runningConfig.Equal(&pcfg) {
 		glog.V(3).Infof("skipping backend reload (no changes detected)")
 		return nil
@@ -209,6 +245,11 @@ func (n *NGINXController) syncIngress(item interface{}) error {
 	n.runningConfig = &pcfg
 	n.SetForceReload(false)
 
+	// nginx does not listen in the status port before the first reload
+	if jwtKeysErr != nil {
+		n.retryConfigureJWTKeys(&pcfg)
+	}
+
 	return nil
 }
 
@@ -232,9 +273,9 @@ func (n *NGINXController) getStreamServices(configmapName string, proto apiv1.Pr
 	}
 
 	var svcs []ingress.L4Service
-	var svcProxyProtocol ingress.ProxyProtocol
 	// k -> port to expose
 	// v -> <namespace>/<service name>:<port from service to be used>
+	//      or a structured service definition (see streamService)
 
 	rp := []int{
 		n.cfg.ListenPorts.HTTP,
@@ -249,94 +290,138 @@ func (n *NGINXController) getStreamServices(configmapName string, proto apiv1.Pr
 	for k, v := range configmap.Data {
 		externalPort, err := strconv.Atoi(k)
 		if err != nil {
-			glog.Warningf("%v is not valid as a TCP/UDP port", k)
+			n.invalidStreamService(configmap, k, fmt.Errorf("%v is not valid as a TCP/UDP port", k))
 			continue
 		}
 
 		if reserverdPorts.Has(externalPort) {
-			glog.Warningf("port %v cannot be used for TCP or UDP services. It is reserved for the Ingress controller", k)
+			n.invalidStreamService(configmap, k, fmt.Errorf("port %v is reserved for the Ingress controller", k))
 			continue
 		}
 
-		nsSvcPort := strings.Split(v, ":")
-		if len(nsSvcPort) < 2 {
-			glog.Warningf("invalid format (namespace/name:port:[PROXY]:[PROXY]) '%v'", k)
+		streamSvc, err := parseStreamService(v, proto)
+		if err != nil {
+			n.invalidStreamService(configmap, k, err)
 			continue
 		}
 
-		nsName := nsSvcPort[0]
-		svcPort := nsSvcPort[1]
-		svcProxyProtocol.Decode = false
-		svcProxyProtocol.Encode = false
+		svcNs, svcName, _ := k8s.ParseNameNS(streamSvc.Service)
+		svcPort := streamSvc.Port.String()
 
-		// Proxy protocol is possible if the service is TCP
-		if len(nsSvcPort) >= 3 && proto == apiv1.ProtocolTCP {
-			if len(nsSvcPort) >= 3 && strings.ToUpper(nsSvcPort[2]) == "PROXY" {
-				svcProxyProtocol.Decode = true
-			}
-			if len(nsSvcPort) == 4 && strings.ToUpper(nsSvcPort[3]) == "PROXY" {
-				svcProxyProtocol.Encode = true
-			}
-		}
-
-		svcNs, svcName, err := k8s.ParseNameNS(nsName)
+		endps, err := n.getStreamEndpoints(streamSvc.Service, svcPort, proto)
 		if err != nil {
 			glog.Warningf("%v", err)
 			continue
 		}
 
-		svc, err := n.store.GetService(nsName)
-		if err != nil {
-			glog.Warningf("error getting service %v: %v", nsName, err)
-			continue
+		l4svc := ingress.L4Service{
+			Port: externalPort,
+			Backend: ingress.L4Backend{
+				Name:                 svcName,
+				Namespace:            svcNs,
+				Port:                 intstr.FromString(svcPort),
+				Protocol:             proto,
+				ProxyProtocol:        streamSvc.ProxyProtocol,
+				ProxyTimeout:         streamSvc.ProxyTimeout,
+				ProxyResponses:       streamSvc.ProxyResponses,
+				WhitelistSourceRange: streamSvc.WhitelistSourceRange,
+				LimitConnections:     streamSvc.LimitConnections,
+			},
+			Endpoints: endps,
 		}
 
-		var endps []ingress.Endpoint
-		targetPort, err := strconv.Atoi(svcPort)
-		if err != nil {
-			glog.V(3).Infof("searching service %v endpoints using the name '%v'", svcNs, svcName, svcPort)
-			for _, sp := range svc.Spec.Ports {
-				if sp.Name == svcPort {
-					if sp.Protocol == proto {
-						endps = n.getEndpoints(svc, &sp, proto, &healthcheck.Config{})
-						break
-					}
-				}
+		if streamSvc.TLSSecret != "" {
+			cert, err := n.store.GetTLSCertificate(streamSvc.TLSSecret)
+			if err != nil {
+				n.invalidStreamService(configmap, k, fmt.Errorf("error obtaining certificate from secret %v: %v", streamSvc.TLSSecret, err))
+				continue
 			}
-		} else {
-			// we need to use the TargetPort (where the endpoints are running)
-			glog.V(3).Infof("searching service %v/%v endpoints using the target port '%v'", svcNs, svcName, targetPort)
-			for _, sp := range svc.Spec.Ports {
-				if sp.Port == int32(targetPort) {
-					if sp.Protocol == proto {
-						endps = n.getEndpoints(svc, &sp, proto, &healthcheck.Config{})
-						break
-					}
+
+			l4svc.SSLCertificate = cert.PemFileName
+			l4svc.SSLPemChecksum = cert.PemSHA
+
+			for _, sni := range streamSvc.SNI {
+				if err := verifyHostname(sni.Host, cert.Certificate); err != nil {
+					glog.Warningf("certificate in secret %v is not valid for sni host %v: %v", streamSvc.TLSSecret, sni.Host, err)
 				}
+
+				sniNs, sniName, _ := k8s.ParseNameNS(sni.Service)
+				sniPort := sni.Port.String()
+
+				sniEndps, err := n.getStreamEndpoints(sni.Service, sniPort, proto)
+				if err != nil {
+					glog.Warningf("ignoring sni host %v: %v", sni.Host, err)
+					continue
+				}
+
+				l4svc.SNI = append(l4svc.SNI, ingress.L4SNIService{
+					Hostname: sni.Host,
+					Backend: ingress.L4Backend{
+						Name:      sniName,
+						Namespace: sniNs,
+						Port:      intstr.FromString(sniPort),
+						Protocol:  proto,
+					},
+					Endpoints: sniEndps,
+				})
 			}
 		}
 
-		// stream services cannot contain empty upstreams and there is no
-		// default backend equivalent
-		if len(endps) == 0 {
-			glog.Warningf("service %v/%v does not have any active endpoints for port %v and protocol %v", svcNs, svcName, svcPort, proto)
-			continue
+		svcs = append(svcs, l4svc)
+	}
+
+	return svcs
+}
+
+// getStreamEndpoints returns the active endpoints of the port (number or name)
+// of a service to be used in a TCP or UDP service
+func (n *NGINXController) getStreamEndpoints(nsName, svcPort string, proto apiv1.Protocol) ([]ingress.Endpoint, error) {
+	svc, err := n.store.GetService(nsName)
+	if err != nil {
+		return nil, fmt.Errorf("error getting service %v: %v", nsName, err)
+	}
+
+	var endps []ingress.Endpoint
+	targetPort, err := strconv.Atoi(svcPort)
+	if err != nil {
+		glog.V(3).Infof("searching service %v endpoints using the name '%v'", nsName, svcPort)
+		for _, sp := range svc.Spec.Ports {
+			if sp.Name == svcPort {
+				if sp.Protocol == proto {
+					endps = n.getEndpoints(svc, &sp, proto, &healthcheck.Config{})
+					break
+				}
+			}
+		}
+	} else {
+		// we need to use the TargetPort (where the endpoints are running)
+		glog.V(3).Infof("searching service %v endpoints using the target port '%v'", nsName, targetPort)
+		for _, sp := range svc.Spec.Ports {
+			if sp.Port == int32(targetPort) {
+				if sp.Protocol == proto {
+					endps = n.getEndpoints(svc, &sp, proto, &healthcheck.Config{})
+					break
+				}
+			}
 		}
+	}
 
-		svcs = append(svcs, ingress.L4Service{
-			Port: externalPort,
-			Backend: ingress.L4Backend{
-				Name:          svcName,
-				Namespace:     svcNs,
-				Port:          intstr.FromString(svcPort),
-				Protocol:      proto,
-				ProxyProtocol: svcProxyProtocol,
-			},
-			Endpoints: endps,
-		})
+	// stream services cannot contain empty upstreams and there is no
+	// default backend equivalent
+	if len(endps) == 0 {
+		return nil, fmt.Errorf("service %v does not have any active endpoints for port %v and protocol %v", nsName, svcPort, proto)
 	}
 
-	return svcs
+	return endps, nil
+}
+
+// invalidStreamService logs and reports using an event on the configmap an
+// entry of the TCP or UDP services configmap that cannot be used
+func (n *NGINXController) invalidStreamService(configmap *apiv1.ConfigMap, port string, err error) {
+	glog.Warningf("invalid service in port %v of configmap %v/%v: %v", port, configmap.Namespace, configmap.Name, err)
+	if n.recorder != nil {
+		n.recorder.Eventf(configmap, apiv1.EventTypeWarning, "INVALID", "invalid service in port %v: %v", port, err)
+	}
 }
 
 // getDefaultUpstream returns an upstream associated with the
@@ -383,6 +468,9 @@ func (n *NGINXController) getBackendServers(ingresses []*extensions.Ingress) ([]
 			if host == "" {
 				host = defServerName
 			}
+			if !isValidServerName(host) {
+				continue
+			}
 			server := servers[host]
 			if server == nil {
 				server = servers[defServerName]
@@ -442,14 +530,19 @@ func (n *NGINXController) getBackendServers(ingresses []*extensions.Ingress) ([]
 						loc.ClientBodyBufferSize = anns.ClientBodyBufferSize
 						loc.ConfigurationSnippet = anns.ConfigurationSnippet
 						loc.CorsConfig = anns.CorsConfig
+						loc.CustomHeaders = anns.CustomHeaders
 						loc.ExternalAuth = anns.ExternalAuth
+						loc.JWTAuth = anns.JWTAuth
 						loc.Proxy = anns.Proxy
 						loc.RateLimit = anns.RateLimit
 						loc.Redirect = anns.Redirect
+						loc.RedirectMap = anns.RedirectMap
 						loc.Rewrite = anns.Rewrite
 						loc.UpstreamVhost = anns.UpstreamVhost
 						loc.VtsFilterKey = anns.VtsFilterKey
 						loc.Whitelist = anns.Whitelist
+						loc.Denylist = anns.Denylist
+						loc.GeoIP = anns.GeoIP
 						loc.Denied = anns.Denied
 						loc.XForwardedPrefix = anns.XForwardedPrefix
 						loc.UsePortInRedirects = anns.UsePortInRedirects
@@ -477,14 +570,19 @@ func (n *NGINXController) getBackendServers(ingresses []*extensions.Ingress) ([]
 						ClientBodyBufferSize: anns.ClientBodyBufferSize,
 						ConfigurationSnippet: anns.ConfigurationSnippet,
 						CorsConfig:           anns.CorsConfig,
+						CustomHeaders:        anns.CustomHeaders,
 						ExternalAuth:         anns.ExternalAuth,
+						JWTAuth:              anns.JWTAuth,
 						Proxy:                anns.Proxy,
 						RateLimit:            anns.RateLimit,
 						Redirect:             anns.Redirect,
+						RedirectMap:          anns.RedirectMap,
 						Rewrite:              anns.Rewrite,
 						UpstreamVhost:        anns.UpstreamVhost,
 						VtsFilterKey:         anns.VtsFilterKey,
 						Whitelist:            anns.Whitelist,
+						Denylist:             anns.Denylist,
+						GeoIP:                anns.GeoIP,
 						Denied:               anns.Denied,
 						XForwardedPrefix:     anns.XForwardedPrefix,
 						UsePortInRedirects:   anns.UsePortInRedirects,
@@ -622,6 +720,18 @@ func (n *NGINXController) createUpstreams(data []*extensions.Ingress, du *ingres
 			if upstreams[defBackend].SecureCACert.Secret == "" {
 				upstreams[defBackend].SecureCACert = anns.SecureUpstream.CACert
 			}
+			if upstreams[defBackend].SecureClientCert.Secret == "" {
+				upstreams[defBackend].SecureClientCert = anns.SecureUpstream.ClientCert
+			}
+			if upstreams[defBackend].SecureServerName == "" {
+				upstreams[defBackend].SecureServerName = anns.SecureUpstream.ServerName
+			}
+			if upstreams[defBackend].SecureVerifyDepth == 0 {
+				upstreams[defBackend].SecureVerifyDepth = anns.SecureUpstream.VerifyDepth
+			}
+			if upstreams[defBackend].SecureProtocols == "" {
+				upstreams[defBackend].SecureProtocols = anns.SecureUpstream.Protocols
+			}
 			if upstreams[defBackend].UpstreamHashBy == "" {
 				upstreams[defBackend].UpstreamHashBy = anns.UpstreamHashBy
 			}
@@ -679,6 +789,22 @@ func (n *NGINXController) createUpstreams(data []*extensions.Ingress, du *ingres
 					upstreams[name].SecureCACert = anns.SecureUpstream.CACert
 				}
 
+				if upstreams[name].SecureClientCert.Secret == "" {
+					upstreams[name].SecureClientCert = anns.SecureUpstream.ClientCert
+				}
+
+				if upstreams[name].SecureServerName == "" {
+					upstreams[name].SecureServerName = anns.SecureUpstream.ServerName
+				}
+
+				if upstreams[name].SecureVerifyDepth == 0 {
+					upstreams[name].SecureVerifyDepth = anns.SecureUpstream.VerifyDepth
+				}
+
+				if upstreams[name].SecureProtocols == "" {
+					upstreams[name].SecureProtocols = anns.SecureUpstream.Protocols
+				}
+
 				if upstreams[name].UpstreamHashBy == "" {
 					upstreams[name].UpstreamHashBy = anns.UpstreamHashBy
 				}
@@ -916,15 +1042,20 @@ func (n *NGINXController) createServers(data []*extensions.Ingress,
 					defLoc.ClientBodyBufferSize = anns.ClientBodyBufferSize
 					defLoc.ConfigurationSnippet = anns.ConfigurationSnippet
 					defLoc.CorsConfig = anns.CorsConfig
+					defLoc.CustomHeaders = anns.CustomHeaders
 					defLoc.ExternalAuth = anns.ExternalAuth
+					defLoc.JWTAuth = anns.JWTAuth
 					defLoc.Proxy = anns.Proxy
 					defLoc.RateLimit = anns.RateLimit
 					// TODO: Redirect and rewrite can affect the catch all behavior. Don't use this annotations for now
 					// defLoc.Redirect = anns.Redirect
 					// defLoc.Rewrite = anns.Rewrite
+					defLoc.RedirectMap = anns.RedirectMap
 					defLoc.UpstreamVhost = anns.UpstreamVhost
 					defLoc.VtsFilterKey = anns.VtsFilterKey
 					defLoc.Whitelist = anns.Whitelist
+					defLoc.Denylist = anns.Denylist
+					defLoc.GeoIP = anns.GeoIP
 					defLoc.Denied = anns.Denied
 					defLoc.GRPC = anns.GRPC
 				}
@@ -936,6 +1067,10 @@ func (n *NGINXController) createServers(data []*extensions.Ingress,
 			if host == "" {
 				host = defServerName
 			}
+			if !isValidServerName(host) {
+				glog.Warningf("ignoring host %v of ingress %v/%v: only a wildcard in the first label is supported (*.example.com)", host, ing.Namespace, ing.Name)
+				continue
+			}
 			if _, ok := servers[host]; ok {
 				// server already configured
 				continue
@@ -970,6 +1105,9 @@ func (n *NGINXController) createServers(data []*extensions.Ingress,
 			if host == "" {
 				host = defServerName
 			}
+			if !isValidServerName(host) {
+				continue
+			}
 
 			// setup server aliases
 			if anns.Alias != "" {
@@ -1000,6 +1138,12 @@ func (n *NGINXController) createServers(data []*extensions.Ingress,
 				servers[host].SSLCiphers = anns.SSLCiphers
 			}
 
+			// the challenges are answered only in the hosts of the certificates
+			// issued using ACME to avoid conflicts with other ACME clients
+			if n.acme != nil && isACMEHost(ing, host) {
+				servers[host].ACMEChallenge = true
+			}
+
 			// only add a certificate if the server does not have one previously configured
 			if servers[host].SSLCertificate != "" {
 				continue
@@ -1010,55 +1154,67 @@ func (n *NGINXController) createServers(data []*extensions.Ingress,
 				continue
 			}
 
-			tlsSecretName := ""
-			found := false
-			for _, tls := range ing.Spec.TLS {
-				if sets.NewString(tls.Hosts...).Has(host) {
-					tlsSecretName = tls.SecretName
-					found = true
-					break
-				}
-			}
-
+			tlsSecretName, found := tlsSecretNameForHost(ing, host)
 			if !found {
 				// does not contains a TLS section but none of the host match
 				continue
 			}
 
-			if tlsSecretName == "" {
-				glog.V(3).Infof("host %v is listed on tls section but secretName is empty. Using default cert", host)
-				servers[host].SSLCertificate = defaultPemFileName
-				servers[host].SSLPemChecksum = defaultPemSHA
-				continue
+			var cert *ingress.SSLCert
+			if tlsSecretName != "" {
+				key := fmt.Sprintf("%v/%v", ing.Namespace, tlsSecretName)
+				cert, err = n.store.GetLocalSecret(key)
+				if err != nil {
+					glog.Warningf("ssl certificate \"%v\" does not exist in local store", key)
+					cert = nil
+				} else if err = cert.Certificate.VerifyHostname(host); err != nil {
+					glog.Warningf("unexpected error validating SSL certificate %v for host %v. Reason: %v", key, host, err)
+					glog.Warningf("Validating certificate against DNS names. This will be deprecated in a future version.")
+					// check the common name field
+					// https://github.com/golang/go/issues/22922
+					err := verifyHostname(host, cert.Certificate)
+					if err != nil {
+						glog.Warningf("ssl certificate %v does not contain a Common Name or Subject Alternative Name for host %v. Reason: %v", key, host, err)
+						cert = nil
+					}
+				}
 			}
 
-			key := fmt.Sprintf("%v/%v", ing.Namespace, tlsSecretName)
-			cert, err := n.store.GetLocalSecret(key)
-			if err != nil {
-				glog.Warningf("ssl certificate \"%v\" does not exist in local store", key)
-				continue
+			if ca := n.getLocalCA(); cert == nil && ca != nil {
+				// the host does not have a usable certificate
+				cert = ca.certificateForHost(host, n.fileSystem, time.Now())
 			}
 
-			err = cert.Certificate.VerifyHostname(host)
-			if err != nil {
-				glog.Warningf("unexpected error validating SSL certificate %v for host %v. Reason: %v", key, host, err)
-				glog.Warningf("Validating certificate against DNS names. This will be deprecated in a future version.")
-				// check the common name field
-				// https://github.com/golang/go/issues/22922
-				err := verifyHostname(host, cert.Certificate)
-				if err != nil {
-					glog.Warningf("ssl certificate %v does not contain a Common Name or Subject Alternative Name for host %v. Reason: %v", key, host, err)
-					continue
+			if cert == nil {
+				if tlsSecretName == "" {
+					glog.V(3).Infof("host %v is listed on tls section but secretName is empty. Using default cert", host)
+					servers[host].SSLCertificate = defaultPemFileName
+					servers[host].SSLPemChecksum = defaultPemSHA
 				}
+				continue
 			}
 
 			servers[host].SSLCertificate = cert.PemFileName
 			servers[host].SSLFullChainCertificate = cert.FullChainPemFileName
 			servers[host].SSLPemChecksum = cert.PemSHA
 			servers[host].SSLExpireTime = cert.ExpireTime
+			servers[host].SSLOCSPResponse = cert.OCSPResponseFileName
+			servers[host].SSLOCSPResponseChecksum = cert.OCSPResponseSHA
+
+			if anns.SSLSecondarySecret != "" {
+				secondary, err := n.store.GetLocalSecret(anns.SSLSecondarySecret)
+				if err != nil {
+					glog.Warningf("ssl certificate \"%v\" does not exist in local store", anns.SSLSecondarySecret)
+				} else if err := checkSecondaryCertificate(host, cert, secondary); err != nil {
+					glog.Warningf("ignoring secondary ssl certificate %v for host %v: %v", anns.SSLSecondarySecret, host, err)
+				} else {
+					servers[host].SSLSecondaryCertificate = secondary.PemFileName
+					servers[host].SSLSecondaryPemChecksum = secondary.PemSHA
+				}
+			}
 
-			if cert.ExpireTime.Before(time.Now().Add(240 * time.Hour)) {
-				glog.Warningf("ssl certificate for host %v is about to expire in 10 days", host)
+			if cert.ExpireTime.Before(time.Now().Add(time.Duration(n.cfg.SSLExpireWarningDays) * 24 * time.Hour)) {
+				glog.Warningf("ssl certificate for host %v is about to expire in %v days", host, n.cfg.SSLExpireWarningDays)
 			}
 		}
 	}
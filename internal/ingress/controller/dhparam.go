@@ -0,0 +1,176 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	apiv1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+
+	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/internal/net/ssl"
+)
+
+const (
+	// dhParamKey is the key of the secrets that contains the DH parameters
+	dhParamKey = "dhparam.pem"
+
+	// dhParamCheckPeriod is the interval used to check the secret with the
+	// DH parameters generated by the controller
+	dhParamCheckPeriod = 10 * time.Minute
+)
+
+// dhParamState contains the DH parameters generated by the controller
+type dhParamState struct {
+	mu  sync.Mutex
+	pem []byte
+}
+
+// ensureDHParam returns the DH parameters stored in the secret
+// (<namespace>/<name>). If the secret does not exist or the parameters are
+// not valid or smaller than bits, new parameters are generated and stored.
+// When several replicas generate parameters the first one stored is used.
+func ensureDHParam(client clientset.Interface, secret string, bits int) ([]byte, error) {
+	ns, name, err := k8s.ParseNameNS(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := client.CoreV1().Secrets(ns).Get(name, metav1.GetOptions{})
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return nil, err
+	}
+
+	exists := err == nil
+	if exists {
+		dh := s.Data[dhParamKey]
+		_, err = ssl.ValidateDHParam(dh, bits)
+		if err == nil {
+			return dh, nil
+		}
+
+		glog.Warningf("invalid DH parameters in secret %v: %v", secret, err)
+	}
+
+	glog.Infof("generating DH parameters of %v bits in secret %v. This can take several minutes", bits, secret)
+	dh, err := ssl.GenerateDHParam(bits)
+	if err != nil {
+		return nil, err
+	}
+
+	if exists {
+		if s.Data == nil {
+			s.Data = map[string][]byte{}
+		}
+		s.Data[dhParamKey] = dh
+		_, err = client.CoreV1().Secrets(ns).Update(s)
+	} else {
+		_, err = client.CoreV1().Secrets(ns).Create(&apiv1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: ns,
+			},
+			Data: map[string][]byte{
+				dhParamKey: dh,
+			},
+		})
+	}
+
+	if k8sErrors.IsConflict(err) || k8sErrors.IsAlreadyExists(err) {
+		glog.V(2).Infof("DH parameters in secret %v updated by another replica", secret)
+		s, err = client.CoreV1().Secrets(ns).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return s.Data[dhParamKey], nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	glog.Infof("stored DH parameters in secret %v", secret)
+	return dh, nil
+}
+
+// syncDHParam reads or generates the DH parameters of the secret configured
+// in the flag --ssl-dh-param-secret and triggers a reload when they change
+func (n *NGINXController) syncDHParam() {
+	dh, err := ensureDHParam(n.cfg.Client, n.cfg.SSLDHParamSecret, n.cfg.SSLDHParamSize)
+	if err != nil {
+		glog.Errorf("unexpected error obtaining DH parameters from secret %v: %v", n.cfg.SSLDHParamSecret, err)
+		return
+	}
+
+	n.dhParam.mu.Lock()
+	changed := !bytes.Equal(n.dhParam.pem, dh)
+	n.dhParam.pem = dh
+	n.dhParam.mu.Unlock()
+
+	if changed {
+		n.SetForceReload(true)
+		n.syncQueue.Enqueue(&extensions.Ingress{})
+	}
+}
+
+// dhParamFile writes the DH parameters used by nginx and returns the path
+// of the file. The secret configured in the ssl-dh-param setting takes
+// precedence over the DH parameters generated by the controller. Invalid
+// parameters are not used.
+func (n *NGINXController) dhParamFile(secretName string) string {
+	if secretName != "" {
+		secret, err := n.store.GetSecret(secretName)
+		if err != nil {
+			glog.Warningf("unexpected error reading secret %v: %v", secretName, err)
+		} else if dh, ok := secret.Data[dhParamKey]; ok {
+			nsSecName := strings.Replace(secretName, "/", "-", -1)
+			pemFileName, err := ssl.AddOrUpdateDHParam(nsSecName, dh, n.fileSystem)
+			if err == nil {
+				return pemFileName
+			}
+
+			glog.Warningf("unexpected error adding or updating dhparam %v file: %v", nsSecName, err)
+		} else {
+			glog.Warningf("secret %v does not contain the key %v", secretName, dhParamKey)
+		}
+	}
+
+	n.dhParam.mu.Lock()
+	dh := n.dhParam.pem
+	n.dhParam.mu.Unlock()
+
+	if len(dh) == 0 {
+		return ""
+	}
+
+	nsSecName := strings.Replace(n.cfg.SSLDHParamSecret, "/", "-", -1)
+	pemFileName, err := ssl.AddOrUpdateDHParam(nsSecName, dh, n.fileSystem)
+	if err != nil {
+		glog.Warningf("unexpected error adding or updating dhparam %v file: %v", nsSecName, err)
+		return ""
+	}
+
+	return pemFileName
+}
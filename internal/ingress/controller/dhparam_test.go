@@ -0,0 +1,67 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+
+	"k8s.io/ingress-nginx/internal/net/ssl"
+)
+
+func TestEnsureDHParam(t *testing.T) {
+	client := testclient.NewSimpleClientset(&apiv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "dhparam",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			dhParamKey: []byte("invalid"),
+		},
+	})
+
+	dh, err := ensureDHParam(client, "default/dhparam", 256)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = ssl.ValidateDHParam(dh, 256)
+	if err != nil {
+		t.Fatalf("expected valid DH parameters but returned error: %v", err)
+	}
+
+	s, err := client.CoreV1().Secrets("default").Get("dhparam", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(s.Data[dhParamKey], dh) {
+		t.Errorf("expected the generated DH parameters in the secret")
+	}
+
+	// valid parameters are not generated again
+	again, err := ensureDHParam(client, "default/dhparam", 256)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(again, dh) {
+		t.Errorf("expected the DH parameters stored in the secret")
+	}
+}
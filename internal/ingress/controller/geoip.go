@@ -0,0 +1,85 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/geoip"
+)
+
+// geoipChangeDelay is the period without changes in the GeoIP directory
+// required to reload nginx, i.e. while several databases are updated
+const geoipChangeDelay = 5 * time.Second
+
+// changedGeoIPDatabases returns the GeoIP databases (.dat files) of a list
+// of changed files. Other files, like temporary files created during the
+// update of a database, are ignored. An error is returned if one of the
+// databases is not valid
+func changedGeoIPDatabases(files []string) ([]string, error) {
+	databases := []string{}
+	for _, file := range files {
+		if filepath.Ext(file) != ".dat" {
+			glog.V(3).Infof("ignoring change of file %v in the GeoIP directory", file)
+			continue
+		}
+
+		err := geoip.CheckDatabaseFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		databases = append(databases, file)
+	}
+
+	return databases, nil
+}
+
+// onGeoIPChange reloads nginx after the GeoIP databases are added or
+// replaced. The reload is skipped when one of them is not valid because
+// nginx would fail to load the new configuration
+func (n *NGINXController) onGeoIPChange(files []string) {
+	for _, file := range files {
+		geoip.InvalidateDatabaseCheck(file)
+	}
+
+	databases, err := changedGeoIPDatabases(files)
+	if err != nil {
+		glog.Errorf("skipping reload of NGINX after the update of the GeoIP databases: %v", err)
+		return
+	}
+
+	if len(databases) == 0 {
+		return
+	}
+
+	glog.Infof("GeoIP databases %v changed. Reloading NGINX", strings.Join(databases, ", "))
+
+	// the country access control annotations depend on the country database
+	for _, database := range databases {
+		if database == geoip.CountryDatabase {
+			n.store.UpdateAnnotations()
+			break
+		}
+	}
+
+	n.SetForceReload(true)
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestChangedGeoIPDatabases(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geoip")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	city := filepath.Join(dir, "GeoLiteCity.dat")
+	ioutil.WriteFile(city, []byte{0x01, 0x00, 0x00, 0x02, 0x00, 0x00, 0xff, 0xff, 0xff, 0x02}, 0644)
+	compressed := filepath.Join(dir, "GeoIPASNum.dat")
+	ioutil.WriteFile(compressed, []byte{0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00}, 0644)
+	tmp := filepath.Join(dir, ".GeoLiteCity.dat.tmp")
+	ioutil.WriteFile(tmp, []byte{}, 0644)
+
+	databases, err := changedGeoIPDatabases([]string{city, tmp})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(databases, []string{city}) {
+		t.Errorf("expected %v but returned %v", []string{city}, databases)
+	}
+
+	_, err = changedGeoIPDatabases([]string{city, compressed})
+	if err == nil {
+		t.Errorf("expected an error with a compressed database")
+	}
+
+	_, err = changedGeoIPDatabases([]string{filepath.Join(dir, "GeoIP.dat")})
+	if err == nil {
+		t.Errorf("expected an error with a missing database")
+	}
+}
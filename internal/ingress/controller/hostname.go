@@ -0,0 +1,63 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strings"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+)
+
+// isWildcardHost returns true if the host is a wildcard name
+// like *.apps.example.com
+func isWildcardHost(host string) bool {
+	return strings.HasPrefix(host, "*.")
+}
+
+// isValidServerName returns false for hosts with a wildcard that nginx
+// does not accept in the server_name directive. Only a wildcard in the
+// first label (*.example.com) is supported.
+func isValidServerName(host string) bool {
+	if !strings.Contains(host, "*") {
+		return true
+	}
+
+	return isWildcardHost(host) && !strings.Contains(host[2:], "*") && len(host) > 2
+}
+
+// tlsSecretNameForHost returns the name of the secret in the TLS section of
+// the Ingress that contains the host. A TLS entry with the exact host takes
+// precedence over a wildcard entry (*.example.com) matching the host.
+func tlsSecretNameForHost(ing *extensions.Ingress, host string) (string, bool) {
+	for _, tls := range ing.Spec.TLS {
+		for _, tlsHost := range tls.Hosts {
+			if tlsHost == host {
+				return tls.SecretName, true
+			}
+		}
+	}
+
+	for _, tls := range ing.Spec.TLS {
+		for _, tlsHost := range tls.Hosts {
+			if isWildcardHost(tlsHost) && matchHostnames(tlsHost, host) {
+				return tls.SecretName, true
+			}
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+)
+
+func TestIsValidServerName(t *testing.T) {
+	testCases := map[string]bool{
+		"foo.bar":            true,
+		"*.apps.example.com": true,
+		"*.":                 false,
+		"*":                  false,
+		"foo.*.bar":          false,
+		"*.*.bar":            false,
+		"foo*.bar":           false,
+	}
+
+	for host, expected := range testCases {
+		if r := isValidServerName(host); r != expected {
+			t.Errorf("%v: expected %v but returned %v", host, expected, r)
+		}
+	}
+}
+
+func TestTLSSecretNameForHost(t *testing.T) {
+	ing := &extensions.Ingress{
+		Spec: extensions.IngressSpec{
+			TLS: []extensions.IngressTLS{
+				{Hosts: []string{"*.apps.example.com"}, SecretName: "wildcard"},
+				{Hosts: []string{"foo.apps.example.com"}, SecretName: "foo"},
+				{Hosts: []string{"bar.example.com"}},
+			},
+		},
+	}
+
+	testCases := []struct {
+		host   string
+		secret string
+		found  bool
+	}{
+		{"foo.apps.example.com", "foo", true},
+		{"bar.apps.example.com", "wildcard", true},
+		{"*.apps.example.com", "wildcard", true},
+		{"bar.example.com", "", true},
+		{"foo.bar.apps.example.com", "", false},
+		{"example.com", "", false},
+	}
+
+	for _, tc := range testCases {
+		secret, found := tlsSecretNameForHost(ing, tc.host)
+		if secret != tc.secret || found != tc.found {
+			t.Errorf("%v: expected %v (%v) but returned %v (%v)", tc.host, tc.secret, tc.found, secret, found)
+		}
+	}
+}
+
+func TestTCPProxyGetWildcard(t *testing.T) {
+	def := &TCPServer{Hostname: "localhost"}
+	p := &TCPProxy{
+		Default: def,
+		ServerList: []*TCPServer{
+			{Hostname: "*.apps.example.com"},
+			{Hostname: "foo.apps.example.com"},
+		},
+	}
+
+	testCases := map[string]string{
+		"foo.apps.example.com":     "foo.apps.example.com",
+		"bar.apps.example.com":     "*.apps.example.com",
+		"foo.bar.apps.example.com": "localhost",
+	}
+
+	for host, expected := range testCases {
+		if s := p.Get(host); s.Hostname != expected {
+			t.Errorf("%v: expected server %v but returned %v", host, expected, s.Hostname)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"crypto"
+	"crypto/x509"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	apiv1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+
+	"k8s.io/ingress-nginx/internal/file"
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/internal/net/ssl"
+)
+
+const (
+	// localCACommonName is the common name of the certificate authority
+	// created when the local CA secret does not exist
+	localCACommonName = "Kubernetes Ingress Controller Local CA"
+
+	// localCertificateValidity is the validity period of the certificates
+	// issued by the local CA
+	localCertificateValidity = 90 * 24 * time.Hour
+	// localCertificateRenewBefore is the time before the expiration of a
+	// certificate issued by the local CA when a new one is issued
+	localCertificateRenewBefore = 30 * 24 * time.Hour
+
+	// localCASyncPeriod is the interval between attempts to load the
+	// certificate authority and checks of the issued certificates
+	localCASyncPeriod = time.Minute
+)
+
+// localCA issues certificates for the hosts listed in the TLS section of
+// the Ingress rules without a usable certificate
+type localCA struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+
+	mu sync.Mutex
+	// certs contains the certificates issued for each host
+	certs map[string]*ingress.SSLCert
+}
+
+// loadLocalCA reads the certificate authority from a secret (<namespace>/<name>).
+// If the secret does not exist or does not contain a keypair a new
+// certificate authority is created and stored in the secret.
+func loadLocalCA(client clientset.Interface, secret string) (*localCA, error) {
+	ns, name, err := k8s.ParseNameNS(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := client.CoreV1().Secrets(ns).Get(name, metav1.GetOptions{})
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return nil, err
+	}
+
+	var certPEM, keyPEM []byte
+	if err == nil {
+		certPEM = s.Data[apiv1.TLSCertKey]
+		keyPEM = s.Data[apiv1.TLSPrivateKeyKey]
+	}
+
+	if len(certPEM) == 0 || len(keyPEM) == 0 {
+		glog.Infof("creating local certificate authority in secret %v", secret)
+		certPEM, keyPEM, err = ssl.GenerateCA(localCACommonName)
+		if err != nil {
+			return nil, err
+		}
+
+		err = storeTLSSecret(client, ns, name, certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected error storing the local certificate authority in secret %v: %v", secret, err)
+		}
+	}
+
+	cert, key, err := ssl.ParseCA(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate authority in secret %v: %v", secret, err)
+	}
+
+	return &localCA{
+		cert:  cert,
+		key:   key,
+		certs: map[string]*ingress.SSLCert{},
+	}, nil
+}
+
+// certificateForHost returns the certificate issued by the local CA for
+// the host, issuing a new one if there is none or it is about to expire.
+// Returns nil if the certificate cannot be issued.
+func (ca *localCA) certificateForHost(host string, fs file.Filesystem, now time.Time) *ingress.SSLCert {
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+
+	cert, ok := ca.certs[host]
+	if ok && !localCertificateRenewalRequired(cert, now) {
+		return cert
+	}
+
+	certPEM, keyPEM, err := ssl.CreateHostCertificate(host, ca.cert, ca.key, localCertificateValidity)
+	if err != nil {
+		glog.Errorf("unexpected error issuing local certificate for host %v: %v", host, err)
+		return cert
+	}
+
+	// *.example.com -> local-ca-_.example.com
+	name := fmt.Sprintf("local-ca-%v", strings.Replace(host, "*", "_", -1))
+	cert, err = ssl.AddOrUpdateCertAndKey(name, certPEM, keyPEM, []byte{}, fs)
+	if err != nil {
+		glog.Errorf("unexpected error writing local certificate for host %v: %v", host, err)
+		return ca.certs[host]
+	}
+
+	glog.Infof("issued local certificate for host %v (expires %v)", host, cert.ExpireTime)
+	ca.certs[host] = cert
+	return cert
+}
+
+// localCertificateRenewalRequired returns true if the certificate is about
+// to expire and must be replaced
+func localCertificateRenewalRequired(cert *ingress.SSLCert, now time.Time) bool {
+	return now.Add(localCertificateRenewBefore).After(cert.ExpireTime)
+}
+
+// getLocalCA returns the local certificate authority or nil if it is not
+// enabled or not loaded yet
+func (n *NGINXController) getLocalCA() *localCA {
+	n.localCAMu.RLock()
+	defer n.localCAMu.RUnlock()
+	return n.localCA
+}
+
+// syncLocalCA loads the local certificate authority, retrying until it is
+// available, and rotates the certificates issued by it
+func (n *NGINXController) syncLocalCA() {
+	if n.getLocalCA() != nil {
+		n.rotateLocalCertificates()
+		return
+	}
+
+	ca, err := loadLocalCA(n.cfg.Client, n.cfg.LocalCASecret)
+	if err != nil {
+		glog.Errorf("unexpected error loading the local certificate authority, using the default certificate: %v", err)
+		return
+	}
+
+	n.localCAMu.Lock()
+	n.localCA = ca
+	n.localCAMu.Unlock()
+
+	// issue the certificates of the hosts without a usable certificate
+	n.syncQueue.Enqueue(&extensions.Ingress{})
+}
+
+// rotateLocalCertificates triggers a synchronization when a certificate
+// issued by the local CA must be renewed. Certificates are removed from the
+// cache and issued again in createServers if the host is still in use.
+func (n *NGINXController) rotateLocalCertificates() {
+	now := time.Now()
+	ca := n.getLocalCA()
+
+	ca.mu.Lock()
+	rotate := false
+	for host, cert := range ca.certs {
+		if localCertificateRenewalRequired(cert, now) {
+			glog.V(2).Infof("local certificate for host %v expires at %v", host, cert.ExpireTime)
+			delete(ca.certs, host)
+			rotate = true
+		}
+	}
+	ca.mu.Unlock()
+
+	if rotate {
+		n.syncQueue.Enqueue(&extensions.Ingress{})
+	}
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"k8s.io/ingress-nginx/internal/file"
+	"k8s.io/ingress-nginx/internal/task"
+)
+
+func TestLoadLocalCA(t *testing.T) {
+	client := testclient.NewSimpleClientset()
+
+	ca, err := loadLocalCA(client, "default/local-ca")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s, err := client.CoreV1().Secrets("default").Get("local-ca", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected a secret with the certificate authority: %v", err)
+	}
+	if len(s.Data[apiv1.TLSCertKey]) == 0 || len(s.Data[apiv1.TLSPrivateKeyKey]) == 0 {
+		t.Fatalf("expected a keypair in the secret")
+	}
+
+	// the certificate authority is reused after a restart
+	loaded, err := loadLocalCA(client, "default/local-ca")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !loaded.cert.Equal(ca.cert) {
+		t.Errorf("expected the certificate authority stored in the secret")
+	}
+
+	_, err = loadLocalCA(client, "local-ca")
+	if err == nil {
+		t.Errorf("expected an error using an invalid secret name")
+	}
+}
+
+func TestSyncLocalCA(t *testing.T) {
+	client := testclient.NewSimpleClientset()
+
+	unavailable := true
+	client.PrependReactor("get", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if unavailable {
+			return true, nil, fmt.Errorf("API server unavailable")
+		}
+		return false, nil, nil
+	})
+
+	n := &NGINXController{
+		cfg:       &Configuration{Client: client, LocalCASecret: "default/local-ca"},
+		syncQueue: task.NewTaskQueue(func(interface{}) error { return nil }),
+		localCAMu: &sync.RWMutex{},
+	}
+
+	n.syncLocalCA()
+	if n.getLocalCA() != nil {
+		t.Fatalf("expected no certificate authority when the secret cannot be read")
+	}
+
+	// the certificate authority is loaded in the next attempt
+	unavailable = false
+	n.syncLocalCA()
+	if n.getLocalCA() == nil {
+		t.Fatalf("expected a certificate authority")
+	}
+}
+
+func TestLocalCACertificateForHost(t *testing.T) {
+	fs, err := file.NewFakeFS()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ca, err := loadLocalCA(testclient.NewSimpleClientset(), "default/local-ca")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now := time.Now()
+	cert := ca.certificateForHost("foo.bar", fs, now)
+	if cert == nil {
+		t.Fatalf("expected a certificate")
+	}
+	if err := cert.Certificate.VerifyHostname("foo.bar"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if cert.PemFileName != file.DefaultSSLDirectory+"/local-ca-foo.bar.pem" {
+		t.Errorf("unexpected file name %v", cert.PemFileName)
+	}
+
+	// cached
+	if c := ca.certificateForHost("foo.bar", fs, now); c.Certificate.SerialNumber.Cmp(cert.Certificate.SerialNumber) != 0 {
+		t.Errorf("expected the cached certificate")
+	}
+
+	// about to expire
+	later := cert.ExpireTime.Add(-localCertificateRenewBefore + time.Hour)
+	if c := ca.certificateForHost("foo.bar", fs, later); c.Certificate.SerialNumber.Cmp(cert.Certificate.SerialNumber) == 0 {
+		t.Errorf("expected a new certificate")
+	}
+
+	wildcard := ca.certificateForHost("*.apps.example.com", fs, now)
+	if wildcard == nil || wildcard.Certificate.VerifyHostname("foo.apps.example.com") != nil {
+		t.Errorf("expected a certificate valid for the wildcard host")
+	}
+}
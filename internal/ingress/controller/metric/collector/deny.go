@@ -0,0 +1,41 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+// NewDenyCollector returns a new prometheus collector of the requests
+// denied by the rules of the global deny lists and the Ingress denylists
+func NewDenyCollector(watchNamespace, ingressClass string, ngxHealthPort int, ngxStatsPath string) Stopable {
+	return newLuaDictCollector("deny lists", "denied_requests_total",
+		"total number of requests denied by a rule of the deny lists",
+		[]string{"rule"}, denyCounters,
+		watchNamespace, ingressClass, ngxHealthPort, ngxStatsPath)
+}
+
+// denyCounters returns the number of denied requests of each rule
+func denyCounters(data []byte) ([]luaDictCounter, error) {
+	rules, err := parseDenyStats(data)
+	if err != nil {
+		return nil, err
+	}
+
+	counters := []luaDictCounter{}
+	for rule, count := range rules {
+		counters = append(counters, luaDictCounter{labels: []string{rule}, value: count})
+	}
+
+	return counters, nil
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type (
+	// luaDictCounter is a counter kept in a Lua shared dictionary with the
+	// values of its labels, after the ingress class and namespace
+	luaDictCounter struct {
+		labels []string
+		value  float64
+	}
+
+	// luaDictParser returns the counters of the JSON document served by nginx
+	luaDictParser func(data []byte) ([]luaDictCounter, error)
+
+	luaDictCollector struct {
+		scrapeChan     chan scrapeRequest
+		ngxHealthPort  int
+		ngxStatsPath   string
+		subject        string
+		counters       *prometheus.Desc
+		parse          luaDictParser
+		watchNamespace string
+		ingressClass   string
+	}
+)
+
+// newLuaDictCollector returns a new prometheus collector of the counters of
+// a Lua shared dictionary served by nginx in ngxStatsPath. Each counter is
+// exposed as the metric name with the ingress class, the namespace and
+// the labels returned by parse
+func newLuaDictCollector(subject, name, help string, labels []string, parse luaDictParser,
+	watchNamespace, ingressClass string, ngxHealthPort int, ngxStatsPath string) Stopable {
+	p := luaDictCollector{
+		scrapeChan:     make(chan scrapeRequest),
+		ngxHealthPort:  ngxHealthPort,
+		ngxStatsPath:   ngxStatsPath,
+		subject:        subject,
+		parse:          parse,
+		watchNamespace: watchNamespace,
+		ingressClass:   ingressClass,
+	}
+
+	p.counters = prometheus.NewDesc(
+		prometheus.BuildFQName(ns, "", name),
+		help,
+		append([]string{"ingress_class", "namespace"}, labels...), nil)
+
+	go p.start()
+
+	return p
+}
+
+// Describe implements prometheus.Collector.
+func (p luaDictCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- p.counters
+}
+
+// Collect implements prometheus.Collector.
+func (p luaDictCollector) Collect(ch chan<- prometheus.Metric) {
+	req := scrapeRequest{results: ch, done: make(chan struct{})}
+	p.scrapeChan <- req
+	<-req.done
+}
+
+func (p luaDictCollector) start() {
+	for req := range p.scrapeChan {
+		ch := req.results
+		p.scrape(ch)
+		req.done <- struct{}{}
+	}
+}
+
+func (p luaDictCollector) Stop() {
+	close(p.scrapeChan)
+}
+
+// scrape reads the counters of the Lua shared dictionary
+func (p luaDictCollector) scrape(ch chan<- prometheus.Metric) {
+	counters, err := getLuaDictStats(p.ngxHealthPort, p.ngxStatsPath, p.subject, p.parse)
+	if err != nil {
+		glog.Warningf("unexpected error obtaining nginx %v info: %v", p.subject, err)
+		return
+	}
+
+	for _, c := range counters {
+		labels := append([]string{p.ingressClass, p.watchNamespace}, c.labels...)
+		ch <- prometheus.MustNewConstMetric(p.counters,
+			prometheus.CounterValue, c.value, labels...)
+	}
+}
+
+func getLuaDictStats(port int, path, subject string, parse luaDictParser) ([]luaDictCounter, error) {
+	url := fmt.Sprintf("http://0.0.0.0:%v%v", port, path)
+	glog.V(3).Infof("start scraping url: %v", url)
+
+	data, err := httpBody(url)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected error scraping nginx %v (%v)", subject, err)
+	}
+
+	return parse(data)
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+// NewRateLimitCollector returns a new prometheus collector of the requests
+// over the limits of the rate limit zones, rejected or only counted in
+// dry-run mode
+func NewRateLimitCollector(watchNamespace, ingressClass string, ngxHealthPort int, ngxStatsPath string) Stopable {
+	return newLuaDictCollector("rate limit", "ratelimit_limited_requests_total",
+		"total number of requests over the limits of a rate limit zone with mode {rejected, dry_run}",
+		[]string{"zone", "mode"}, rateLimitCounters,
+		watchNamespace, ingressClass, ngxHealthPort, ngxStatsPath)
+}
+
+// rateLimitCounters returns the number of limited requests of each zone
+func rateLimitCounters(data []byte) ([]luaDictCounter, error) {
+	zones, err := parseRateLimitStats(data)
+	if err != nil {
+		return nil, err
+	}
+
+	counters := []luaDictCounter{}
+	for zone, stats := range zones {
+		counters = append(counters,
+			luaDictCounter{labels: []string{zone, "rejected"}, value: stats.Rejected},
+			luaDictCounter{labels: []string{zone, "dry_run"}, value: stats.DryRun})
+	}
+
+	return counters, nil
+}
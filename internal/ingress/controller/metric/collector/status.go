@@ -193,6 +193,32 @@ func getNginxVtsMetrics(port int, path string) (*vts, error) {
 	return vts, nil
 }
 
+// rateLimitZone contains the number of requests over the limits of a zone
+type rateLimitZone struct {
+	Rejected float64 `json:"rejected"`
+	DryRun   float64 `json:"dryRun"`
+}
+
+func parseRateLimitStats(data []byte) (map[string]rateLimitZone, error) {
+	zones := map[string]rateLimitZone{}
+	err := json.Unmarshal(data, &zones)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected error json unmarshal (%v)", err)
+	}
+
+	return zones, nil
+}
+
+func parseDenyStats(data []byte) (map[string]float64, error) {
+	rules := map[string]float64{}
+	err := json.Unmarshal(data, &rules)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected error json unmarshal (%v)", err)
+	}
+
+	return rules, nil
+}
+
 func parse(data string) *basicStatus {
 	acr := ac.FindStringSubmatch(data)
 	sahrr := sahr.FindStringSubmatch(data)
@@ -70,3 +70,70 @@ func TestToint(t *testing.T) {
 		}
 	}
 }
+
+func TestParseRateLimitStats(t *testing.T) {
+	zones, err := parseRateLimitStats([]byte(`{"default_foo":{"rejected":10,"dryRun":2},"default_bar":{"dryRun":5}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]rateLimitZone{
+		"default_foo": {Rejected: 10, DryRun: 2},
+		"default_bar": {DryRun: 5},
+	}
+	if diff := pretty.Compare(zones, expected); diff != "" {
+		t.Errorf("unexpected rate limit stats: %v", diff)
+	}
+
+	_, err = parseRateLimitStats([]byte(`[]`))
+	if err == nil {
+		t.Errorf("expected an error parsing invalid rate limit stats")
+	}
+}
+
+func TestParseDenyStats(t *testing.T) {
+	rules, err := parseDenyStats([]byte(`{"source:10.0.0.0/8":3,"user-agent:BadBot":1}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]float64{
+		"source:10.0.0.0/8": 3,
+		"user-agent:BadBot": 1,
+	}
+	if diff := pretty.Compare(rules, expected); diff != "" {
+		t.Errorf("unexpected deny stats: %v", diff)
+	}
+}
+
+func TestLuaDictCounters(t *testing.T) {
+	counters, err := rateLimitCounters([]byte(`{"default_foo":{"rejected":10,"dryRun":2}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []luaDictCounter{
+		{labels: []string{"default_foo", "rejected"}, value: 10},
+		{labels: []string{"default_foo", "dry_run"}, value: 2},
+	}
+	if diff := pretty.Compare(counters, expected); diff != "" {
+		t.Errorf("unexpected rate limit counters: %v", diff)
+	}
+
+	counters, err = denyCounters([]byte(`{"source:10.0.0.0/8":3}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected = []luaDictCounter{
+		{labels: []string{"source:10.0.0.0/8"}, value: 3},
+	}
+	if diff := pretty.Compare(counters, expected); diff != "" {
+		t.Errorf("unexpected deny counters: %v", diff)
+	}
+
+	_, err = denyCounters([]byte(`[]`))
+	if err == nil {
+		t.Errorf("expected an error parsing invalid deny stats")
+	}
+}
@@ -17,9 +17,12 @@ limitations under the License.
 package controller
 
 import (
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 
 	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/k8s"
 )
 
 const (
@@ -28,12 +31,16 @@ const (
 	reloadLabel    = "reloads"
 	sslLabelExpire = "ssl_expire_time_seconds"
 	sslLabelHost   = "host"
+	sslLabelSecret = "secret"
 )
 
 func init() {
 	prometheus.MustRegister(reloadOperation)
 	prometheus.MustRegister(reloadOperationErrors)
 	prometheus.MustRegister(sslExpireTime)
+	prometheus.MustRegister(sslCertificateExpireDays)
+	prometheus.MustRegister(sslCertificateValid)
+	prometheus.MustRegister(sslCRLExpireDays)
 }
 
 var (
@@ -62,6 +69,30 @@ var (
 		},
 		[]string{sslLabelHost},
 	)
+	sslCertificateExpireDays = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: ns,
+			Name:      "ssl_certificate_expire_days",
+			Help:      "Number of days until the SSL certificate of a secret used in Ingress rules expires",
+		},
+		[]string{sslLabelSecret},
+	)
+	sslCertificateValid = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: ns,
+			Name:      "ssl_certificate_valid",
+			Help:      "Indicates if the SSL certificate of a secret used in Ingress rules is in its validity period and has a valid chain (1) or not (0)",
+		},
+		[]string{sslLabelSecret},
+	)
+	sslCRLExpireDays = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: ns,
+			Name:      "ssl_crl_expire_days",
+			Help:      "Number of days until the next update of the certificate revocation lists of a secret used for client certificate authentication",
+		},
+		[]string{sslLabelSecret},
+	)
 )
 
 func incReloadCount() {
@@ -79,3 +110,32 @@ func setSSLExpireTime(servers []*ingress.Server) {
 		}
 	}
 }
+
+func setCertificateMetrics(statuses []CertificateStatus) {
+	// remove the metrics of secrets not used anymore
+	sslCertificateExpireDays.Reset()
+	sslCertificateValid.Reset()
+
+	for _, s := range statuses {
+		sslCertificateExpireDays.WithLabelValues(s.Secret).Set(s.DaysToExpiry)
+
+		valid := 0.0
+		if s.Valid {
+			valid = 1
+		}
+		sslCertificateValid.WithLabelValues(s.Secret).Set(valid)
+	}
+}
+
+func setCRLMetrics(certs []*ingress.SSLCert, now time.Time) {
+	// remove the metrics of secrets not used anymore
+	sslCRLExpireDays.Reset()
+
+	for _, c := range certs {
+		if c.CRLFileName == "" || c.CRLNextUpdate.IsZero() {
+			continue
+		}
+
+		sslCRLExpireDays.WithLabelValues(k8s.MetaNamespaceKey(c)).Set(c.CRLNextUpdate.Sub(now).Hours() / 24)
+	}
+}
@@ -38,18 +38,20 @@ import (
 	"github.com/eapache/channels"
 	apiv1 "k8s.io/api/core/v1"
 	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes/scheme"
 	v1core "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/flowcontrol"
 	"k8s.io/kubernetes/pkg/util/filesystem"
 
-	"path/filepath"
-
 	"k8s.io/ingress-nginx/internal/file"
 	"k8s.io/ingress-nginx/internal/ingress"
 	"k8s.io/ingress-nginx/internal/ingress/annotations"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/class"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/jwtauth"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/redirectmap"
 	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
 	"k8s.io/ingress-nginx/internal/ingress/controller/process"
 	"k8s.io/ingress-nginx/internal/ingress/controller/store"
@@ -57,7 +59,6 @@ import (
 	"k8s.io/ingress-nginx/internal/ingress/status"
 	ing_net "k8s.io/ingress-nginx/internal/net"
 	"k8s.io/ingress-nginx/internal/net/dns"
-	"k8s.io/ingress-nginx/internal/net/ssl"
 	"k8s.io/ingress-nginx/internal/task"
 	"k8s.io/ingress-nginx/internal/watch"
 )
@@ -76,6 +77,14 @@ var (
 	geoipPath   = "/etc/nginx/geoip"
 	cfgPath     = "/etc/nginx/nginx.conf"
 	nginxBinary = "/usr/sbin/nginx"
+
+	// jwtKeysBackoff configures the retries to send the JWT keys after a
+	// reload, until nginx listens in the status port
+	jwtKeysBackoff = wait.Backoff{
+		Duration: 1 * time.Second,
+		Factor:   1.5,
+		Steps:    4,
+	}
 )
 
 // NewNGINXController creates a new NGINX Ingress controller.
@@ -116,21 +125,30 @@ func NewNGINXController(config *Configuration, fs file.Filesystem) *NGINXControl
 
 		stopLock: &sync.Mutex{},
 
+		localCAMu: &sync.RWMutex{},
+
 		fileSystem: fs,
 
 		// create an empty configuration.
 		runningConfig: &ingress.Configuration{},
 
 		Proxy: &TCPProxy{},
+
+		certReporter: &certificateReporter{reported: sets.NewString()},
+
+		dhParam: &dhParamState{},
 	}
 
 	n.store = store.New(
 		config.EnableSSLChainCompletion,
+		config.EnableOCSPStapling,
 		config.Namespace,
 		config.ConfigMapName,
 		config.TCPConfigMapName,
 		config.UDPConfigMapName,
 		config.DefaultSSLCertificate,
+		config.SSLIntermediateBundle,
+		config.SSLSessionTicketKeysSecret,
 		config.ResyncPeriod,
 		config.Client,
 		fs,
@@ -142,6 +160,10 @@ func NewNGINXController(config *Configuration, fs file.Filesystem) *NGINXControl
 
 	n.annotations = annotations.NewAnnotationExtractor(n.store)
 
+	if config.ACMEDirectoryURL != "" {
+		n.acme = newACMEIssuer(config)
+	}
+
 	if config.UpdateStatus {
 		n.syncStatus = status.NewStatusSyncer(status.Config{
 			Client:                 config.Client,
@@ -193,34 +215,10 @@ Error loading new template : %v
 			glog.Fatalf("unexpected error creating file watcher: %v", err)
 		}
 
-		filesToWatch := []string{}
-		err := filepath.Walk("/etc/nginx/geoip/", func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-
-			if info.IsDir() {
-				return nil
-			}
-
-			filesToWatch = append(filesToWatch, path)
-			return nil
-		})
-
+		_, err = watch.NewDirectoryWatcher(geoipPath, geoipChangeDelay, n.onGeoIPChange)
 		if err != nil {
 			glog.Fatalf("unexpected error creating file watcher: %v", err)
 		}
-
-		for _, f := range filesToWatch {
-			_, err = watch.NewFileWatcher(f, func() {
-				glog.Info("file %v changed. Reloading NGINX", f)
-				n.SetForceReload(true)
-			})
-			if err != nil {
-				glog.Fatalf("unexpected error creating file watcher: %v", err)
-			}
-		}
-
 	}
 
 	return n
@@ -274,6 +272,26 @@ type NGINXController struct {
 	store store.Storer
 
 	fileSystem filesystem.Filesystem
+
+	// acme issues certificates using ACME. nil if ACME is not enabled
+	acme *acmeIssuer
+
+	// localCA issues certificates for hosts without a usable certificate.
+	// nil if the local CA is not enabled or not loaded yet
+	localCA   *localCA
+	localCAMu *sync.RWMutex
+
+	certReporter *certificateReporter
+
+	// dhParam contains the DH parameters generated by the controller
+	dhParam *dhParamState
+
+	// redirectMap contains the rules of the global redirect map
+	redirectMap redirectmap.Config
+
+	// runningJWTKeys contains the JSON encoded public keys used to validate
+	// JWT bearer tokens sent to nginx
+	runningJWTKeys []byte
 }
 
 // Start start a new NGINX master process running in foreground.
@@ -299,6 +317,30 @@ func (n *NGINXController) Start() {
 		n.setupSSLProxy()
 	}
 
+	if n.acme != nil {
+		if s, ok := n.acme.solver.(*acmeSecretSolver); ok {
+			go s.Run(n.stopCh)
+		}
+		if n.syncStatus == nil {
+			glog.Warning("ACME certificates are requested by every replica (flag --update-status=false was specified)")
+		}
+		go wait.Until(n.syncACMECertificates, acmeSyncPeriod, n.stopCh)
+	}
+
+	if n.cfg.LocalCASecret != "" {
+		go wait.Until(n.syncLocalCA, localCASyncPeriod, n.stopCh)
+	}
+
+	if n.cfg.SSLDHParamSecret != "" {
+		go wait.Until(n.syncDHParam, dhParamCheckPeriod, n.stopCh)
+	}
+
+	if n.cfg.SSLSessionTicketKeysSecret != "" {
+		go wait.Until(n.syncSessionTicketKeys, sessionTicketKeysCheckPeriod, n.stopCh)
+	}
+
+	go wait.Until(n.reportCertificateIssues, certificateCheckPeriod, n.stopCh)
+
 	glog.Info("starting NGINX process...")
 	n.start(cmd)
 
@@ -508,14 +550,13 @@ func (n *NGINXController) OnUpdate(ingressCfg ingress.Configuration) error {
 	// if is required.
 	// https://trac.nginx.org/nginx/ticket/352
 	// https://trac.nginx.org/nginx/ticket/631
-	var longestName int
-	var serverNameBytes int
+	longestName, serverNameBytes := serverNamesSize(ingressCfg.Servers)
 	redirectServers := make(map[string]string)
 	for _, srv := range ingressCfg.Servers {
-		if longestName < len(srv.Hostname) {
-			longestName = len(srv.Hostname)
+		if srv.RedirectFromToWWW && isWildcardHost(srv.Hostname) {
+			glog.Warningf("ignoring redirect from/to www for wildcard host %v", srv.Hostname)
+			continue
 		}
-		serverNameBytes += len(srv.Hostname)
 		if srv.RedirectFromToWWW {
 			var n string
 			if strings.HasPrefix(srv.Hostname, "www.") {
@@ -583,29 +624,40 @@ func (n *NGINXController) OnUpdate(ingressCfg ingress.Configuration) error {
 		addHeaders = cmap.Data
 	}
 
-	sslDHParam := ""
-	if cfg.SSLDHParam != "" {
-		secretName := cfg.SSLDHParam
-
-		secret, err := n.store.GetSecret(secretName)
+	redirectMap := redirectmap.Config{}
+	if cfg.RedirectMap != "" {
+		cmap, err := n.store.GetConfigMap(cfg.RedirectMap)
 		if err != nil {
-			glog.Warningf("unexpected error reading secret %v: %v", secretName, err)
+			glog.Warningf("unexpected error reading redirect map %v: %v", cfg.RedirectMap, err)
+		} else {
+			redirectMap = *redirectmap.ParseRules(cfg.RedirectMap, cmap.Data)
 		}
+	}
 
-		nsSecName := strings.Replace(secretName, "/", "-", -1)
-
-		dh, ok := secret.Data["dhparam.pem"]
-		if ok {
-			pemFileName, err := ssl.AddOrUpdateDHParam(nsSecName, dh, n.fileSystem)
-			if err != nil {
-				glog.Warningf("unexpected error adding or updating dhparam %v file: %v", nsSecName, err)
-			} else {
-				sslDHParam = pemFileName
-			}
+	if !(&n.redirectMap).Equal(&redirectMap) {
+		for _, rule := range redirectMap.InvalidRules {
+			glog.Warningf("ignoring invalid rule in redirect map %v: %v", redirectMap.Name, rule)
+		}
+		if redirectMap.Name != "" {
+			glog.Infof("loaded %v redirect rules from ConfigMap %v", len(redirectMap.Rules), redirectMap.Name)
 		}
+		n.redirectMap = redirectMap
+	}
+
+	// the exact sources of the redirect maps are stored in hash tables
+	longestSource, sourceBytes := redirectSourcesSize(ingressCfg.Servers, redirectMap)
+	mapHashBucketSize := nginxHashBucketSize(longestSource)
+	if cfg.MapHashBucketSize < mapHashBucketSize {
+		glog.V(3).Infof("adjusting MapHashBucketSize variable to %v", mapHashBucketSize)
+		cfg.MapHashBucketSize = mapHashBucketSize
+	}
+	mapHashMaxSize := nextPowerOf2(sourceBytes)
+	if cfg.MapHashMaxSize < mapHashMaxSize {
+		glog.V(3).Infof("adjusting MapHashMaxSize variable to %v", mapHashMaxSize)
+		cfg.MapHashMaxSize = mapHashMaxSize
 	}
 
-	cfg.SSLDHParam = sslDHParam
+	cfg.SSLDHParam = n.dhParamFile(cfg.SSLDHParam)
 
 	tc := ngx_config.TemplateConfig{
 		ProxySetHeaders:             setHeaders,
@@ -626,6 +678,8 @@ func (n *NGINXController) OnUpdate(ingressCfg ingress.Configuration) error {
 		ListenPorts:                 n.cfg.ListenPorts,
 		PublishService:              n.GetPublishService(),
 		DynamicConfigurationEnabled: n.cfg.DynamicConfigurationEnabled,
+		SSLSessionTicketKeys:        n.writeSessionTicketKeys(),
+		RedirectMap:                 redirectMap,
 	}
 
 	content, err := n.t.Write(tc)
@@ -679,6 +733,29 @@ func (n *NGINXController) OnUpdate(ingressCfg ingress.Configuration) error {
 	return nil
 }
 
+// serverNamesSize returns the length of the longest name and the total
+// size of the names (hostnames and aliases) used in server_name directives.
+// nginx stores wildcard names (*.example.com) in separate hashes that never
+// use longer keys than the name itself, so they are sized like exact names.
+func serverNamesSize(servers []*ingress.Server) (int, int) {
+	var longestName int
+	var serverNameBytes int
+	for _, srv := range servers {
+		for _, name := range []string{srv.Hostname, srv.Alias} {
+			if name == "" {
+				continue
+			}
+
+			if longestName < len(name) {
+				longestName = len(name)
+			}
+			serverNameBytes += len(name)
+		}
+	}
+
+	return longestName, serverNameBytes
+}
+
 // nginxHashBucketSize computes the correct nginx hash_bucket_size for a hash with the given longest key
 func nginxHashBucketSize(longestString int) int {
 	// See https://github.com/kubernetes/ingress-nginxs/issues/623 for an explanation
@@ -689,6 +766,41 @@ func nginxHashBucketSize(longestString int) int {
 	return nextPowerOf2(rawSize)
 }
 
+// redirectSourcesSize returns the length of the longest exact source and
+// the total size of the exact sources of the redirect maps used by the
+// locations and the global redirect map. Each map is counted only once
+func redirectSourcesSize(servers []*ingress.Server, global redirectmap.Config) (int, int) {
+	maps := []redirectmap.Config{global}
+	for _, srv := range servers {
+		for _, loc := range srv.Locations {
+			maps = append(maps, loc.RedirectMap)
+		}
+	}
+
+	var longestSource int
+	var sourceBytes int
+	found := map[string]bool{}
+	for _, rm := range maps {
+		if rm.Name == "" || found[rm.Name] {
+			continue
+		}
+		found[rm.Name] = true
+
+		for _, rule := range rm.Rules {
+			if rule.IsRegex() {
+				continue
+			}
+
+			if longestSource < len(rule.Source) {
+				longestSource = len(rule.Source)
+			}
+			sourceBytes += len(rule.Source)
+		}
+	}
+
+	return longestSource, sourceBytes
+}
+
 // http://graphics.stanford.edu/~seander/bithacks.html#RoundUpPowerOf2
 // https://play.golang.org/p/TVSyCcdxUh
 func nextPowerOf2(v int) int {
@@ -778,7 +890,67 @@ func (n *NGINXController) ConfigureDynamically(pcfg *ingress.Configuration) erro
 
 	glog.V(2).Infof("posting backends configuration: %s", buf)
 
-	url := fmt.Sprintf("http://localhost:%d/configuration/backends", n.cfg.ListenPorts.Status)
+	return postConfiguration(n.cfg.ListenPorts.Status, "backends", buf)
+}
+
+// configureJWTKeys JSON encodes the public keys of the locations that
+// validate JWT bearer tokens and POSTs them to an internal HTTP endpoint
+// that is handled by Lua when they change. The keys are not part of the
+// configuration to update them without a reload
+func (n *NGINXController) configureJWTKeys(pcfg *ingress.Configuration) error {
+	keys := map[string][]jwtauth.Key{}
+	for _, server := range pcfg.Servers {
+		for _, location := range server.Locations {
+			if location.JWTAuth.Keys != "" {
+				keys[location.JWTAuth.Keys] = location.JWTAuth.KeySet.Keys()
+			}
+		}
+	}
+
+	buf, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+
+	if bytes.Equal(buf, n.runningJWTKeys) {
+		return nil
+	}
+
+	glog.V(2).Infof("posting JWT keys of %v secrets or configmaps", len(keys))
+
+	err = postConfiguration(n.cfg.ListenPorts.Status, "jwt-keys", buf)
+	if err != nil {
+		return fmt.Errorf("could not configure JWT keys: %v", err)
+	}
+
+	n.runningJWTKeys = buf
+	return nil
+}
+
+// retryConfigureJWTKeys sends the JWT keys that could not be sent before a
+// reload. The keys are sent again in the next synchronization when all the
+// retries fail
+func (n *NGINXController) retryConfigureJWTKeys(pcfg *ingress.Configuration) {
+	err := wait.ExponentialBackoff(jwtKeysBackoff, func() (bool, error) {
+		err := n.configureJWTKeys(pcfg)
+		if err != nil {
+			glog.Warningf("%v", err)
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		glog.Errorf("could not configure JWT keys after the reload: %v", err)
+		return
+	}
+
+	glog.Infof("JWT keys configured after the reload")
+}
+
+// postConfiguration POSTs a JSON encoded configuration to an internal HTTP
+// endpoint that is handled by Lua
+func postConfiguration(port int, name string, buf []byte) error {
+	url := fmt.Sprintf("http://localhost:%d/configuration/%v", port, name)
 	resp, err := http.Post(url, "application/json", bytes.NewReader(buf))
 	if err != nil {
 		return err
@@ -17,9 +17,21 @@ limitations under the License.
 package controller
 
 import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
 
 	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/jwtauth"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/redirectmap"
+	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
 )
 
 func TestIsDynamicallyConfigurable(t *testing.T) {
@@ -129,6 +141,48 @@ func TestNginxHashBucketSize(t *testing.T) {
 	}
 }
 
+func TestServerNamesSize(t *testing.T) {
+	servers := []*ingress.Server{
+		{Hostname: "_"},
+		{Hostname: "*.apps.example.com"},
+		{Hostname: "foo.bar", Alias: "a-very-long-alias.foo.bar"},
+	}
+
+	longest, total := serverNamesSize(servers)
+	if longest != 25 {
+		t.Errorf("expected 25 as longest name but returned %v", longest)
+	}
+	if total != 1+18+7+25 {
+		t.Errorf("expected %v bytes but returned %v", 1+18+7+25, total)
+	}
+}
+
+func TestRedirectSourcesSize(t *testing.T) {
+	rm := redirectmap.Config{
+		Name: "default/redirects",
+		Rules: []redirectmap.Rule{
+			{Source: "/a-long-source"},
+			{Source: "~^/a-very-long-regular-expression$"},
+		},
+	}
+	global := redirectmap.Config{
+		Name:  "default/global",
+		Rules: []redirectmap.Rule{{Source: "/global"}},
+	}
+	servers := []*ingress.Server{
+		{Locations: []*ingress.Location{{RedirectMap: rm}, {}}},
+		{Locations: []*ingress.Location{{RedirectMap: rm}}},
+	}
+
+	longest, total := redirectSourcesSize(servers, global)
+	if longest != 14 {
+		t.Errorf("expected 14 as longest source but returned %v", longest)
+	}
+	if total != 14+7 {
+		t.Errorf("expected %v bytes but returned %v", 14+7, total)
+	}
+}
+
 func TestNextPowerOf2(t *testing.T) {
 	// Powers of 2
 	actual := nextPowerOf2(2)
@@ -184,3 +238,111 @@ func TestNextPowerOf2(t *testing.T) {
 		t.Errorf("TestNextPowerOf2: expected %d but returned %d.", 0, actual)
 	}
 }
+
+func TestConfigureJWTKeys(t *testing.T) {
+	status := http.StatusCreated
+	posted := []map[string][]jwtauth.Key{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/configuration/jwt-keys" {
+			t.Errorf("unexpected path %v", r.URL.Path)
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		keys := map[string][]jwtauth.Key{}
+		if err := json.Unmarshal(body, &keys); err != nil {
+			t.Errorf("unexpected error decoding keys: %v", err)
+		}
+		posted = append(posted, keys)
+
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+
+	_, p, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	port, _ := strconv.Atoi(p)
+
+	ks, err := jwtauth.ParseKeys(map[string][]byte{
+		"jwks.json": []byte(`{"kty":"EC","kid":"ec","crv":"P-256",` +
+			`"x":"f83OJ3D2xF1Bg8vub9tLe1gHMzV76e8Tus9uPHvRVEU","y":"x_FEzRu9m36HLN_tue659LNpXW6pCyStikYjKIWI5a0"}`),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pcfg := &ingress.Configuration{
+		Servers: []*ingress.Server{{
+			Hostname: "example.com",
+			Locations: []*ingress.Location{
+				{Path: "/", JWTAuth: jwtauth.Config{Keys: "default/jwt", KeySet: ks}},
+				{Path: "/public"},
+			},
+		}},
+	}
+
+	n := &NGINXController{
+		cfg: &Configuration{ListenPorts: &ngx_config.ListenPorts{Status: port}},
+	}
+
+	status = http.StatusInternalServerError
+	if err := n.configureJWTKeys(pcfg); err == nil {
+		t.Errorf("expected an error when the keys are not saved")
+	}
+
+	status = http.StatusCreated
+	for i := 0; i < 2; i++ {
+		if err := n.configureJWTKeys(pcfg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// the keys are sent again after the error but not when they do not change
+	if len(posted) != 2 {
+		t.Fatalf("expected 2 requests but %v were sent", len(posted))
+	}
+	keys := posted[1]["default/jwt"]
+	if len(posted[1]) != 1 || len(keys) != 1 || keys[0].ID != "ec" || keys[0].Type != "EC" {
+		t.Errorf("unexpected keys %v", posted[1])
+	}
+}
+
+func TestRetryConfigureJWTKeys(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		// nginx is not listening in the status port yet
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	_, p, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	port, _ := strconv.Atoi(p)
+
+	backoff := jwtKeysBackoff
+	defer func() { jwtKeysBackoff = backoff }()
+	jwtKeysBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 3}
+
+	pcfg := &ingress.Configuration{
+		Servers: []*ingress.Server{{
+			Hostname: "example.com",
+			Locations: []*ingress.Location{
+				{Path: "/", JWTAuth: jwtauth.Config{Keys: "default/jwt", KeySet: &jwtauth.KeySet{}}},
+			},
+		}},
+	}
+
+	n := &NGINXController{
+		cfg: &Configuration{ListenPorts: &ngx_config.ListenPorts{Status: port}},
+	}
+
+	n.retryConfigureJWTKeys(pcfg)
+	if requests != 2 {
+		t.Errorf("expected 2 requests but %v were sent", requests)
+	}
+	if n.runningJWTKeys == nil {
+		t.Errorf("expected the keys to be configured after the retry")
+	}
+}
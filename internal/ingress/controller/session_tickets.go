@@ -0,0 +1,226 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/golang/glog"
+
+	apiv1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+
+	"k8s.io/ingress-nginx/internal/k8s"
+)
+
+const (
+	// sessionTicketKeyLength is the size of the keys generated by the
+	// controller (AES256 keys, supported since nginx 1.11.8)
+	sessionTicketKeyLength = 80
+
+	// sessionTicketKeysCount is the number of keys kept in the secret: the
+	// first one encrypts new tickets and the others decrypt tickets issued
+	// before the last rotations
+	sessionTicketKeysCount = 3
+
+	// sessionTicketKeysCheckPeriod is the interval used to check if the
+	// keys must be rotated
+	sessionTicketKeysCheckPeriod = time.Minute
+
+	// sessionTicketKeysRotatedAt is the annotation of the secret with the
+	// time of the last rotation
+	sessionTicketKeysRotatedAt = "nginx.ingress.kubernetes.io/session-ticket-keys-rotated-at"
+
+	// sessionTicketKeyFile is the path of the files used by nginx
+	sessionTicketKeyFile = "/etc/nginx/tickets-%v.key"
+)
+
+// sessionTicketKeyName returns the name of the key in the secret that
+// contains the session ticket key with the index i
+func sessionTicketKeyName(i int) string {
+	return fmt.Sprintf("ticket-key-%v", i)
+}
+
+// sessionTicketKeysFromSecret returns the valid session ticket keys of a
+// secret, from the most recent to the oldest one
+func sessionTicketKeysFromSecret(s *apiv1.Secret) [][]byte {
+	keys := [][]byte{}
+	if s == nil {
+		return keys
+	}
+
+	for i := 0; i < sessionTicketKeysCount; i++ {
+		key, ok := s.Data[sessionTicketKeyName(i)]
+		if !ok {
+			break
+		}
+
+		// nginx accepts 48 (AES128) or 80 (AES256) bytes keys
+		if len(key) != 48 && len(key) != 80 {
+			glog.Warningf("ignoring session ticket key %v in secret %v/%v: invalid length %v",
+				sessionTicketKeyName(i), s.Namespace, s.Name, len(key))
+			continue
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// nextSessionTicketKeys returns the keys after a rotation: the new key is
+// used to encrypt tickets and the previous ones are kept to decrypt them
+func nextSessionTicketKeys(keys [][]byte, key []byte) [][]byte {
+	next := [][]byte{key}
+	for _, k := range keys {
+		if len(next) == sessionTicketKeysCount {
+			break
+		}
+		next = append(next, k)
+	}
+
+	return next
+}
+
+// sessionTicketKeysRotationRequired returns true if the keys of the secret
+// are older than the rotation period
+func sessionTicketKeysRotationRequired(s *apiv1.Secret, rotation time.Duration, now time.Time) bool {
+	if len(sessionTicketKeysFromSecret(s)) == 0 {
+		return true
+	}
+
+	rotatedAt, err := time.Parse(time.RFC3339, s.Annotations[sessionTicketKeysRotatedAt])
+	if err != nil {
+		return true
+	}
+
+	return !now.Before(rotatedAt.Add(rotation))
+}
+
+// rotateSessionTicketKeys creates the secret (<namespace>/<name>) with the
+// session ticket keys or rotates the keys when the rotation period is over.
+// Every replica runs the rotation. The update of the secret fails with a
+// conflict if another replica rotated the keys first.
+func rotateSessionTicketKeys(client clientset.Interface, secret string, rotation time.Duration, now time.Time) error {
+	ns, name, err := k8s.ParseNameNS(secret)
+	if err != nil {
+		return err
+	}
+
+	s, err := client.CoreV1().Secrets(ns).Get(name, metav1.GetOptions{})
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return err
+	}
+
+	exists := err == nil
+	if exists && !sessionTicketKeysRotationRequired(s, rotation, now) {
+		return nil
+	}
+
+	key := make([]byte, sessionTicketKeyLength)
+	_, err = rand.Read(key)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		s = &apiv1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: ns,
+			},
+		}
+	}
+
+	keys := nextSessionTicketKeys(sessionTicketKeysFromSecret(s), key)
+
+	if s.Annotations == nil {
+		s.Annotations = map[string]string{}
+	}
+	s.Annotations[sessionTicketKeysRotatedAt] = now.UTC().Format(time.RFC3339)
+
+	s.Data = map[string][]byte{}
+	for i, k := range keys {
+		s.Data[sessionTicketKeyName(i)] = k
+	}
+
+	if exists {
+		_, err = client.CoreV1().Secrets(ns).Update(s)
+	} else {
+		_, err = client.CoreV1().Secrets(ns).Create(s)
+	}
+
+	if k8sErrors.IsConflict(err) || k8sErrors.IsAlreadyExists(err) {
+		glog.V(2).Infof("session ticket keys in secret %v updated by another replica", secret)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	glog.Infof("rotated session ticket keys in secret %v", secret)
+	return nil
+}
+
+// syncSessionTicketKeys rotates the session ticket keys if required
+func (n *NGINXController) syncSessionTicketKeys() {
+	err := rotateSessionTicketKeys(n.cfg.Client, n.cfg.SSLSessionTicketKeysSecret,
+		n.cfg.SSLSessionTicketKeysRotation, time.Now())
+	if err != nil {
+		glog.Errorf("unexpected error rotating session ticket keys in secret %v: %v",
+			n.cfg.SSLSessionTicketKeysSecret, err)
+	}
+}
+
+// writeSessionTicketKeys writes the session ticket keys of the secret
+// obtained from the local store in the files used by nginx and returns
+// their paths. Files are only written when their content changes.
+func (n *NGINXController) writeSessionTicketKeys() []string {
+	files := []string{}
+	if n.cfg.SSLSessionTicketKeysSecret == "" {
+		return files
+	}
+
+	s, err := n.store.GetSecret(n.cfg.SSLSessionTicketKeysSecret)
+	if err != nil {
+		glog.Warningf("session ticket keys secret %v not found: %v", n.cfg.SSLSessionTicketKeysSecret, err)
+		return files
+	}
+
+	for i, key := range sessionTicketKeysFromSecret(s) {
+		path := fmt.Sprintf(sessionTicketKeyFile, i)
+
+		current, err := ioutil.ReadFile(path)
+		if err != nil || !bytes.Equal(current, key) {
+			err = ioutil.WriteFile(path, key, 0600)
+			if err != nil {
+				glog.Errorf("unexpected error writing session ticket key %v: %v", path, err)
+				return []string{}
+			}
+		}
+
+		files = append(files, path)
+	}
+
+	return files
+}
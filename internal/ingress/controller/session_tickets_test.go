@@ -0,0 +1,94 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNextSessionTicketKeys(t *testing.T) {
+	keys := [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+
+	next := nextSessionTicketKeys(keys, []byte("d"))
+	expected := [][]byte{[]byte("d"), []byte("a"), []byte("b")}
+	if len(next) != len(expected) {
+		t.Fatalf("expected %v keys but returned %v", len(expected), len(next))
+	}
+	for i := range expected {
+		if !bytes.Equal(next[i], expected[i]) {
+			t.Errorf("expected key %v to be %s but returned %s", i, expected[i], next[i])
+		}
+	}
+
+	next = nextSessionTicketKeys([][]byte{}, []byte("a"))
+	if len(next) != 1 {
+		t.Errorf("expected one key but returned %v", len(next))
+	}
+}
+
+func TestRotateSessionTicketKeys(t *testing.T) {
+	client := testclient.NewSimpleClientset()
+	now := time.Now()
+	rotation := 12 * time.Hour
+
+	err := rotateSessionTicketKeys(client, "default/tickets", rotation, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s, err := client.CoreV1().Secrets("default").Get("tickets", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected a secret with the session ticket keys: %v", err)
+	}
+	keys := sessionTicketKeysFromSecret(s)
+	if len(keys) != 1 || len(keys[0]) != sessionTicketKeyLength {
+		t.Fatalf("expected one key of %v bytes but returned %v", sessionTicketKeyLength, keys)
+	}
+
+	// the rotation period is not over
+	err = rotateSessionTicketKeys(client, "default/tickets", rotation, now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s, _ = client.CoreV1().Secrets("default").Get("tickets", metav1.GetOptions{})
+	if len(sessionTicketKeysFromSecret(s)) != 1 {
+		t.Errorf("expected no rotation before the end of the rotation period")
+	}
+
+	for i := 1; i <= sessionTicketKeysCount; i++ {
+		err = rotateSessionTicketKeys(client, "default/tickets", rotation, now.Add(time.Duration(i)*rotation))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	s, _ = client.CoreV1().Secrets("default").Get("tickets", metav1.GetOptions{})
+	rotated := sessionTicketKeysFromSecret(s)
+	if len(rotated) != sessionTicketKeysCount {
+		t.Fatalf("expected %v keys but returned %v", sessionTicketKeysCount, len(rotated))
+	}
+	for _, k := range rotated {
+		if bytes.Equal(k, keys[0]) {
+			t.Errorf("expected the first key to be discarded after %v rotations", sessionTicketKeysCount)
+		}
+	}
+}
@@ -26,6 +26,9 @@ import (
 const (
 	ngxStatusPath = "/nginx_status"
 	ngxVtsPath    = "/nginx_status/format/json"
+
+	ngxRateLimitPath = "/ratelimit_status"
+	ngxDenyPath      = "/deny_status"
 )
 
 func (n *NGINXController) setupMonitor(sm statusModule) {
@@ -39,9 +42,11 @@ func (n *NGINXController) setupMonitor(sm statusModule) {
 }
 
 type statsCollector struct {
-	process prometheus.Collector
-	basic   collector.Stopable
-	vts     collector.Stopable
+	process   prometheus.Collector
+	basic     collector.Stopable
+	vts       collector.Stopable
+	ratelimit collector.Stopable
+	deny      collector.Stopable
 
 	namespace  string
 	watchClass string
@@ -88,10 +93,25 @@ func newStatsCollector(ns, class, binary string, port int) *statsCollector {
 		glog.Fatalf("unexpected error registering nginx collector: %v", err)
 	}
 
+	// the rate limit and deny metrics do not depend on the status module
+	rl := collector.NewRateLimitCollector(ns, class, port, ngxRateLimitPath)
+	err = prometheus.Register(rl)
+	if err != nil {
+		glog.Fatalf("unexpected error registering nginx rate limit collector: %v", err)
+	}
+
+	deny := collector.NewDenyCollector(ns, class, port, ngxDenyPath)
+	err = prometheus.Register(deny)
+	if err != nil {
+		glog.Fatalf("unexpected error registering nginx deny collector: %v", err)
+	}
+
 	return &statsCollector{
 		namespace:  ns,
 		watchClass: class,
 		process:    pc,
+		ratelimit:  rl,
+		deny:       deny,
 		port:       port,
 	}
 }
@@ -17,6 +17,7 @@ limitations under the License.
 package store
 
 import (
+	"crypto/x509"
 	"fmt"
 	"strings"
 
@@ -83,6 +84,7 @@ func (s k8sStore) getPemCertificate(secretName string) (*ingress.SSLCert, error)
 	cert, okcert := secret.Data[apiv1.TLSCertKey]
 	key, okkey := secret.Data[apiv1.TLSPrivateKeyKey]
 	ca := secret.Data["ca.crt"]
+	crl := secret.Data["ca.crl"]
 
 	// namespace/secretName -> namespace-secretName
 	nsSecName := strings.Replace(secretName, "/", "-", -1)
@@ -106,9 +108,16 @@ func (s k8sStore) getPemCertificate(secretName string) (*ingress.SSLCert, error)
 		glog.V(3).Infof("found 'tls.crt' and 'tls.key', configuring %v as a TLS Secret (CN: %v)", secretName, sslCert.CN)
 		if ca != nil {
 			glog.V(3).Infof("found 'ca.crt', secret %v can also be used for Certificate Authentication", secretName)
+
+			if len(crl) > 0 {
+				err = ssl.AddCRL(nsSecName, crl, ca, sslCert, s.filesystem)
+				if err != nil {
+					return nil, fmt.Errorf("unexpected error creating CRL file: %v", err)
+				}
+			}
 		}
 	} else if ca != nil {
-		sslCert, err = ssl.AddCertAuth(nsSecName, ca, s.filesystem)
+		sslCert, err = ssl.AddCertAuth(nsSecName, ca, crl, s.filesystem)
 
 		if err != nil {
 			return nil, fmt.Errorf("unexpected error creating pem file: %v", err)
@@ -118,8 +127,17 @@ func (s k8sStore) getPemCertificate(secretName string) (*ingress.SSLCert, error)
 		// this does not enable Certificate Authentication
 		glog.V(3).Infof("found only 'ca.crt', configuring %v as an Certificate Authentication Secret", secretName)
 
+	} else if crl != nil {
+		sslCert = &ingress.SSLCert{}
+		err = ssl.AddCRL(nsSecName, crl, nil, sslCert, s.filesystem)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected error creating CRL file: %v", err)
+		}
+
+		// used in the 'nginx.ingress.kubernetes.io/auth-tls-crl-secret' annotation
+		glog.V(3).Infof("found only 'ca.crl', configuring %v as a Certificate Revocation List Secret", secretName)
 	} else {
-		return nil, fmt.Errorf("no keypair or CA cert could be found in %v", secretName)
+		return nil, fmt.Errorf("no keypair, CA cert or CRL could be found in %v", secretName)
 	}
 
 	sslCert.Name = secret.Name
@@ -129,6 +147,15 @@ func (s k8sStore) getPemCertificate(secretName string) (*ingress.SSLCert, error)
 }
 
 func (s k8sStore) checkSSLChainIssues() {
+	bundle := s.intermediateCertificates()
+
+	incomplete := []string{}
+	defer func() {
+		if len(incomplete) > 0 {
+			glog.Warningf("unable to complete the intermediate CA certificates chain of secrets: %v", strings.Join(incomplete, ", "))
+		}
+	}()
+
 	for _, item := range s.ListLocalSecrets() {
 		secretName := k8s.MetaNamespaceKey(item)
 		secret, err := s.GetLocalSecret(secretName)
@@ -141,9 +168,16 @@ func (s k8sStore) checkSSLChainIssues() {
 			continue
 		}
 
-		data, err := ssl.FullChainCert(secret.PemFileName, s.filesystem)
+		if secret.PemFileName == "" {
+			// secret with only a certificate revocation list
+			continue
+		}
+
+		data, err := ssl.FullChainCert(secret.PemFileName, bundle, s.filesystem)
 		if err != nil {
 			glog.Errorf("unexpected error generating SSL certificate with full intermediate chain CA certs: %v", err)
+			s.setSSLChainError(secretName, secret, err)
+			incomplete = append(incomplete, secretName)
 			continue
 		}
 
@@ -170,6 +204,7 @@ func (s k8sStore) checkSSLChainIssues() {
 		}
 
 		dst.FullChainPemFileName = fullChainPemFileName
+		dst.ChainError = ""
 
 		glog.Infof("updating local copy of ssl certificate %v with missing intermediate CA certs", secretName)
 		s.sslStore.Update(secretName, dst)
@@ -179,6 +214,55 @@ func (s k8sStore) checkSSLChainIssues() {
 	}
 }
 
+// intermediateCertificates returns the certificates of the intermediate
+// bundle. The bundle is read from a ConfigMap or, if it does not exist, a
+// Secret. All the values of the ConfigMap or Secret are parsed as PEM
+// encoded certificates.
+func (s k8sStore) intermediateCertificates() []*x509.Certificate {
+	if s.intermediateBundle == "" {
+		return nil
+	}
+
+	var values [][]byte
+	if cm, err := s.listers.ConfigMap.ByKey(s.intermediateBundle); err == nil {
+		for _, v := range cm.Data {
+			values = append(values, []byte(v))
+		}
+	} else if secret, err := s.listers.Secret.ByKey(s.intermediateBundle); err == nil {
+		for _, v := range secret.Data {
+			values = append(values, v)
+		}
+	} else {
+		glog.Warningf("intermediate bundle %v not found (ConfigMap or Secret)", s.intermediateBundle)
+		return nil
+	}
+
+	var certs []*x509.Certificate
+	for _, v := range values {
+		certs = append(certs, ssl.ParseCertificates(v)...)
+	}
+
+	return certs
+}
+
+// setSSLChainError records the failure of the completion of the chain of a
+// certificate in the local store, to be reported by the controller
+func (s k8sStore) setSSLChainError(secretName string, secret *ingress.SSLCert, chainErr error) {
+	if secret.ChainError == chainErr.Error() {
+		return
+	}
+
+	dst := &ingress.SSLCert{}
+	err := mergo.MergeWithOverwrite(dst, secret)
+	if err != nil {
+		glog.Errorf("unexpected error updating SSL certificate %v: %v", secretName, err)
+		return
+	}
+
+	dst.ChainError = chainErr.Error()
+	s.sslStore.Update(secretName, dst)
+}
+
 // checkMissingSecrets verifies if one or more ingress rules contains
 // a reference to a secret that is not present in the local secret store.
 func (s k8sStore) checkMissingSecrets() {
@@ -194,13 +278,15 @@ func (s k8sStore) checkMissingSecrets() {
 			}
 		}
 
-		key, _ := parser.GetStringAnnotation("auth-tls-secret", ing)
-		if key == "" {
-			continue
-		}
+		for _, annotation := range []string{"auth-tls-secret", "auth-tls-crl-secret", "ssl-secondary-secret"} {
+			key, _ := parser.GetStringAnnotation(annotation, ing)
+			if key == "" {
+				continue
+			}
 
-		if _, ok := s.sslStore.Get(key); !ok {
-			s.syncSecret(key)
+			if _, ok := s.sslStore.Get(key); !ok {
+				s.syncSecret(key)
+			}
 		}
 	}
 }
@@ -216,11 +302,13 @@ func (s k8sStore) ReadSecrets(ing *extensions.Ingress) {
 		s.syncSecret(key)
 	}
 
-	key, _ := parser.GetStringAnnotation("auth-tls-secret", ing)
-	if key == "" {
-		return
+	for _, annotation := range []string{"auth-tls-secret", "auth-tls-crl-secret", "ssl-secondary-secret"} {
+		key, _ := parser.GetStringAnnotation(annotation, ing)
+		if key == "" {
+			continue
+		}
+		s.syncSecret(key)
 	}
-	s.syncSecret(key)
 }
 
 // sendDummyEvent sends a dummy event to trigger an update
@@ -0,0 +1,205 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/imdario/mergo"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/internal/net/ssl"
+)
+
+const (
+	// ocspDefaultRefresh is the refresh interval of OCSP responses without
+	// a next update time
+	ocspDefaultRefresh = time.Hour
+
+	ocspTimeout = 10 * time.Second
+)
+
+var (
+	ocspStapleAge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "ingress_controller",
+			Name:      "ocsp_staple_age_seconds",
+			Help:      "Number of seconds since the OCSP response stapled by a certificate was produced (thisUpdate)",
+		},
+		[]string{"secret"},
+	)
+	ocspFetchErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "ingress_controller",
+			Name:      "ocsp_fetch_errors",
+			Help:      "Cumulative number of errors obtaining OCSP responses",
+		},
+		[]string{"secret"},
+	)
+
+	ocspClient = &http.Client{Timeout: ocspTimeout}
+)
+
+func init() {
+	prometheus.MustRegister(ocspStapleAge)
+	prometheus.MustRegister(ocspFetchErrors)
+}
+
+// checkOCSPResponses fetches the OCSP responses of the certificates in the
+// local store and writes them next to the PEM files to be used in the
+// ssl_stapling_file directive. Responses are refreshed before they expire.
+func (s k8sStore) checkOCSPResponses() {
+	now := time.Now()
+	for _, item := range s.ListLocalSecrets() {
+		key := k8s.MetaNamespaceKey(item)
+		cert, err := s.GetLocalSecret(key)
+		if err != nil || cert.Certificate == nil || len(cert.Certificate.OCSPServer) == 0 {
+			continue
+		}
+
+		if cert.OCSPResponseFileName != "" {
+			ocspStapleAge.WithLabelValues(key).Set(now.Sub(cert.OCSPThisUpdate).Seconds())
+		}
+
+		if !ocspRefreshRequired(cert, now) {
+			continue
+		}
+
+		err = s.updateOCSPResponse(key, cert)
+		if err == nil {
+			continue
+		}
+
+		ocspFetchErrors.WithLabelValues(key).Inc()
+		glog.Warningf("unexpected error obtaining OCSP response for secret %v: %v", key, err)
+
+		if cert.OCSPResponseFileName != "" && !cert.OCSPNextUpdate.IsZero() && now.After(cert.OCSPNextUpdate) {
+			// nginx must not staple an expired response
+			glog.Warningf("OCSP response of secret %v expired at %v, disabling stapling", key, cert.OCSPNextUpdate)
+			s.setOCSPResponse(key, cert.PemSHA, nil, "")
+			ocspStapleAge.DeleteLabelValues(key)
+		}
+	}
+}
+
+// ocspRefreshRequired returns true when the certificate does not have an
+// OCSP response or the response reached the middle of its validity period
+func ocspRefreshRequired(cert *ingress.SSLCert, now time.Time) bool {
+	if cert.OCSPResponseFileName == "" {
+		return true
+	}
+
+	if cert.OCSPNextUpdate.IsZero() {
+		return now.After(cert.OCSPThisUpdate.Add(ocspDefaultRefresh))
+	}
+
+	validity := cert.OCSPNextUpdate.Sub(cert.OCSPThisUpdate)
+	return now.After(cert.OCSPThisUpdate.Add(validity / 2))
+}
+
+// updateOCSPResponse fetches the OCSP response of a certificate and writes
+// it to disk
+func (s k8sStore) updateOCSPResponse(key string, cert *ingress.SSLCert) error {
+	var chain []byte
+	for _, name := range []string{cert.PemFileName, cert.FullChainPemFileName} {
+		if name == "" {
+			continue
+		}
+
+		data, err := s.filesystem.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		chain = append(chain, data...)
+	}
+
+	issuer := ssl.FindIssuer(cert.Certificate, chain)
+	if issuer == nil {
+		return fmt.Errorf("the issuer certificate is not available (include the intermediate certificates in the secret)")
+	}
+
+	resp, err := ssl.FetchOCSPResponse(cert.Certificate, issuer, ocspClient)
+	if err != nil {
+		return err
+	}
+
+	if resp.Status == ssl.OCSPRevoked {
+		glog.Warningf("the certificate of secret %v was revoked at %v", key, resp.RevokedAt)
+	}
+
+	fileName := fmt.Sprintf("%v.ocsp", strings.TrimSuffix(cert.PemFileName, ".pem"))
+	f, err := s.filesystem.Create(fileName)
+	if err != nil {
+		return fmt.Errorf("could not create OCSP response file %v: %v", fileName, err)
+	}
+	defer f.Close()
+
+	_, err = f.Write(resp.Raw)
+	if err != nil {
+		return fmt.Errorf("could not write OCSP response file %v: %v", fileName, err)
+	}
+
+	glog.V(3).Infof("OCSP response of secret %v valid until %v", key, resp.NextUpdate)
+	s.setOCSPResponse(key, cert.PemSHA, resp, fileName)
+	return nil
+}
+
+// setOCSPResponse updates the OCSP response of the certificate in the local
+// store. A nil response removes the current one.
+func (s k8sStore) setOCSPResponse(key, pemSHA string, resp *ssl.OCSPResponse, fileName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cur, err := s.GetLocalSecret(key)
+	if err != nil || cur.PemSHA != pemSHA {
+		// the certificate changed while the response was obtained
+		return
+	}
+
+	dst := &ingress.SSLCert{}
+	err = mergo.MergeWithOverwrite(dst, cur)
+	if err != nil {
+		glog.Errorf("unexpected error updating OCSP response of secret %v: %v", key, err)
+		return
+	}
+
+	dst.OCSPResponseFileName = ""
+	dst.OCSPResponseSHA = ""
+	dst.OCSPThisUpdate = time.Time{}
+	dst.OCSPNextUpdate = time.Time{}
+
+	if resp != nil {
+		sum := sha1.Sum(resp.Raw)
+		dst.OCSPResponseFileName = fileName
+		dst.OCSPResponseSHA = hex.EncodeToString(sum[:])
+		dst.OCSPThisUpdate = resp.ThisUpdate
+		dst.OCSPNextUpdate = resp.NextUpdate
+	}
+
+	s.sslStore.Update(key, dst)
+	// this update must trigger an update
+	// (like an update event from a change in Ingress)
+	s.sendDummyEvent()
+}
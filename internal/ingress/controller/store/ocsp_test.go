@@ -0,0 +1,60 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+)
+
+func TestOCSPRefreshRequired(t *testing.T) {
+	now := time.Now()
+
+	testCases := []struct {
+		title    string
+		cert     *ingress.SSLCert
+		expected bool
+	}{
+		{"without response", &ingress.SSLCert{}, true},
+		{"recent response", &ingress.SSLCert{
+			OCSPResponseFileName: "/etc/ingress-controller/ssl/default-foo.ocsp",
+			OCSPThisUpdate:       now.Add(-time.Hour),
+			OCSPNextUpdate:       now.Add(7 * 24 * time.Hour),
+		}, false},
+		{"response in the second half of its validity", &ingress.SSLCert{
+			OCSPResponseFileName: "/etc/ingress-controller/ssl/default-foo.ocsp",
+			OCSPThisUpdate:       now.Add(-5 * 24 * time.Hour),
+			OCSPNextUpdate:       now.Add(2 * 24 * time.Hour),
+		}, true},
+		{"recent response without next update", &ingress.SSLCert{
+			OCSPResponseFileName: "/etc/ingress-controller/ssl/default-foo.ocsp",
+			OCSPThisUpdate:       now.Add(-time.Minute),
+		}, false},
+		{"old response without next update", &ingress.SSLCert{
+			OCSPResponseFileName: "/etc/ingress-controller/ssl/default-foo.ocsp",
+			OCSPThisUpdate:       now.Add(-2 * ocspDefaultRefresh),
+		}, true},
+	}
+
+	for _, tc := range testCases {
+		if r := ocspRefreshRequired(tc.cert, now); r != tc.expected {
+			t.Errorf("%v: expected %v but returned %v", tc.title, tc.expected, r)
+		}
+	}
+}
@@ -20,7 +20,10 @@ import (
 	"encoding/base64"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"sync"
 	"time"
 
@@ -43,7 +46,9 @@ import (
 	"k8s.io/ingress-nginx/internal/file"
 	"k8s.io/ingress-nginx/internal/ingress"
 	"k8s.io/ingress-nginx/internal/ingress/annotations"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/auth"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/class"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/geoip"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
 	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
 	ngx_template "k8s.io/ingress-nginx/internal/ingress/controller/template"
@@ -89,6 +94,10 @@ type Storer interface {
 	//   ca.crt: contains the certificate chain used for authentication
 	GetAuthCertificate(string) (*resolver.AuthSSLCert, error)
 
+	// GetTLSCertificate resolves a given secret name into an SSL certificate.
+	// The secret must contain a keypair (tls.crt and tls.key)
+	GetTLSCertificate(string) (*ingress.SSLCert, error)
+
 	// GetDefaultBackend returns the default backend configuration
 	GetDefaultBackend() defaults.Backend
 
@@ -97,6 +106,10 @@ type Storer interface {
 
 	// ReadSecrets extracts information about secrets from an Ingress rule
 	ReadSecrets(*extensions.Ingress)
+
+	// UpdateAnnotations extracts again the annotations of all the Ingress
+	// rules, i.e. after a change of the files used to validate them
+	UpdateAnnotations()
 }
 
 // EventType type of event associated with an informer
@@ -171,6 +184,10 @@ func (c *Controller) Run(stopCh chan struct{}) {
 type k8sStore struct {
 	isOCSPCheckEnabled bool
 
+	// isOCSPStaplingEnabled enables the fetch of the OCSP responses of the
+	// certificates stapled by nginx
+	isOCSPStaplingEnabled bool
+
 	// backendConfig contains the running configuration from the configmap
 	// this is required because this rarely changes but is a very expensive
 	// operation to execute in each OnUpdate invocation
@@ -193,6 +210,10 @@ type k8sStore struct {
 	// secret in the annotations.
 	secretIngressMap map[string]sets.String
 
+	// secretIngressMu protects secretIngressMap. The annotations are
+	// extracted from the informers and from other goroutines
+	secretIngressMu *sync.RWMutex
+
 	filesystem file.Filesystem
 
 	// updateCh
@@ -202,11 +223,21 @@ type k8sStore struct {
 	mu *sync.Mutex
 
 	defaultSSLCertificate string
+
+	// intermediateBundle ConfigMap or Secret (<namespace>/<name>) with
+	// the intermediate CA certificates used to complete certificate chains
+	intermediateBundle string
+
+	// sessionTicketKeys Secret (<namespace>/<name>) with the TLS session
+	// ticket keys. Changes in the secret require a reload
+	sessionTicketKeys string
+
+	recorder record.EventRecorder
 }
 
 // New creates a new object store to be used in the ingress controller
-func New(checkOCSP bool,
-	namespace, configmap, tcp, udp, defaultSSLCertificate string,
+func New(checkOCSP, ocspStapling bool,
+	namespace, configmap, tcp, udp, defaultSSLCertificate, intermediateBundle, sessionTicketKeys string,
 	resyncPeriod time.Duration,
 	client clientset.Interface,
 	fs file.Filesystem,
@@ -214,6 +245,7 @@ func New(checkOCSP bool,
 
 	store := &k8sStore{
 		isOCSPCheckEnabled:    checkOCSP,
+		isOCSPStaplingEnabled: ocspStapling,
 		cache:                 &Controller{},
 		listers:               &Lister{},
 		sslStore:              NewSSLCertTracker(),
@@ -222,7 +254,10 @@ func New(checkOCSP bool,
 		backendConfig:         ngx_config.NewDefault(),
 		mu:                    &sync.Mutex{},
 		secretIngressMap:      make(map[string]sets.String),
+		secretIngressMu:       &sync.RWMutex{},
 		defaultSSLCertificate: defaultSSLCertificate,
+		intermediateBundle:    intermediateBundle,
+		sessionTicketKeys:     sessionTicketKeys,
 	}
 
 	eventBroadcaster := record.NewBroadcaster()
@@ -233,6 +268,7 @@ func New(checkOCSP bool,
 	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, apiv1.EventSource{
 		Component: "nginx-ingress-controller",
 	})
+	store.recorder = recorder
 
 	// k8sStore fulfils resolver.Resolver interface
 	store.annotations = annotations.NewAnnotationExtractor(store)
@@ -273,6 +309,9 @@ func New(checkOCSP bool,
 				return
 			}
 			recorder.Eventf(delIng, apiv1.EventTypeNormal, "DELETE", fmt.Sprintf("Ingress %s/%s", delIng.Namespace, delIng.Name))
+			if anns, err := store.GetIngressAnnotations(delIng); err == nil {
+				removeAuthFile(anns.BasicDigestAuth.File)
+			}
 			store.listers.IngressAnnotation.Delete(delIng)
 			updateCh.In() <- Event{
 				Type: DeleteEvent,
@@ -303,13 +342,45 @@ func New(checkOCSP bool,
 	}
 
 	secrEventHandler := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			sec := obj.(*apiv1.Secret)
+			key := fmt.Sprintf("%v/%v", sec.Namespace, sec.Name)
+
+			if key == store.sessionTicketKeys {
+				glog.Infof("TLS session ticket keys secret %v created", key)
+				updateCh.In() <- Event{
+					Type: ConfigurationEvent,
+					Obj:  obj,
+				}
+			}
+
+			// ingress annotations referencing the secret before its creation
+			if store.reparseIngresses(key) {
+				glog.Infof("secret %v created and it is used in ingress annotations", key)
+				updateCh.In() <- Event{
+					Type: ConfigurationEvent,
+					Obj:  obj,
+				}
+			}
+		},
 		UpdateFunc: func(old, cur interface{}) {
 			if !reflect.DeepEqual(old, cur) {
 				sec := cur.(*apiv1.Secret)
 				key := fmt.Sprintf("%v/%v", sec.Namespace, sec.Name)
 
+				if key == store.sessionTicketKeys {
+					if !reflect.DeepEqual(old.(*apiv1.Secret).Data, sec.Data) {
+						glog.Infof("TLS session ticket keys in secret %v changed", key)
+						updateCh.In() <- Event{
+							Type: ConfigurationEvent,
+							Obj:  cur,
+						}
+					}
+					return
+				}
+
 				// parse the ingress annotations (again)
-				if set, ok := store.secretIngressMap[key]; ok {
+				if ings := store.getSecretReferences(key); len(ings) > 0 {
 					glog.Infof("secret %v changed and it is used in ingress annotations. Parsing...", key)
 					_, err := store.GetLocalSecret(k8s.MetaNamespaceKey(sec))
 					if err == nil {
@@ -320,7 +391,7 @@ func New(checkOCSP bool,
 						}
 					}
 
-					for _, name := range set.List() {
+					for _, name := range ings {
 						ing, _ := store.GetIngress(name)
 						store.extractAnnotations(ing)
 					}
@@ -329,6 +400,10 @@ func New(checkOCSP bool,
 						Type: ConfigurationEvent,
 						Obj:  cur,
 					}
+				} else if _, err := store.GetLocalSecret(key); err == nil {
+					// the secret is not referenced by ingress annotations
+					// but is used by other resources (like TCP services)
+					store.syncSecret(key)
 				}
 			}
 		},
@@ -355,15 +430,8 @@ func New(checkOCSP bool,
 
 			// parse the ingress annotations (again)c
 			key := fmt.Sprintf("%v/%v", sec.Namespace, sec.Name)
-			if set, ok := store.secretIngressMap[key]; ok {
-				glog.Infof("secret %v was removed and it is used in ingress annotations. Parsing...", key)
-				for _, name := range set.List() {
-					ing, _ := store.GetIngress(name)
-					if ing != nil {
-						store.extractAnnotations(ing)
-					}
-				}
-
+			if store.reparseIngresses(key) {
+				glog.Infof("secret %v was removed and it is used in ingress annotations", key)
 				updateCh.In() <- Event{
 					Type: ConfigurationEvent,
 					Obj:  sec,
@@ -409,6 +477,14 @@ func New(checkOCSP bool,
 					Obj:  obj,
 				}
 			}
+			// ingress annotations referencing the configmap before its creation
+			if store.reparseIngresses(mapKey) {
+				glog.Infof("configmap %v created and it is used in ingress annotations", mapKey)
+				updateCh.In() <- Event{
+					Type: ConfigurationEvent,
+					Obj:  obj,
+				}
+			}
 		},
 		UpdateFunc: func(old, cur interface{}) {
 			if !reflect.DeepEqual(old, cur) {
@@ -430,6 +506,48 @@ func New(checkOCSP bool,
 						Obj:  cur,
 					}
 				}
+				// global redirect map
+				if mapKey == store.backendConfig.RedirectMap {
+					recorder.Eventf(m, apiv1.EventTypeNormal, "UPDATE", "ConfigMap %v", mapKey)
+					updateCh.In() <- Event{
+						Type: ConfigurationEvent,
+						Obj:  cur,
+					}
+				}
+				// configmaps with JWT keys, redirect maps or headers used in ingress annotations
+				if store.reparseIngresses(mapKey) {
+					glog.Infof("configmap %v changed and it is used in ingress annotations", mapKey)
+					updateCh.In() <- Event{
+						Type: ConfigurationEvent,
+						Obj:  cur,
+					}
+				}
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			m, ok := obj.(*apiv1.ConfigMap)
+			if !ok {
+				// If we reached here it means the configmap was deleted but its final state is unrecorded.
+				tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+				if !ok {
+					glog.Errorf("couldn't get object from tombstone %#v", obj)
+					return
+				}
+				m, ok = tombstone.Obj.(*apiv1.ConfigMap)
+				if !ok {
+					glog.Errorf("Tombstone contained object that is not a ConfigMap: %#v", obj)
+					return
+				}
+			}
+
+			// the ingress annotations referencing the configmap are invalid now
+			mapKey := fmt.Sprintf("%s/%s", m.Namespace, m.Name)
+			if store.reparseIngresses(mapKey) {
+				glog.Infof("configmap %v was removed and it is used in ingress annotations", mapKey)
+				updateCh.In() <- Event{
+					Type: ConfigurationEvent,
+					Obj:  m,
+				}
 			}
 		},
 	}
@@ -467,34 +585,154 @@ func (s *k8sStore) extractAnnotations(ing *extensions.Ingress) {
 
 	anns := s.annotations.Extract(ing)
 
-	secName := anns.BasicDigestAuth.Secret
-	if secName != "" {
-		if _, ok := s.secretIngressMap[secName]; !ok {
-			s.secretIngressMap[secName] = sets.NewString()
-		}
-		v := s.secretIngressMap[secName]
-		if !v.Has(key) {
-			v.Insert(key)
-		}
+	// remove the password file of the previous annotations when the
+	// authentication is disabled
+	if old, err := s.GetIngressAnnotations(ing); err == nil && old.BasicDigestAuth.File != anns.BasicDigestAuth.File {
+		removeAuthFile(old.BasicDigestAuth.File)
 	}
 
-	secName = anns.CertificateAuth.Secret
-	if secName != "" {
-		if _, ok := s.secretIngressMap[secName]; !ok {
-			s.secretIngressMap[secName] = sets.NewString()
+	for _, entry := range anns.BasicDigestAuth.InvalidEntries {
+		s.recorder.Eventf(ing, apiv1.EventTypeWarning, "AUTH", "Rejecting invalid entry in secret %v: %v", anns.BasicDigestAuth.Secret, entry)
+	}
+	for _, entry := range anns.BasicDigestAuth.WeakEntries {
+		s.recorder.Eventf(ing, apiv1.EventTypeWarning, "AUTH", "Weak password hash in secret %v: %v", anns.BasicDigestAuth.Secret, entry)
+	}
+
+	// nginx fails to start when the GeoIP databases are not valid, the
+	// locations with country access control are denied instead
+	if dbErr, ok := geoip.IsDatabaseError(anns.Denied); ok {
+		s.recorder.Eventf(ing, apiv1.EventTypeWarning, "GEOIP", "Denying access to Ingress %v: %v", key, dbErr)
+	}
+
+	// the rules of redirect maps are reported only when they change
+	if old, err := s.GetIngressAnnotations(ing); anns.RedirectMap.Name != "" && (err != nil || !(&old.RedirectMap).Equal(&anns.RedirectMap)) {
+		for _, rule := range anns.RedirectMap.InvalidRules {
+			s.recorder.Eventf(ing, apiv1.EventTypeWarning, "REDIRECT", "Ignoring invalid rule in redirect map %v: %v", anns.RedirectMap.Name, rule)
 		}
-		v := s.secretIngressMap[secName]
-		if !v.Has(key) {
-			v.Insert(key)
+		s.recorder.Eventf(ing, apiv1.EventTypeNormal, "REDIRECT", "Loaded %v redirect rules from ConfigMap %v", len(anns.RedirectMap.Rules), anns.RedirectMap.Name)
+	}
+
+	if old, err := s.GetIngressAnnotations(ing); err != nil || !(&old.CustomHeaders).Equal(&anns.CustomHeaders) {
+		for _, header := range anns.CustomHeaders.InvalidHeaders {
+			s.recorder.Eventf(ing, apiv1.EventTypeWarning, "HEADERS", "Ignoring invalid header %v", header)
 		}
 	}
 
+	s.addSecretReference(anns.BasicDigestAuth.Secret, key)
+	s.addSecretReference(anns.CertificateAuth.Secret, key)
+	s.addSecretReference(anns.CertificateAuth.CRLSecret, key)
+	s.addSecretReference(anns.SSLSecondarySecret, key)
+	s.addSecretReference(anns.SecureUpstream.CACert.Secret, key)
+	s.addSecretReference(anns.SecureUpstream.ClientCert.Secret, key)
+	// the configmaps are recorded even when the parsing fails because they
+	// can be created later. The JWT keys can be located in a secret
+	s.addSecretReference(annotationReference(ing, "jwt-keys"), key)
+	s.addSecretReference(annotationReference(ing, "redirect-map"), key)
+	s.addSecretReference(annotationReference(ing, "request-headers"), key)
+	s.addSecretReference(annotationReference(ing, "response-headers"), key)
+
 	err := s.listers.IngressAnnotation.Update(anns)
 	if err != nil {
 		glog.Error(err)
 	}
 }
 
+// addSecretReference records that an Ingress uses a secret or configmap in
+// its annotations. Changes in the secret or configmap parse the annotations
+// of the Ingress again
+func (s *k8sStore) addSecretReference(name, ingKey string) {
+	if name == "" {
+		return
+	}
+
+	s.secretIngressMu.Lock()
+	defer s.secretIngressMu.Unlock()
+
+	if _, ok := s.secretIngressMap[name]; !ok {
+		s.secretIngressMap[name] = sets.NewString()
+	}
+	s.secretIngressMap[name].Insert(ingKey)
+}
+
+// reparseIngresses parses the annotations of the ingresses that reference
+// the secret or configmap name again. Returns true if any ingress uses it
+func (s *k8sStore) reparseIngresses(name string) bool {
+	ings := s.getSecretReferences(name)
+	for _, ingKey := range ings {
+		ing, _ := s.GetIngress(ingKey)
+		if ing != nil {
+			s.extractAnnotations(ing)
+		}
+	}
+
+	return len(ings) > 0
+}
+
+// annotationReference returns the configmap or secret (<namespace>/<name>)
+// referenced in an annotation of an Ingress, even if it does not exist. The
+// annotations can only reference objects in the namespace of the Ingress
+func annotationReference(ing *extensions.Ingress, annotation string) string {
+	name, err := parser.GetStringAnnotation(annotation, ing)
+	name = strings.TrimSpace(name)
+	if err != nil || name == "" || strings.Contains(name, "/") {
+		return ""
+	}
+
+	return fmt.Sprintf("%v/%v", ing.Namespace, name)
+}
+
+// getSecretReferences returns the keys of the ingresses that reference
+// the secret or configmap name in the annotations
+func (s *k8sStore) getSecretReferences(name string) []string {
+	s.secretIngressMu.RLock()
+	defer s.secretIngressMu.RUnlock()
+
+	if set, ok := s.secretIngressMap[name]; ok {
+		return set.List()
+	}
+
+	return nil
+}
+
+// removeAuthFile removes a password file of the basic or digest
+// authentication that is not used anymore
+func removeAuthFile(path string) {
+	if path == "" {
+		return
+	}
+
+	err := os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		glog.Warningf("unexpected error removing password file %v: %v", path, err)
+	}
+}
+
+// removeStaleAuthFiles removes the password files in dir that are not used
+// by the annotations of the Ingress rules, i.e. the files of the Ingress
+// rules deleted while the controller was not running
+func (s k8sStore) removeStaleAuthFiles(dir string) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.passwd"))
+	if err != nil {
+		glog.Warningf("unexpected error listing password files in %v: %v", dir, err)
+		return
+	}
+
+	used := sets.NewString()
+	for _, ing := range s.ListIngresses() {
+		anns, err := s.GetIngressAnnotations(ing)
+		if err == nil && anns.BasicDigestAuth.File != "" {
+			used.Insert(anns.BasicDigestAuth.File)
+		}
+	}
+
+	for _, file := range files {
+		if !used.Has(file) {
+			glog.Infof("removing stale password file %v", file)
+			removeAuthFile(file)
+		}
+	}
+}
+
 // GetSecret returns a Secret using the namespace and name as key
 func (s k8sStore) GetSecret(key string) (*apiv1.Secret, error) {
 	return s.listers.Secret.ByKey(key)
@@ -582,12 +820,33 @@ func (s k8sStore) GetAuthCertificate(name string) (*resolver.AuthSSLCert, error)
 	}
 
 	return &resolver.AuthSSLCert{
-		Secret:     name,
-		CAFileName: cert.CAFileName,
-		PemSHA:     cert.PemSHA,
+		Secret:      name,
+		CAFileName:  cert.CAFileName,
+		PemSHA:      cert.PemSHA,
+		PemFileName: cert.PemFileName,
+		CRLFileName: cert.CRLFileName,
+		CRLSHA:      cert.CRLSHA,
 	}, nil
 }
 
+// GetTLSCertificate is used by the TCP services to get a keypair from a secret
+func (s k8sStore) GetTLSCertificate(name string) (*ingress.SSLCert, error) {
+	if _, err := s.GetLocalSecret(name); err != nil {
+		s.syncSecret(name)
+	}
+
+	cert, err := s.GetLocalSecret(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if cert.Certificate == nil {
+		return nil, fmt.Errorf("secret %v does not contain a keypair", name)
+	}
+
+	return cert, nil
+}
+
 // GetDefaultBackend returns the default backend
 func (s k8sStore) GetDefaultBackend() defaults.Backend {
 	return s.backendConfig.Backend
@@ -600,6 +859,11 @@ func (s k8sStore) GetBackendConfiguration() ngx_config.Configuration {
 func (s *k8sStore) setConfig(cmap *apiv1.ConfigMap) {
 	s.backendConfig = ngx_template.ReadConfig(cmap.Data)
 
+	// annotations without value use the defaults of the configuration
+	for _, ing := range s.ListIngresses() {
+		s.extractAnnotations(ing)
+	}
+
 	// TODO: this should not be done here
 	if s.backendConfig.SSLSessionTicketKey != "" {
 		d, err := base64.StdEncoding.DecodeString(s.backendConfig.SSLSessionTicketKey)
@@ -611,6 +875,13 @@ func (s *k8sStore) setConfig(cmap *apiv1.ConfigMap) {
 	}
 }
 
+// UpdateAnnotations extracts again the annotations of all the Ingress rules
+func (s *k8sStore) UpdateAnnotations() {
+	for _, ing := range s.ListIngresses() {
+		s.extractAnnotations(ing)
+	}
+}
+
 // Run initiates the synchronization of the controllers
 // and the initial synchronization of the secrets.
 func (s k8sStore) Run(stopCh chan struct{}) {
@@ -623,6 +894,8 @@ func (s k8sStore) Run(stopCh chan struct{}) {
 		s.ReadSecrets(ing)
 	}
 
+	s.removeStaleAuthFiles(auth.AuthDirectory)
+
 	if s.defaultSSLCertificate != "" {
 		s.syncSecret(s.defaultSSLCertificate)
 	}
@@ -633,4 +906,8 @@ func (s k8sStore) Run(stopCh chan struct{}) {
 	if s.isOCSPCheckEnabled {
 		go wait.Until(s.checkSSLChainIssues, 60*time.Second, stopCh)
 	}
+
+	if s.isOCSPStaplingEnabled {
+		go wait.Until(s.checkOCSPResponses, 60*time.Second, stopCh)
+	}
 }
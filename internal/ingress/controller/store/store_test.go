@@ -18,7 +18,10 @@ package store
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -30,9 +33,14 @@ import (
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 
 	"k8s.io/ingress-nginx/internal/file"
+	"k8s.io/ingress-nginx/internal/ingress/annotations"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/auth"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
 	"k8s.io/ingress-nginx/test/e2e/framework"
 )
 
@@ -66,12 +74,14 @@ func TestStore(t *testing.T) {
 		}(updateCh)
 
 		fs := newFS(t)
-		storer := New(true,
+		storer := New(true, false,
 			ns.Name,
 			fmt.Sprintf("%v/config", ns.Name),
 			fmt.Sprintf("%v/tcp", ns.Name),
 			fmt.Sprintf("%v/udp", ns.Name),
 			"",
+			"",
+			"",
 			10*time.Minute,
 			clientSet,
 			fs,
@@ -153,12 +163,14 @@ func TestStore(t *testing.T) {
 		}(updateCh)
 
 		fs := newFS(t)
-		storer := New(true,
+		storer := New(true, false,
 			ns.Name,
 			fmt.Sprintf("%v/config", ns.Name),
 			fmt.Sprintf("%v/tcp", ns.Name),
 			fmt.Sprintf("%v/udp", ns.Name),
 			"",
+			"",
+			"",
 			10*time.Minute,
 			clientSet,
 			fs,
@@ -294,12 +306,14 @@ func TestStore(t *testing.T) {
 		}(updateCh)
 
 		fs := newFS(t)
-		storer := New(true,
+		storer := New(true, false,
 			ns.Name,
 			fmt.Sprintf("%v/config", ns.Name),
 			fmt.Sprintf("%v/tcp", ns.Name),
 			fmt.Sprintf("%v/udp", ns.Name),
 			"",
+			"",
+			"",
 			10*time.Minute,
 			clientSet,
 			fs,
@@ -380,12 +394,14 @@ func TestStore(t *testing.T) {
 		}(updateCh)
 
 		fs := newFS(t)
-		storer := New(true,
+		storer := New(true, false,
 			ns.Name,
 			fmt.Sprintf("%v/config", ns.Name),
 			fmt.Sprintf("%v/tcp", ns.Name),
 			fmt.Sprintf("%v/udp", ns.Name),
 			"",
+			"",
+			"",
 			10*time.Minute,
 			clientSet,
 			fs,
@@ -494,6 +510,100 @@ func TestStore(t *testing.T) {
 	// check invalid secret (missing ca)
 }
 
+func TestSecretReferences(t *testing.T) {
+	s := &k8sStore{
+		secretIngressMap: make(map[string]sets.String),
+		secretIngressMu:  &sync.RWMutex{},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s.addSecretReference("default/keys", fmt.Sprintf("default/ing-%v", i))
+			s.addSecretReference("", "default/ignored")
+			s.getSecretReferences("default/keys")
+		}(i)
+	}
+	wg.Wait()
+
+	ings := s.getSecretReferences("default/keys")
+	if len(ings) != 10 {
+		t.Errorf("expected 10 ingresses referencing the secret but %v returned", len(ings))
+	}
+	if ings := s.getSecretReferences("default/unknown"); len(ings) != 0 {
+		t.Errorf("expected no ingresses referencing an unknown secret but %v returned", ings)
+	}
+}
+
+func TestAnnotationReference(t *testing.T) {
+	ing := &extensions.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+			Annotations: map[string]string{
+				parser.GetAnnotationWithPrefix("redirect-map"):     " redirects",
+				parser.GetAnnotationWithPrefix("request-headers"):  "other/headers",
+				parser.GetAnnotationWithPrefix("response-headers"): " ",
+				parser.GetAnnotationWithPrefix("jwt-keys"):         "keys",
+			},
+		},
+	}
+
+	if name := annotationReference(ing, "redirect-map"); name != "default/redirects" {
+		t.Errorf("expected default/redirects but %v returned", name)
+	}
+	if name := annotationReference(ing, "jwt-keys"); name != "default/keys" {
+		t.Errorf("expected default/keys but %v returned", name)
+	}
+	for _, annotation := range []string{"request-headers", "response-headers"} {
+		if name := annotationReference(ing, annotation); name != "" {
+			t.Errorf("%v: expected no reference but %v returned", annotation, name)
+		}
+	}
+}
+
+func TestRemoveStaleAuthFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatalf("unexpected error creating temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	used := filepath.Join(dir, "default-foo.passwd")
+	stale := filepath.Join(dir, "default-deleted.passwd")
+	other := filepath.Join(dir, "ca.pem")
+	for _, file := range []string{used, stale, other} {
+		if err := ioutil.WriteFile(file, []byte{}, 0644); err != nil {
+			t.Fatalf("unexpected error writing file: %v", err)
+		}
+	}
+
+	s := &k8sStore{listers: &Lister{}}
+	s.listers.Ingress.Store = cache.NewStore(cache.MetaNamespaceKeyFunc)
+	s.listers.IngressAnnotation.Store = cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	meta := metav1.ObjectMeta{Name: "foo", Namespace: "default"}
+	s.listers.Ingress.Add(&extensions.Ingress{ObjectMeta: meta})
+	s.listers.IngressAnnotation.Add(&annotations.Ingress{
+		ObjectMeta:      meta,
+		BasicDigestAuth: auth.Config{File: used},
+	})
+
+	s.removeStaleAuthFiles(dir)
+
+	for file, exists := range map[string]bool{used: true, stale: false, other: true} {
+		_, err := os.Stat(file)
+		if exists && err != nil {
+			t.Errorf("expected the file %v to exist but returned %v", file, err)
+		}
+		if !exists && !os.IsNotExist(err) {
+			t.Errorf("expected the file %v to be removed", file)
+		}
+	}
+}
+
 func createNamespace(clientSet *kubernetes.Clientset, t *testing.T) *apiv1.Namespace {
 	t.Log("creating temporal namespace")
 	ns, err := framework.CreateKubeNamespace("store-test", clientSet)
@@ -0,0 +1,259 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/k8s"
+	ing_net "k8s.io/ingress-nginx/internal/net"
+)
+
+var (
+	// nginx time intervals (http://nginx.org/en/docs/syntax.html)
+	streamTimeoutRegex = regexp.MustCompile(`^([0-9]+(ms|s|m|h|d|w|M|y)?)+$`)
+
+	// compact format <namespace/service name>:<service port>:[PROXY]:[PROXY]
+	compactStreamServiceRegex = regexp.MustCompile(`^[^\s{]+$`)
+)
+
+// streamService describes a TCP or UDP service exposed using the structured
+// format of the tcp and udp services configmaps, i.e.
+//
+//	service: default/postgres
+//	port: 5432
+//	proxyProtocol:
+//	  decode: true
+//	proxyTimeout: 10m
+//	whitelistSourceRange:
+//	- 10.0.0.0/8
+//	limitConnections: 100
+//	tlsSecret: default/postgres-tls
+//	sni:
+//	- host: reports.example.com
+//	  service: default/postgres-reports
+//	  port: 5432
+type streamService struct {
+	// Service is the service to expose using the format <namespace>/<name>
+	Service string `json:"service"`
+	// Port is the number or the name of the service port
+	Port intstr.IntOrString `json:"port"`
+	// ProxyProtocol configures the decoding (listen) and/or encoding
+	// (proxy_pass) of the proxy protocol. Only valid for TCP services
+	ProxyProtocol ingress.ProxyProtocol `json:"proxyProtocol,omitempty"`
+	// ProxyTimeout overrides the proxy-stream-timeout setting
+	ProxyTimeout string `json:"proxyTimeout,omitempty"`
+	// ProxyResponses overrides the proxy-stream-responses setting.
+	// Only valid for UDP services
+	ProxyResponses int `json:"proxyResponses,omitempty"`
+	// WhitelistSourceRange list of CIDRs allowed to connect to the service
+	WhitelistSourceRange []string `json:"whitelistSourceRange,omitempty"`
+	// LimitConnections number of concurrent connections allowed from a
+	// single IP address
+	LimitConnections int `json:"limitConnections,omitempty"`
+	// TLSSecret secret (<namespace>/<name>) containing the keypair used to
+	// terminate TLS connections. Only valid for TCP services
+	TLSSecret string `json:"tlsSecret,omitempty"`
+	// SNI services selected using the server name indicated by the client in
+	// the TLS handshake. Service is used when there is no match
+	SNI []streamSNIService `json:"sni,omitempty"`
+}
+
+// streamSNIService describes a service selected using the server name
+// indicated by the client in a TLS terminated stream service
+type streamSNIService struct {
+	// Host is the server name. Wildcards (*.example.com) are allowed
+	Host string `json:"host"`
+	// Service is the service to expose using the format <namespace>/<name>
+	Service string `json:"service"`
+	// Port is the number or the name of the service port
+	Port intstr.IntOrString `json:"port"`
+}
+
+// parseStreamService parses the value of an entry in the tcp or udp services
+// configmap. The value can use the compact format
+// <namespace/service name>:<service port>:[PROXY]:[PROXY] or a YAML (or JSON)
+// document describing a streamService.
+func parseStreamService(value string, proto apiv1.Protocol) (*streamService, error) {
+	value = strings.TrimSpace(value)
+
+	var svc *streamService
+	var err error
+	if compactStreamServiceRegex.MatchString(value) {
+		svc, err = parseCompactStreamService(value, proto)
+	} else {
+		svc, err = parseStructuredStreamService(value)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.validate(proto)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc, nil
+}
+
+func parseCompactStreamService(value string, proto apiv1.Protocol) (*streamService, error) {
+	nsSvcPort := strings.Split(value, ":")
+	if len(nsSvcPort) < 2 {
+		return nil, fmt.Errorf("invalid format (namespace/name:port:[PROXY]:[PROXY]) '%v'", value)
+	}
+
+	svc := &streamService{
+		Service: nsSvcPort[0],
+		Port:    intstr.Parse(nsSvcPort[1]),
+	}
+
+	// Proxy protocol is possible if the service is TCP
+	if proto == apiv1.ProtocolTCP {
+		if len(nsSvcPort) >= 3 && strings.ToUpper(nsSvcPort[2]) == "PROXY" {
+			svc.ProxyProtocol.Decode = true
+		}
+		if len(nsSvcPort) == 4 && strings.ToUpper(nsSvcPort[3]) == "PROXY" {
+			svc.ProxyProtocol.Encode = true
+		}
+	}
+
+	return svc, nil
+}
+
+func parseStructuredStreamService(value string) (*streamService, error) {
+	data, err := yaml.YAMLToJSON([]byte(value))
+	if err != nil {
+		return nil, fmt.Errorf("invalid service definition: %v", err)
+	}
+
+	svc := &streamService{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(svc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid service definition: %v", err)
+	}
+
+	return svc, nil
+}
+
+// validate checks the content of the stream service returning an error
+// describing the first invalid field
+func (s *streamService) validate(proto apiv1.Protocol) error {
+	_, _, err := k8s.ParseNameNS(s.Service)
+	if err != nil {
+		return err
+	}
+
+	if s.Port.String() == "" || s.Port.String() == "0" {
+		return fmt.Errorf("service %v does not contain a port", s.Service)
+	}
+
+	if proto != apiv1.ProtocolTCP && (s.ProxyProtocol.Decode || s.ProxyProtocol.Encode) {
+		return fmt.Errorf("proxy protocol is only supported in TCP services")
+	}
+
+	if s.ProxyTimeout != "" && !streamTimeoutRegex.MatchString(s.ProxyTimeout) {
+		return fmt.Errorf("proxyTimeout %v is not a valid time interval", s.ProxyTimeout)
+	}
+
+	if s.ProxyResponses < 0 {
+		return fmt.Errorf("proxyResponses %v must be a positive number", s.ProxyResponses)
+	}
+
+	if s.ProxyResponses > 0 && proto != apiv1.ProtocolUDP {
+		return fmt.Errorf("proxyResponses is only supported in UDP services")
+	}
+
+	if s.LimitConnections < 0 {
+		return fmt.Errorf("limitConnections %v must be a positive number", s.LimitConnections)
+	}
+
+	if len(s.WhitelistSourceRange) > 0 {
+		ipnets, ips, err := ing_net.ParseIPNets(s.WhitelistSourceRange...)
+		if err != nil {
+			return fmt.Errorf("whitelistSourceRange contains an invalid IP address or network: %v", err)
+		}
+
+		cidrs := []string{}
+		for k := range ipnets {
+			cidrs = append(cidrs, k)
+		}
+		for k := range ips {
+			cidrs = append(cidrs, k)
+		}
+
+		sort.Strings(cidrs)
+		s.WhitelistSourceRange = cidrs
+	}
+
+	if s.TLSSecret != "" {
+		if proto != apiv1.ProtocolTCP {
+			return fmt.Errorf("TLS termination is only supported in TCP services")
+		}
+
+		_, _, err := k8s.ParseNameNS(s.TLSSecret)
+		if err != nil {
+			return fmt.Errorf("invalid tlsSecret: %v", err)
+		}
+	}
+
+	if len(s.SNI) > 0 && s.TLSSecret == "" {
+		return fmt.Errorf("sni requires TLS termination (tlsSecret)")
+	}
+
+	hosts := sets.NewString()
+	for _, sni := range s.SNI {
+		host := strings.TrimPrefix(sni.Host, "*.")
+		if errs := validation.IsDNS1123Subdomain(host); len(errs) > 0 {
+			return fmt.Errorf("sni host %v is not valid: %v", sni.Host, strings.Join(errs, ", "))
+		}
+
+		if hosts.Has(sni.Host) {
+			return fmt.Errorf("sni host %v is duplicated", sni.Host)
+		}
+		hosts.Insert(sni.Host)
+
+		_, _, err := k8s.ParseNameNS(sni.Service)
+		if err != nil {
+			return fmt.Errorf("invalid service for sni host %v: %v", sni.Host, err)
+		}
+
+		if sni.Port.String() == "" || sni.Port.String() == "0" {
+			return fmt.Errorf("service %v of sni host %v does not contain a port", sni.Service, sni.Host)
+		}
+	}
+
+	sort.SliceStable(s.SNI, func(i, j int) bool {
+		return s.SNI[i].Host < s.SNI[j].Host
+	})
+
+	return nil
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+)
+
+func TestParseStreamService(t *testing.T) {
+	testCases := []struct {
+		title    string
+		value    string
+		proto    apiv1.Protocol
+		expected *streamService
+		err      bool
+	}{
+		{"compact format", "default/echo:8080", apiv1.ProtocolTCP,
+			&streamService{Service: "default/echo", Port: intstr.Parse("8080")}, false},
+		{"compact format with named port", "default/echo:http", apiv1.ProtocolTCP,
+			&streamService{Service: "default/echo", Port: intstr.Parse("http")}, false},
+		{"compact format with proxy protocol", "default/echo:8080:PROXY:PROXY", apiv1.ProtocolTCP,
+			&streamService{Service: "default/echo", Port: intstr.Parse("8080"),
+				ProxyProtocol: ingress.ProxyProtocol{Decode: true, Encode: true}}, false},
+		{"compact format ignores proxy protocol in UDP", "default/dns:53:PROXY", apiv1.ProtocolUDP,
+			&streamService{Service: "default/dns", Port: intstr.Parse("53")}, false},
+		{"compact format without port", "default/echo", apiv1.ProtocolTCP, nil, true},
+		{"compact format without namespace", "echo:8080", apiv1.ProtocolTCP, nil, true},
+		{"structured format", `
+service: default/postgres
+port: 5432
+proxyProtocol:
+  decode: true
+proxyTimeout: 10m
+whitelistSourceRange:
+- 192.168.0.0/16
+- 10.0.0.0/8
+limitConnections: 10
+`, apiv1.ProtocolTCP,
+			&streamService{
+				Service:              "default/postgres",
+				Port:                 intstr.FromInt(5432),
+				ProxyProtocol:        ingress.ProxyProtocol{Decode: true},
+				ProxyTimeout:         "10m",
+				WhitelistSourceRange: []string{"10.0.0.0/8", "192.168.0.0/16"},
+				LimitConnections:     10,
+			}, false},
+		{"structured format using JSON", `{"service": "kube-system/kube-dns", "port": "dns", "proxyResponses": 2}`, apiv1.ProtocolUDP,
+			&streamService{
+				Service:        "kube-system/kube-dns",
+				Port:           intstr.FromString("dns"),
+				ProxyResponses: 2,
+			}, false},
+		{"structured format with TLS termination and SNI", `
+service: iot/mqtt
+port: 8883
+tlsSecret: iot/mqtt-tls
+sni:
+- host: v5.mqtt.example.com
+  service: iot/mqtt-v5
+  port: 8883
+- host: "*.legacy.example.com"
+  service: iot/mqtt-legacy
+  port: mqtt
+`, apiv1.ProtocolTCP,
+			&streamService{
+				Service:   "iot/mqtt",
+				Port:      intstr.FromInt(8883),
+				TLSSecret: "iot/mqtt-tls",
+				SNI: []streamSNIService{
+					{Host: "*.legacy.example.com", Service: "iot/mqtt-legacy", Port: intstr.FromString("mqtt")},
+					{Host: "v5.mqtt.example.com", Service: "iot/mqtt-v5", Port: intstr.FromInt(8883)},
+				},
+			}, false},
+		{"TLS termination in UDP", "service: default/dns\nport: 53\ntlsSecret: default/dns-tls", apiv1.ProtocolUDP, nil, true},
+		{"invalid TLS secret", "service: default/echo\nport: 80\ntlsSecret: echo-tls", apiv1.ProtocolTCP, nil, true},
+		{"SNI without TLS termination", "service: default/echo\nport: 80\nsni: [{host: a.example.com, service: default/a, port: 80}]", apiv1.ProtocolTCP, nil, true},
+		{"invalid SNI host", "service: default/echo\nport: 80\ntlsSecret: default/tls\nsni: [{host: a_b, service: default/a, port: 80}]", apiv1.ProtocolTCP, nil, true},
+		{"duplicated SNI host", "service: default/echo\nport: 80\ntlsSecret: default/tls\nsni: [{host: a.example.com, service: default/a, port: 80}, {host: a.example.com, service: default/b, port: 80}]", apiv1.ProtocolTCP, nil, true},
+		{"unknown field", "service: default/echo\nport: 80\nfoo: bar", apiv1.ProtocolTCP, nil, true},
+		{"missing port", "service: default/echo", apiv1.ProtocolTCP, nil, true},
+		{"invalid timeout", "service: default/echo\nport: 80\nproxyTimeout: 10 minutes", apiv1.ProtocolTCP, nil, true},
+		{"invalid source range", "service: default/echo\nport: 80\nwhitelistSourceRange: [10.0.0.0/33]", apiv1.ProtocolTCP, nil, true},
+		{"negative connection limit", "service: default/echo\nport: 80\nlimitConnections: -1", apiv1.ProtocolTCP, nil, true},
+		{"proxy responses in TCP", "service: default/echo\nport: 80\nproxyResponses: 1", apiv1.ProtocolTCP, nil, true},
+		{"proxy protocol in UDP", "service: default/dns\nport: 53\nproxyProtocol: {decode: true}", apiv1.ProtocolUDP, nil, true},
+	}
+
+	for _, tc := range testCases {
+		svc, err := parseStreamService(tc.value, tc.proto)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%v: expected an error but none returned", tc.title)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tc.title, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(svc, tc.expected) {
+			t.Errorf("%v: expected %+v but returned %+v", tc.title, tc.expected, svc)
+		}
+	}
+}
@@ -52,6 +52,13 @@ func (p *TCPProxy) Get(host string) *TCPServer {
 		}
 	}
 
+	// exact hostnames take precedence over wildcards
+	for _, s := range p.ServerList {
+		if isWildcardHost(s.Hostname) && matchHostnames(s.Hostname, host) {
+			return s
+		}
+	}
+
 	return p.Default
 }
 
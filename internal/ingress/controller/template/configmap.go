@@ -19,6 +19,7 @@ package template
 import (
 	"fmt"
 	"net"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -40,6 +41,10 @@ const (
 	httpRedirectCode     = "http-redirect-code"
 	proxyStreamResponses = "proxy-stream-responses"
 	hideHeaders          = "hide-headers"
+	globalAuthHeaders    = "global-auth-response-headers"
+	denySourceRange      = "deny-source-range"
+	denyUserAgents       = "deny-user-agents"
+	denyReferers         = "deny-referers"
 )
 
 var (
@@ -59,6 +64,10 @@ func ReadConfig(src map[string]string) config.Configuration {
 	whiteList := make([]string, 0)
 	proxyList := make([]string, 0)
 	hideHeadersList := make([]string, 0)
+	var globalAuthHeadersList []string
+	denySourceRangeList := make([]string, 0)
+	denyUserAgentsList := make([]string, 0)
+	denyReferersList := make([]string, 0)
 
 	bindAddressIpv4List := make([]string, 0)
 	bindAddressIpv6List := make([]string, 0)
@@ -79,6 +88,15 @@ func ReadConfig(src map[string]string) config.Configuration {
 		delete(conf, hideHeaders)
 		hideHeadersList = strings.Split(val, ",")
 	}
+	if val, ok := conf[globalAuthHeaders]; ok {
+		delete(conf, globalAuthHeaders)
+		for _, h := range strings.Split(val, ",") {
+			h = strings.TrimSpace(h)
+			if h != "" {
+				globalAuthHeadersList = append(globalAuthHeadersList, h)
+			}
+		}
+	}
 	if val, ok := conf[skipAccessLogUrls]; ok {
 		delete(conf, skipAccessLogUrls)
 		skipUrls = strings.Split(val, ",")
@@ -87,6 +105,29 @@ func ReadConfig(src map[string]string) config.Configuration {
 		delete(conf, whitelistSourceRange)
 		whiteList = append(whiteList, strings.Split(val, ",")...)
 	}
+	if val, ok := conf[denySourceRange]; ok {
+		delete(conf, denySourceRange)
+		for _, cidr := range strings.Split(val, ",") {
+			cidr = strings.TrimSpace(cidr)
+			if cidr == "" {
+				continue
+			}
+			_, _, err := ing_net.ParseIPNets(cidr)
+			if err != nil {
+				glog.Warningf("%v is not a valid IP address or network in %v: %v", cidr, denySourceRange, err)
+				continue
+			}
+			denySourceRangeList = append(denySourceRangeList, cidr)
+		}
+	}
+	if val, ok := conf[denyUserAgents]; ok {
+		delete(conf, denyUserAgents)
+		denyUserAgentsList = parseDenyRegexps(denyUserAgents, val)
+	}
+	if val, ok := conf[denyReferers]; ok {
+		delete(conf, denyReferers)
+		denyReferersList = parseDenyRegexps(denyReferers, val)
+	}
 	if val, ok := conf[proxyRealIPCIDR]; ok {
 		delete(conf, proxyRealIPCIDR)
 		proxyList = append(proxyList, strings.Split(val, ",")...)
@@ -142,6 +183,10 @@ func ReadConfig(src map[string]string) config.Configuration {
 	to.BindAddressIpv4 = bindAddressIpv4List
 	to.BindAddressIpv6 = bindAddressIpv6List
 	to.HideHeaders = hideHeadersList
+	to.GlobalExternalAuthResponseHeaders = globalAuthHeadersList
+	to.DenySourceRange = denySourceRangeList
+	to.DenyUserAgents = denyUserAgentsList
+	to.DenyReferers = denyReferersList
 	to.HTTPRedirectCode = redirectCode
 	to.ProxyStreamResponses = streamResponses
 	to.DisableIpv6DNS = !ing_net.IsIPv6Enabled()
@@ -165,6 +210,26 @@ func ReadConfig(src map[string]string) config.Configuration {
 	return to
 }
 
+// parseDenyRegexps returns the valid regular expressions of a comma
+// separated list of a deny list. Invalid expressions are ignored
+func parseDenyRegexps(name, val string) []string {
+	exprs := make([]string, 0)
+	for _, expr := range strings.Split(val, ",") {
+		expr = strings.TrimSpace(expr)
+		if expr == "" {
+			continue
+		}
+		_, err := regexp.Compile(expr)
+		if err != nil {
+			glog.Warningf("%v is not a valid regular expression in %v: %v", expr, name, err)
+			continue
+		}
+		exprs = append(exprs, expr)
+	}
+
+	return exprs
+}
+
 func filterErrors(codes []int) []int {
 	var fa []int
 	for _, code := range codes {
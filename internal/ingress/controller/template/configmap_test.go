@@ -84,6 +84,36 @@ func TestMergeConfigMapToStruct(t *testing.T) {
 	if diff := pretty.Compare(to, def); diff != "" {
 		t.Errorf("unexpected diff: (-got +want)\n%s", diff)
 	}
+
+	def = config.NewDefault()
+	def.GlobalExternalAuthURL = "http://auth.svc/check"
+	def.GlobalExternalAuthResponseHeaders = []string{"X-User", "X-Email"}
+	to = ReadConfig(map[string]string{
+		"global-auth-url":              "http://auth.svc/check",
+		"global-auth-response-headers": "X-User, X-Email,",
+	})
+
+	if diff := pretty.Compare(to, def); diff != "" {
+		t.Errorf("unexpected diff: (-got +want)\n%s", diff)
+	}
+}
+
+func TestDenyLists(t *testing.T) {
+	to := ReadConfig(map[string]string{
+		"deny-source-range": "10.0.0.0/8, 192.168.1.1,invalid",
+		"deny-user-agents":  "BadBot, ^curl/7\\.[0-9]+$, (invalid",
+		"deny-referers":     "spam\\.example\\.com,",
+	})
+
+	if diff := pretty.Compare(to.DenySourceRange, []string{"10.0.0.0/8", "192.168.1.1"}); diff != "" {
+		t.Errorf("unexpected deny source range: (-got +want)\n%s", diff)
+	}
+	if diff := pretty.Compare(to.DenyUserAgents, []string{"BadBot", `^curl/7\.[0-9]+$`}); diff != "" {
+		t.Errorf("unexpected deny user agents: (-got +want)\n%s", diff)
+	}
+	if diff := pretty.Compare(to.DenyReferers, []string{`spam\.example\.com`}); diff != "" {
+		t.Errorf("unexpected deny referers: (-got +want)\n%s", diff)
+	}
 }
 
 func TestDefaultLoadBalance(t *testing.T) {
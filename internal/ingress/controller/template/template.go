@@ -39,6 +39,7 @@ import (
 	"k8s.io/ingress-nginx/internal/file"
 	"k8s.io/ingress-nginx/internal/ingress"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/ratelimit"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/redirectmap"
 	"k8s.io/ingress-nginx/internal/ingress/controller/config"
 	ing_net "k8s.io/ingress-nginx/internal/net"
 )
@@ -122,17 +123,38 @@ var (
 		"buildLocation":            buildLocation,
 		"buildAuthLocation":        buildAuthLocation,
 		"buildAuthResponseHeaders": buildAuthResponseHeaders,
+		"buildAuthCacheZones":      buildAuthCacheZones,
+		"buildJWTCheck":            buildJWTCheck,
 		"buildLoadBalancingConfig": buildLoadBalancingConfig,
 		"buildProxyPass":           buildProxyPass,
+		"buildProxySSL":            buildProxySSL,
 		"filterRateLimits":         filterRateLimits,
 		"buildRateLimitZones":      buildRateLimitZones,
 		"buildRateLimit":           buildRateLimit,
+		"buildRateLimitDryRun":     buildRateLimitDryRun,
+		"buildRateLimitReject":     buildRateLimitReject,
+		"filterServerRateLimits":   filterServerRateLimits,
+		"buildRateLimitMirror":     buildRateLimitMirror,
 		"buildResolvers":           buildResolvers,
 		"buildUpstreamName":        buildUpstreamName,
 		"isLocationInLocationList": isLocationInLocationList,
 		"isLocationAllowed":        isLocationAllowed,
 		"buildLogFormatUpstream":   buildLogFormatUpstream,
 		"buildDenyVariable":        buildDenyVariable,
+		"buildDenyMap":             buildDenyMap,
+		"buildDenyRuleNames":       buildDenyRuleNames,
+		"buildDenyCheck":           buildDenyCheck,
+		"buildDenylistRule":        buildDenylistRule,
+		"buildGeoIPMap":            buildGeoIPMap,
+		"buildGeoIPAccess":         buildGeoIPAccess,
+		"filterCorsOrigins":        filterCorsOrigins,
+		"buildCorsOriginMap":       buildCorsOriginMap,
+		"buildCorsOrigin":          buildCorsOrigin,
+		"filterRedirectMaps":       filterRedirectMaps,
+		"buildRedirectMap":         buildRedirectMap,
+		"mergeHeaders":             mergeHeaders,
+		"filterHeaders":            filterHeaders,
+		"buildHideHeaders":         buildHideHeaders,
 		"getenv":                   os.Getenv,
 		"contains":                 strings.Contains,
 		"hasPrefix":                strings.HasPrefix,
@@ -231,6 +253,13 @@ func buildLocation(input interface{}) string {
 	return path
 }
 
+const (
+	// authCacheZone is the name of the cache zone used to store the
+	// responses of the external authentication services
+	authCacheZone = "auth_cache"
+	authCachePath = "/tmp/nginx-cache-auth"
+)
+
 func buildAuthLocation(input interface{}) string {
 	location, ok := input.(*ingress.Location)
 	if !ok {
@@ -269,6 +298,53 @@ func buildAuthResponseHeaders(input interface{}) []string {
 	return res
 }
 
+// buildJWTCheck returns the Lua code that validates the JWT bearer tokens
+// of a location. The configuration is a base64 encoded JSON object and the
+// public keys are sent to nginx using the configuration endpoint
+func buildJWTCheck(input interface{}) string {
+	location, ok := input.(*ingress.Location)
+	if !ok {
+		glog.Errorf("expected an '*ingress.Location' type but %T was returned", input)
+		return ""
+	}
+
+	if location.JWTAuth.Keys == "" {
+		return ""
+	}
+
+	b, err := json.Marshal(location.JWTAuth)
+	if err != nil {
+		glog.Errorf("unexpected error encoding JWT configuration: %v", err)
+		return ""
+	}
+
+	return fmt.Sprintf(`require("jwt").validate("%v")`, base64.StdEncoding.EncodeToString(b))
+}
+
+// buildAuthCacheZones returns the cache zone shared by the locations that
+// cache the responses of the external authentication service
+func buildAuthCacheZones(input interface{}) []string {
+	zones := []string{}
+
+	servers, ok := input.([]*ingress.Server)
+	if !ok {
+		glog.Errorf("expected a '[]*ingress.Server' type but %T was returned", input)
+		return zones
+	}
+
+	for _, server := range servers {
+		for _, loc := range server.Locations {
+			if loc.ExternalAuth.URL != "" && loc.ExternalAuth.AuthCacheKey != "" {
+				zone := fmt.Sprintf("proxy_cache_path %v levels=1:2 keys_zone=%v:10m max_size=128m inactive=30m use_temp_path=off;",
+					authCachePath, authCacheZone)
+				return append(zones, zone)
+			}
+		}
+	}
+
+	return zones
+}
+
 func buildLogFormatUpstream(input interface{}) string {
 	cfg, ok := input.(config.Configuration)
 	if !ok {
@@ -405,6 +481,65 @@ func buildProxyPass(host string, b interface{}, loc interface{}, dynamicConfigur
 	return defProxyPass
 }
 
+// buildProxySSL returns the directives used to verify the certificate of an
+// HTTPS backend and to present a client certificate to it
+func buildProxySSL(b interface{}, loc interface{}) []string {
+	directives := []string{}
+
+	backends, ok := b.([]*ingress.Backend)
+	if !ok {
+		glog.Errorf("expected an '[]*ingress.Backend' type but %T was returned", b)
+		return directives
+	}
+
+	location, ok := loc.(*ingress.Location)
+	if !ok {
+		glog.Errorf("expected a '*ingress.Location' type but %T was returned", loc)
+		return directives
+	}
+
+	for _, backend := range backends {
+		if backend.Name != location.Backend {
+			continue
+		}
+
+		if !backend.Secure {
+			break
+		}
+
+		if backend.SecureCACert.CAFileName != "" {
+			directives = append(directives,
+				fmt.Sprintf("proxy_ssl_trusted_certificate %v;", backend.SecureCACert.CAFileName),
+				"proxy_ssl_verify on;")
+			if backend.SecureVerifyDepth > 0 {
+				directives = append(directives,
+					fmt.Sprintf("proxy_ssl_verify_depth %v;", backend.SecureVerifyDepth))
+			}
+		}
+
+		if backend.SecureClientCert.PemFileName != "" {
+			directives = append(directives,
+				fmt.Sprintf("proxy_ssl_certificate %v;", backend.SecureClientCert.PemFileName),
+				fmt.Sprintf("proxy_ssl_certificate_key %v;", backend.SecureClientCert.PemFileName))
+		}
+
+		if backend.SecureServerName != "" {
+			directives = append(directives,
+				fmt.Sprintf("proxy_ssl_name %v;", backend.SecureServerName),
+				"proxy_ssl_server_name on;")
+		}
+
+		if backend.SecureProtocols != "" {
+			directives = append(directives,
+				fmt.Sprintf("proxy_ssl_protocols %v;", backend.SecureProtocols))
+		}
+
+		break
+	}
+
+	return directives
+}
+
 // TODO: Needs Unit Tests
 func filterRateLimits(input interface{}) []ratelimit.Config {
 	ratelimits := []ratelimit.Config{}
@@ -481,6 +616,11 @@ func buildRateLimitZones(input interface{}) []string {
 
 // buildRateLimit produces an array of limit_req to be used inside the Path of
 // Ingress rules. The order: connections by IP first, then RPS, and RPM last.
+// Limits keyed by a JWT claim extract the claim of the bearer token first,
+// before the token is validated in the access phase.
+// Rejected requests are sent to a named location that returns the response
+// configured in the Ingress. In dry-run mode the limits are applied to a
+// mirror of the request that never blocks the original one.
 func buildRateLimit(input interface{}) []string {
 	limits := []string{}
 
@@ -490,39 +630,133 @@ func buildRateLimit(input interface{}) []string {
 		return limits
 	}
 
-	if loc.RateLimit.Connections.Limit > 0 {
-		limit := fmt.Sprintf("limit_conn %v %v;",
-			loc.RateLimit.Connections.Name, loc.RateLimit.Connections.Limit)
+	if loc.RateLimit.ID != "" {
+		if loc.RateLimit.DryRun {
+			limits = append(limits,
+				fmt.Sprintf("mirror %v;", buildRateLimitMirror(loc.RateLimit)),
+				"mirror_request_body off;")
+		} else {
+			limits = append(limits, rateLimitDirectives(loc.RateLimit)...)
+			limits = append(limits, fmt.Sprintf("error_page %v = @ratelimit_%v;",
+				rateLimitRejectStatus, loc.RateLimit.ID))
+		}
+	}
+
+	if loc.RateLimit.LimitRateAfter > 0 {
+		limit := fmt.Sprintf("limit_rate_after %vk;",
+			loc.RateLimit.LimitRateAfter)
 		limits = append(limits, limit)
 	}
 
-	if loc.RateLimit.RPS.Limit > 0 {
-		limit := fmt.Sprintf("limit_req zone=%v burst=%v nodelay;",
-			loc.RateLimit.RPS.Name, loc.RateLimit.RPS.Burst)
+	if loc.RateLimit.LimitRate > 0 {
+		limit := fmt.Sprintf("limit_rate %vk;",
+			loc.RateLimit.LimitRate)
 		limits = append(limits, limit)
 	}
 
-	if loc.RateLimit.RPM.Limit > 0 {
-		limit := fmt.Sprintf("limit_req zone=%v burst=%v nodelay;",
-			loc.RateLimit.RPM.Name, loc.RateLimit.RPM.Burst)
+	return limits
+}
+
+// rateLimitRejectStatus is the status code of the requests over the limits
+// inside nginx. The named location of the rejected requests replaces it
+// with the status code of the rate limit, so the error_page of the rate
+// limits does not handle the responses of the backends
+const rateLimitRejectStatus = 599
+
+// rateLimitDirectives returns the limit_conn and limit_req directives of a
+// rate limit and the status code of the rejected requests
+func rateLimitDirectives(rl ratelimit.Config) []string {
+	limits := []string{}
+
+	if rl.JWTClaim != "" {
+		limit := fmt.Sprintf(`set_by_lua_block %v { return require("jwt").claim(ngx.var.http_authorization, "%v") }`,
+			rl.Key, rl.JWTClaim)
 		limits = append(limits, limit)
 	}
 
-	if loc.RateLimit.LimitRateAfter > 0 {
-		limit := fmt.Sprintf("limit_rate_after %vk;",
-			loc.RateLimit.LimitRateAfter)
+	if rl.Connections.Limit > 0 {
+		limit := fmt.Sprintf("limit_conn %v %v;",
+			rl.Connections.Name, rl.Connections.Limit)
 		limits = append(limits, limit)
 	}
 
-	if loc.RateLimit.LimitRate > 0 {
-		limit := fmt.Sprintf("limit_rate %vk;",
-			loc.RateLimit.LimitRate)
+	if rl.RPS.Limit > 0 {
+		limit := fmt.Sprintf("limit_req zone=%v burst=%v nodelay;",
+			rl.RPS.Name, rl.RPS.Burst)
+		limits = append(limits, limit)
+	}
+
+	if rl.RPM.Limit > 0 {
+		limit := fmt.Sprintf("limit_req zone=%v burst=%v nodelay;",
+			rl.RPM.Name, rl.RPM.Burst)
 		limits = append(limits, limit)
 	}
 
+	limits = append(limits,
+		fmt.Sprintf("limit_conn_status %v;", rateLimitRejectStatus),
+		fmt.Sprintf("limit_req_status %v;", rateLimitRejectStatus))
+
 	return limits
 }
 
+// filterServerRateLimits returns the rate limits of the locations of a
+// server. Each rate limit requires a named location to reject requests
+func filterServerRateLimits(input interface{}) []ratelimit.Config {
+	ratelimits := []ratelimit.Config{}
+	found := sets.String{}
+
+	server, ok := input.(*ingress.Server)
+	if !ok {
+		glog.Errorf("expected an '*ingress.Server' type but %T was returned", input)
+		return ratelimits
+	}
+	for _, loc := range server.Locations {
+		if loc.RateLimit.ID != "" && !found.Has(loc.RateLimit.ID) {
+			found.Insert(loc.RateLimit.ID)
+			ratelimits = append(ratelimits, loc.RateLimit)
+		}
+	}
+	return ratelimits
+}
+
+// buildRateLimitMirror returns the path of the internal location
+// that applies the limits to the mirror of the requests in dry-run mode
+func buildRateLimitMirror(input interface{}) string {
+	rl, ok := input.(ratelimit.Config)
+	if !ok {
+		glog.Errorf("expected a 'ratelimit.Config' type but %T was returned", input)
+		return ""
+	}
+
+	return fmt.Sprintf("/_ratelimit-dry-run-%v", rl.ID)
+}
+
+// buildRateLimitDryRun produces the directives of the internal location that
+// applies the limits to the mirror of the requests in dry-run mode
+func buildRateLimitDryRun(input interface{}) []string {
+	rl, ok := input.(ratelimit.Config)
+	if !ok {
+		glog.Errorf("expected a 'ratelimit.Config' type but %T was returned", input)
+		return []string{}
+	}
+
+	limits := rateLimitDirectives(rl)
+	return append(limits, fmt.Sprintf("error_page %v = @ratelimit_dry_run_%v;", rateLimitRejectStatus, rl.ID))
+}
+
+// buildRateLimitReject returns the Lua call that counts a rejected request
+// and returns the status code and body configured in the rate limit
+func buildRateLimitReject(input interface{}) string {
+	rl, ok := input.(ratelimit.Config)
+	if !ok {
+		glog.Errorf("expected a 'ratelimit.Config' type but %T was returned", input)
+		return ""
+	}
+
+	return fmt.Sprintf(`require("ratelimit").reject("%v", %v, "%v")`,
+		rl.Name, rl.StatusCode, base64.StdEncoding.EncodeToString([]byte(rl.ResponseBody)))
+}
+
 func isLocationInLocationList(location interface{}, rawLocationList string) bool {
 	loc, ok := location.(*ingress.Location)
 	if !ok {
@@ -578,6 +812,415 @@ func buildDenyVariable(a interface{}) string {
 	return fmt.Sprintf("$deny_%v", denyPathSlugMap[l])
 }
 
+// buildDenyMap produces the entries of the map of a global deny list of
+// regular expressions. The value of each entry is the index of the rule
+func buildDenyMap(input interface{}, kind string) []string {
+	entries := []string{}
+
+	exprs, ok := input.([]string)
+	if !ok {
+		glog.Errorf("expected a '[]string' type but %T was returned", input)
+		return entries
+	}
+
+	for i, expr := range exprs {
+		expr = strings.Replace(expr, `\`, `\\`, -1)
+		expr = strings.Replace(expr, `"`, `\"`, -1)
+		entries = append(entries, fmt.Sprintf(`"~*%v" "%v:%v";`, expr, kind, i))
+	}
+
+	return entries
+}
+
+// buildDenyRuleNames returns the names of the rules of the global deny
+// lists of regular expressions indexed by the value of the map entries,
+// as a base64 encoded JSON object
+func buildDenyRuleNames(input interface{}) string {
+	cfg, ok := input.(config.Configuration)
+	if !ok {
+		glog.Errorf("expected a 'config.Configuration' type but %T was returned", input)
+		return ""
+	}
+
+	names := map[string]string{}
+	for i, expr := range cfg.DenyUserAgents {
+		names[fmt.Sprintf("user-agent:%v", i)] = fmt.Sprintf("user-agent:%v", expr)
+	}
+	for i, expr := range cfg.DenyReferers {
+		names[fmt.Sprintf("referer:%v", i)] = fmt.Sprintf("referer:%v", expr)
+	}
+
+	b, err := json.Marshal(names)
+	if err != nil {
+		glog.Errorf("unexpected error encoding deny rules: %v", err)
+		return ""
+	}
+
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// buildDenylistRule returns the name of the rule of the denylist of a
+// location used in the metrics of denied requests
+func buildDenylistRule(input interface{}) string {
+	loc, ok := input.(*ingress.Location)
+	if !ok {
+		glog.Errorf("expected an '*ingress.Location' type but %T was returned", input)
+		return ""
+	}
+
+	if loc.Ingress == nil {
+		return "ingress"
+	}
+
+	return fmt.Sprintf("ingress:%v/%v", loc.Ingress.Namespace, loc.Ingress.Name)
+}
+
+// buildDenyCheck returns the Lua call that denies the requests matching
+// the global deny lists or the denylist of the location, or an empty
+// string when there are no deny lists
+func buildDenyCheck(c interface{}, l interface{}, name string) string {
+	cfg, ok := c.(config.Configuration)
+	if !ok {
+		glog.Errorf("expected a 'config.Configuration' type but %T was returned", c)
+		return ""
+	}
+	loc, ok := l.(*ingress.Location)
+	if !ok {
+		glog.Errorf("expected an '*ingress.Location' type but %T was returned", l)
+		return ""
+	}
+
+	vars := []string{}
+	if len(cfg.DenySourceRange) > 0 {
+		vars = append(vars, "ngx.var.deny_source_rule")
+	}
+	if len(cfg.DenyUserAgents) > 0 {
+		vars = append(vars, "ngx.var.deny_user_agent_rule")
+	}
+	if len(cfg.DenyReferers) > 0 {
+		vars = append(vars, "ngx.var.deny_referer_rule")
+	}
+	if len(loc.Denylist.CIDR) > 0 {
+		v := buildDenyVariable(fmt.Sprintf("denylist_%v", name))
+		vars = append(vars, fmt.Sprintf("ngx.var.%v", strings.TrimPrefix(v, "$")))
+	}
+
+	if len(vars) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(`require("deny").check(%v)`, strings.Join(vars, ", "))
+}
+
+// buildGeoIPMap returns the entries of the map of the country codes of
+// the clients to the access to a location (0 allowed and 1 denied). When
+// the location contains allowed countries the rest of the countries are
+// denied. Denied countries take precedence over allowed ones
+func buildGeoIPMap(input interface{}) []string {
+	entries := []string{}
+
+	loc, ok := input.(*ingress.Location)
+	if !ok {
+		glog.Errorf("expected an '*ingress.Location' type but %T was returned", input)
+		return entries
+	}
+
+	denied := map[string]bool{}
+	for _, c := range loc.GeoIP.DenyCountries {
+		denied[c] = true
+	}
+
+	if len(loc.GeoIP.AllowCountries) > 0 {
+		entries = append(entries, "default 1;")
+		for _, c := range loc.GeoIP.AllowCountries {
+			if !denied[c] {
+				entries = append(entries, fmt.Sprintf("%v 0;", c))
+			}
+		}
+		return entries
+	}
+
+	entries = append(entries, "default 0;")
+	for _, c := range loc.GeoIP.DenyCountries {
+		entries = append(entries, fmt.Sprintf("%v 1;", c))
+	}
+
+	return entries
+}
+
+// buildGeoIPAccess returns the directive used to respond to the requests
+// from denied countries, a redirect if the location defines an URL or a
+// forbidden status code
+func buildGeoIPAccess(input interface{}) string {
+	loc, ok := input.(*ingress.Location)
+	if !ok {
+		glog.Errorf("expected an '*ingress.Location' type but %T was returned", input)
+		return ""
+	}
+
+	if loc.GeoIP.RedirectURL != "" {
+		return fmt.Sprintf("return 302 %v;", loc.GeoIP.RedirectURL)
+	}
+
+	return "return 403;"
+}
+
+// isCorsOriginMapped checks if the allowed origins of a location require a
+// map to echo back the origin of the request, i.e. a list of origins or
+// regular expressions
+func isCorsOriginMapped(origins []string) bool {
+	if len(origins) > 1 {
+		return true
+	}
+
+	return len(origins) == 1 && strings.HasPrefix(origins[0], "~")
+}
+
+// filterCorsOrigins returns the distinct lists of allowed origins of the
+// locations with CORS enabled that require a map of origins
+func filterCorsOrigins(input interface{}) [][]string {
+	lists := [][]string{}
+	found := sets.String{}
+
+	servers, ok := input.([]*ingress.Server)
+	if !ok {
+		glog.Errorf("expected a '[]*ingress.Server' type but %T was returned", input)
+		return lists
+	}
+
+	for _, server := range servers {
+		for _, loc := range server.Locations {
+			origins := loc.CorsConfig.CorsAllowOrigin
+			if !loc.CorsConfig.CorsEnabled || !isCorsOriginMapped(origins) {
+				continue
+			}
+
+			key := strings.Join(origins, ",")
+			if !found.Has(key) {
+				found.Insert(key)
+				lists = append(lists, origins)
+			}
+		}
+	}
+
+	return lists
+}
+
+// buildCorsOriginMap returns the entries of the map of the origins of the
+// requests to the value of the header Access-Control-Allow-Origin. Only
+// the allowed origins are echoed back
+func buildCorsOriginMap(input interface{}) []string {
+	entries := []string{}
+
+	origins, ok := input.([]string)
+	if !ok {
+		glog.Errorf("expected a '[]string' type but %T was returned", input)
+		return entries
+	}
+
+	for _, origin := range origins {
+		origin = strings.Replace(origin, `\`, `\\`, -1)
+		entries = append(entries, fmt.Sprintf(`"%v" $http_origin;`, origin))
+	}
+
+	return entries
+}
+
+// buildCorsOrigin returns the value of the header Access-Control-Allow-Origin
+// of a list of allowed origins: '*', a single origin or the variable of the
+// map of origins
+func buildCorsOrigin(input interface{}) string {
+	origins, ok := input.([]string)
+	if !ok {
+		glog.Errorf("expected a '[]string' type but %T was returned", input)
+		return ""
+	}
+
+	if len(origins) == 0 {
+		return "*"
+	}
+
+	if !isCorsOriginMapped(origins) {
+		return origins[0]
+	}
+
+	return buildDenyVariable(fmt.Sprintf("cors_%v", strings.Join(origins, ",")))
+}
+
+// filterRedirectMaps returns the distinct redirect maps with rules used by
+// the locations and the global redirect map
+func filterRedirectMaps(s interface{}, g interface{}) []redirectmap.Config {
+	maps := []redirectmap.Config{}
+	found := sets.String{}
+
+	servers, ok := s.([]*ingress.Server)
+	if !ok {
+		glog.Errorf("expected a '[]*ingress.Server' type but %T was returned", s)
+		return maps
+	}
+	global, ok := g.(redirectmap.Config)
+	if !ok {
+		glog.Errorf("expected a 'redirectmap.Config' type but %T was returned", g)
+		return maps
+	}
+
+	candidates := []redirectmap.Config{global}
+	for _, server := range servers {
+		for _, loc := range server.Locations {
+			candidates = append(candidates, loc.RedirectMap)
+		}
+	}
+
+	for _, rm := range candidates {
+		if len(rm.Rules) > 0 && !found.Has(rm.Name) {
+			found.Insert(rm.Name)
+			maps = append(maps, rm)
+		}
+	}
+
+	return maps
+}
+
+// redirectMapBlock is a map of the sources of the rules of a redirect map
+// with a status code to the targets of the redirects
+type redirectMapBlock struct {
+	Variable string
+	Code     int
+	Entries  []string
+}
+
+// buildRedirectMap returns a map block for each status code used in the
+// rules of a redirect map. The value of the variable of the block is the
+// target of the redirect, with the path and query string of the request
+// when the rule preserves them. The path is the original (not decoded)
+// path of the request to avoid the injection of headers
+func buildRedirectMap(input interface{}) []redirectMapBlock {
+	blocks := []redirectMapBlock{}
+
+	rm, ok := input.(redirectmap.Config)
+	if !ok {
+		glog.Errorf("expected a 'redirectmap.Config' type but %T was returned", input)
+		return blocks
+	}
+
+	index := map[int]int{}
+	for _, rule := range rm.Rules {
+		i, ok := index[rule.Code]
+		if !ok {
+			i = len(blocks)
+			index[rule.Code] = i
+			blocks = append(blocks, redirectMapBlock{
+				Variable: buildDenyVariable(fmt.Sprintf("redirect_%v_%v", rm.Name, rule.Code)),
+				Code:     rule.Code,
+				Entries:  []string{},
+			})
+		}
+
+		target := rule.Target
+		if rule.PreservePath {
+			target = strings.TrimSuffix(target, "/") + "$redirect_request_path"
+		}
+		if rule.PreserveQuery {
+			target += "$is_args$args"
+		}
+
+		source := strings.Replace(rule.Source, `\`, `\\`, -1)
+		blocks[i].Entries = append(blocks[i].Entries, fmt.Sprintf(`"%v" "%v";`, source, target))
+	}
+
+	return blocks
+}
+
+// mergeHeaders returns the headers of the configuration replaced or
+// removed by the headers of a location with the same name
+func mergeHeaders(g interface{}, l interface{}) map[string]string {
+	headers := map[string]string{}
+
+	global, ok := g.(map[string]string)
+	if !ok {
+		glog.Errorf("expected a 'map[string]string' type but %T was returned", g)
+		return headers
+	}
+	location, ok := l.(map[string]string)
+	if !ok {
+		glog.Errorf("expected a 'map[string]string' type but %T was returned", l)
+		return headers
+	}
+
+	for k, v := range filterHeaders(global, location) {
+		headers[k] = v
+	}
+	for k, v := range location {
+		headers[k] = v
+	}
+
+	return headers
+}
+
+// filterHeaders returns the headers of the configuration not defined in a
+// location. The names of the headers are case insensitive
+func filterHeaders(g interface{}, l interface{}) map[string]string {
+	headers := map[string]string{}
+
+	global, ok := g.(map[string]string)
+	if !ok {
+		glog.Errorf("expected a 'map[string]string' type but %T was returned", g)
+		return headers
+	}
+	location, ok := l.(map[string]string)
+	if !ok {
+		glog.Errorf("expected a 'map[string]string' type but %T was returned", l)
+		return headers
+	}
+
+	names := sets.String{}
+	for k := range location {
+		names.Insert(strings.ToLower(k))
+	}
+
+	for k, v := range global {
+		if !names.Has(strings.ToLower(k)) {
+			headers[k] = v
+		}
+	}
+
+	return headers
+}
+
+// buildHideHeaders returns the headers hidden in a location. The headers
+// of the configuration are included because proxy_hide_header directives
+// are not inherited when the location defines its own
+func buildHideHeaders(g interface{}, l interface{}) []string {
+	headers := []string{}
+
+	global, ok := g.([]string)
+	if !ok {
+		glog.Errorf("expected a '[]string' type but %T was returned", g)
+		return headers
+	}
+	location, ok := l.([]string)
+	if !ok {
+		glog.Errorf("expected a '[]string' type but %T was returned", l)
+		return headers
+	}
+
+	if len(location) == 0 {
+		return headers
+	}
+
+	names := sets.String{}
+	for _, list := range [][]string{global, location} {
+		for _, header := range list {
+			if !names.Has(strings.ToLower(header)) {
+				names.Insert(strings.ToLower(header))
+				headers = append(headers, header)
+			}
+		}
+	}
+
+	return headers
+}
+
 // TODO: Needs Unit Tests
 func buildUpstreamName(host string, b interface{}, loc interface{}) string {
 
@@ -32,8 +32,14 @@ import (
 	"k8s.io/ingress-nginx/internal/file"
 	"k8s.io/ingress-nginx/internal/ingress"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/authreq"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/cors"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/geoip"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/jwtauth"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/ratelimit"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/redirectmap"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/rewrite"
 	"k8s.io/ingress-nginx/internal/ingress/controller/config"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
 var (
@@ -366,6 +372,77 @@ func TestBuildProxyPass(t *testing.T) {
 	}
 }
 
+func TestBuildProxySSL(t *testing.T) {
+	loc := &ingress.Location{Backend: "upstream-name"}
+
+	backend := &ingress.Backend{
+		Name:   "upstream-name",
+		Secure: true,
+		SecureCACert: resolver.AuthSSLCert{
+			CAFileName: "/ssl/ca-default-ca.pem",
+		},
+		SecureClientCert: resolver.AuthSSLCert{
+			PemFileName: "/ssl/default-client.pem",
+		},
+		SecureServerName:  "internal.example.com",
+		SecureVerifyDepth: 2,
+		SecureProtocols:   "TLSv1.2 TLSv1.3",
+	}
+
+	expected := []string{
+		"proxy_ssl_trusted_certificate /ssl/ca-default-ca.pem;",
+		"proxy_ssl_verify on;",
+		"proxy_ssl_verify_depth 2;",
+		"proxy_ssl_certificate /ssl/default-client.pem;",
+		"proxy_ssl_certificate_key /ssl/default-client.pem;",
+		"proxy_ssl_name internal.example.com;",
+		"proxy_ssl_server_name on;",
+		"proxy_ssl_protocols TLSv1.2 TLSv1.3;",
+	}
+
+	directives := buildProxySSL([]*ingress.Backend{backend}, loc)
+	if !reflect.DeepEqual(expected, directives) {
+		t.Errorf("expected '%v' but returned '%v'", expected, directives)
+	}
+
+	backend.Secure = false
+	directives = buildProxySSL([]*ingress.Backend{backend}, loc)
+	if len(directives) != 0 {
+		t.Errorf("expected no directives for a non secure backend but returned '%v'", directives)
+	}
+}
+
+func TestBuildAuthCacheZones(t *testing.T) {
+	servers := []*ingress.Server{
+		{
+			Hostname: "foo.bar",
+			Locations: []*ingress.Location{
+				{Path: "/", ExternalAuth: authreq.Config{URL: "http://auth.svc/check"}},
+			},
+		},
+	}
+
+	zones := buildAuthCacheZones(servers)
+	if len(zones) != 0 {
+		t.Errorf("expected no cache zones but returned %v", zones)
+	}
+
+	servers[0].Locations = append(servers[0].Locations, &ingress.Location{
+		Path: "/api",
+		ExternalAuth: authreq.Config{
+			URL:               "http://auth.svc/check",
+			AuthCacheKey:      "$http_authorization",
+			AuthCacheDuration: []string{"200 5m"},
+		},
+	})
+
+	zones = buildAuthCacheZones(servers)
+	expected := []string{"proxy_cache_path /tmp/nginx-cache-auth levels=1:2 keys_zone=auth_cache:10m max_size=128m inactive=30m use_temp_path=off;"}
+	if !reflect.DeepEqual(expected, zones) {
+		t.Errorf("expected '%v' but returned '%v'", expected, zones)
+	}
+}
+
 func TestBuildAuthLocation(t *testing.T) {
 	authURL := "foo.com/auth"
 
@@ -403,6 +480,38 @@ func TestBuildAuthResponseHeaders(t *testing.T) {
 	}
 }
 
+func TestBuildJWTCheck(t *testing.T) {
+	loc := &ingress.Location{
+		JWTAuth: jwtauth.Config{
+			Keys:         "default/jwt",
+			Issuer:       "https://issuer.example.com",
+			Audience:     []string{"api"},
+			ClaimHeaders: []jwtauth.ClaimHeader{{Claim: "sub", Header: "X-User"}},
+			Realm:        "api \"v1\"",
+		},
+	}
+
+	check := buildJWTCheck(loc)
+	prefix := `require("jwt").validate("`
+	if !strings.HasPrefix(check, prefix) || !strings.HasSuffix(check, `")`) {
+		t.Fatalf("unexpected JWT check %v", check)
+	}
+
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSuffix(strings.TrimPrefix(check, prefix), `")`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `{"keys":"default/jwt","issuer":"https://issuer.example.com","audience":["api"],` +
+		`"claimHeaders":[{"claim":"sub","header":"X-User"}],"realm":"api \"v1\""}`
+	if string(b) != expected {
+		t.Errorf("Expected \n'%v'\nbut returned \n'%v'", expected, string(b))
+	}
+
+	if check := buildJWTCheck(&ingress.Location{Path: "/cat"}); check != "" {
+		t.Errorf("Expected no JWT check without keys but returned '%v'", check)
+	}
+}
+
 func TestTemplateWithData(t *testing.T) {
 	pwd, _ := os.Getwd()
 	f, err := os.Open(path.Join(pwd, "../../../../test/data/config.json"))
@@ -477,6 +586,269 @@ func TestBuildDenyVariable(t *testing.T) {
 	}
 }
 
+func TestBuildDenyMap(t *testing.T) {
+	entries := buildDenyMap([]string{"BadBot", `^curl/7\.[0-9]+$`, `say "hi"`}, "user-agent")
+	expected := []string{
+		`"~*BadBot" "user-agent:0";`,
+		`"~*^curl/7\\.[0-9]+$" "user-agent:1";`,
+		`"~*say \"hi\"" "user-agent:2";`,
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("Expected '%v' but returned '%v'", expected, entries)
+	}
+}
+
+func TestBuildDenyRuleNames(t *testing.T) {
+	cfg := config.NewDefault()
+	cfg.DenyUserAgents = []string{"BadBot"}
+	cfg.DenyReferers = []string{`spam\.example\.com`}
+
+	b, err := base64.StdEncoding.DecodeString(buildDenyRuleNames(cfg))
+	if err != nil {
+		t.Fatalf("unexpected error decoding the rule names: %v", err)
+	}
+
+	names := map[string]string{}
+	err = json.Unmarshal(b, &names)
+	if err != nil {
+		t.Fatalf("unexpected error decoding the rule names: %v", err)
+	}
+
+	expected := map[string]string{
+		"user-agent:0": "user-agent:BadBot",
+		"referer:0":    `referer:spam\.example\.com`,
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected '%v' but returned '%v'", expected, names)
+	}
+}
+
+func TestBuildDenyCheck(t *testing.T) {
+	cfg := config.NewDefault()
+	loc := &ingress.Location{}
+
+	if check := buildDenyCheck(cfg, loc, "example.com_/"); check != "" {
+		t.Errorf("Expected no check without deny lists but returned '%v'", check)
+	}
+
+	cfg.DenySourceRange = []string{"10.0.0.0/8"}
+	cfg.DenyReferers = []string{"spam"}
+	expected := `require("deny").check(ngx.var.deny_source_rule, ngx.var.deny_referer_rule)`
+	if check := buildDenyCheck(cfg, loc, "example.com_/"); check != expected {
+		t.Errorf("Expected '%v' but returned '%v'", expected, check)
+	}
+
+	loc.Denylist.CIDR = []string{"1.1.1.1/32"}
+	variable := strings.TrimPrefix(buildDenyVariable("denylist_example.com_/"), "$")
+	expected = fmt.Sprintf(`require("deny").check(ngx.var.deny_source_rule, ngx.var.deny_referer_rule, ngx.var.%v)`, variable)
+	if check := buildDenyCheck(cfg, loc, "example.com_/"); check != expected {
+		t.Errorf("Expected '%v' but returned '%v'", expected, check)
+	}
+}
+
+func TestBuildGeoIPMap(t *testing.T) {
+	tests := []struct {
+		config   geoip.Config
+		expected []string
+	}{
+		{
+			geoip.Config{DenyCountries: []string{"CN", "RU"}},
+			[]string{"default 0;", "CN 1;", "RU 1;"},
+		},
+		{
+			geoip.Config{AllowCountries: []string{"CA", "US"}},
+			[]string{"default 1;", "CA 0;", "US 0;"},
+		},
+		{
+			geoip.Config{AllowCountries: []string{"CA", "US"}, DenyCountries: []string{"US"}},
+			[]string{"default 1;", "CA 0;"},
+		},
+	}
+
+	for _, test := range tests {
+		entries := buildGeoIPMap(&ingress.Location{GeoIP: test.config})
+		if !reflect.DeepEqual(entries, test.expected) {
+			t.Errorf("Expected '%v' but returned '%v'", test.expected, entries)
+		}
+	}
+}
+
+func TestBuildGeoIPAccess(t *testing.T) {
+	loc := &ingress.Location{
+		GeoIP: geoip.Config{DenyCountries: []string{"US"}},
+	}
+
+	expected := "return 403;"
+	if access := buildGeoIPAccess(loc); access != expected {
+		t.Errorf("Expected '%v' but returned '%v'", expected, access)
+	}
+
+	loc.GeoIP.RedirectURL = "https://example.com/unavailable"
+	expected = "return 302 https://example.com/unavailable;"
+	if access := buildGeoIPAccess(loc); access != expected {
+		t.Errorf("Expected '%v' but returned '%v'", expected, access)
+	}
+}
+
+func TestFilterCorsOrigins(t *testing.T) {
+	origins := []string{"https://a.test.com", `~^https://.+\.test\.com$`}
+	servers := []*ingress.Server{
+		{
+			Locations: []*ingress.Location{
+				{CorsConfig: cors.Config{CorsEnabled: true, CorsAllowOrigin: []string{"*"}}},
+				{CorsConfig: cors.Config{CorsEnabled: true, CorsAllowOrigin: origins}},
+				{CorsConfig: cors.Config{CorsEnabled: false, CorsAllowOrigin: []string{"~^https://b"}}},
+			},
+		},
+		{
+			Locations: []*ingress.Location{
+				{CorsConfig: cors.Config{CorsEnabled: true, CorsAllowOrigin: origins}},
+				{CorsConfig: cors.Config{CorsEnabled: true, CorsAllowOrigin: []string{"https://c.test.com"}}},
+			},
+		},
+	}
+
+	expected := [][]string{origins}
+	if lists := filterCorsOrigins(servers); !reflect.DeepEqual(lists, expected) {
+		t.Errorf("Expected '%v' but returned '%v'", expected, lists)
+	}
+}
+
+func TestBuildCorsOrigin(t *testing.T) {
+	if origin := buildCorsOrigin([]string{"*"}); origin != "*" {
+		t.Errorf("Expected '*' but returned '%v'", origin)
+	}
+
+	if origin := buildCorsOrigin([]string{"https://a.test.com"}); origin != "https://a.test.com" {
+		t.Errorf("Expected 'https://a.test.com' but returned '%v'", origin)
+	}
+
+	origins := []string{"https://a.test.com", `~^https://.+\.test\.com$`}
+	expected := buildDenyVariable("cors_" + strings.Join(origins, ","))
+	if origin := buildCorsOrigin(origins); origin != expected {
+		t.Errorf("Expected '%v' but returned '%v'", expected, origin)
+	}
+
+	expectedEntries := []string{
+		`"https://a.test.com" $http_origin;`,
+		`"~^https://.+\\.test\\.com$" $http_origin;`,
+	}
+	if entries := buildCorsOriginMap(origins); !reflect.DeepEqual(entries, expectedEntries) {
+		t.Errorf("Expected '%v' but returned '%v'", expectedEntries, entries)
+	}
+}
+
+func TestFilterRedirectMaps(t *testing.T) {
+	rm := redirectmap.Config{
+		Name:  "default/redirects",
+		Rules: []redirectmap.Rule{{Source: "/old", Target: "/new", Code: 301}},
+	}
+	global := redirectmap.Config{
+		Name:  "default/global",
+		Rules: []redirectmap.Rule{{Source: "/global", Target: "/new", Code: 301}},
+	}
+	servers := []*ingress.Server{
+		{
+			Locations: []*ingress.Location{
+				{RedirectMap: rm},
+				{RedirectMap: redirectmap.Config{Name: "default/empty"}},
+			},
+		},
+		{
+			Locations: []*ingress.Location{{RedirectMap: rm}, {}},
+		},
+	}
+
+	expected := []redirectmap.Config{global, rm}
+	if maps := filterRedirectMaps(servers, global); !reflect.DeepEqual(maps, expected) {
+		t.Errorf("Expected '%v' but returned '%v'", expected, maps)
+	}
+}
+
+func TestBuildRedirectMap(t *testing.T) {
+	rm := redirectmap.Config{
+		Name: "default/redirects",
+		Rules: []redirectmap.Rule{
+			{Source: "/old", Target: "https://example.com/new", Code: 301},
+			{Source: "/shop", Target: "https://shop.example.com/", Code: 302, PreservePath: true, PreserveQuery: true},
+			{Source: `~^/blog/(?<slug>[^/]+)\.html$`, Target: "https://blog.example.com/$slug", Code: 301},
+		},
+	}
+
+	expected := []redirectMapBlock{
+		{
+			Variable: buildDenyVariable("redirect_default/redirects_301"),
+			Code:     301,
+			Entries: []string{
+				`"/old" "https://example.com/new";`,
+				`"~^/blog/(?<slug>[^/]+)\\.html$" "https://blog.example.com/$slug";`,
+			},
+		},
+		{
+			Variable: buildDenyVariable("redirect_default/redirects_302"),
+			Code:     302,
+			Entries: []string{
+				`"/shop" "https://shop.example.com$redirect_request_path$is_args$args";`,
+			},
+		},
+	}
+
+	if blocks := buildRedirectMap(rm); !reflect.DeepEqual(blocks, expected) {
+		t.Errorf("Expected '%v' but returned '%v'", expected, blocks)
+	}
+
+	if blocks := buildRedirectMap(redirectmap.Config{}); len(blocks) != 0 {
+		t.Errorf("Expected no blocks without rules but returned '%v'", blocks)
+	}
+}
+
+func TestMergeHeaders(t *testing.T) {
+	global := map[string]string{
+		"X-Global":   "global",
+		"X-Override": "global",
+		"X-Remove":   "global",
+	}
+	location := map[string]string{
+		"x-override": "$host",
+		"X-Remove":   "",
+		"X-Location": "location",
+	}
+
+	expected := map[string]string{
+		"X-Global":   "global",
+		"x-override": "$host",
+		"X-Remove":   "",
+		"X-Location": "location",
+	}
+	if headers := mergeHeaders(global, location); !reflect.DeepEqual(headers, expected) {
+		t.Errorf("Expected '%v' but returned '%v'", expected, headers)
+	}
+
+	expected = map[string]string{
+		"X-Global": "global",
+	}
+	if headers := filterHeaders(global, location); !reflect.DeepEqual(headers, expected) {
+		t.Errorf("Expected '%v' but returned '%v'", expected, headers)
+	}
+
+	if headers := mergeHeaders(global, map[string]string(nil)); !reflect.DeepEqual(headers, global) {
+		t.Errorf("Expected '%v' but returned '%v'", global, headers)
+	}
+}
+
+func TestBuildHideHeaders(t *testing.T) {
+	global := []string{"X-Powered-By", "Server"}
+
+	if headers := buildHideHeaders(global, []string{}); len(headers) != 0 {
+		t.Errorf("Expected no headers without location headers but returned '%v'", headers)
+	}
+
+	expected := []string{"X-Powered-By", "Server", "X-Debug"}
+	if headers := buildHideHeaders(global, []string{"server", "X-Debug"}); !reflect.DeepEqual(headers, expected) {
+		t.Errorf("Expected '%v' but returned '%v'", expected, headers)
+	}
+}
+
 func TestBuildClientBodyBufferSize(t *testing.T) {
 	a := isValidClientBodyBufferSize("1000")
 	if a != true {
@@ -599,6 +971,9 @@ func TestBuildNextUpstream(t *testing.T) {
 func TestBuildRateLimit(t *testing.T) {
 	loc := &ingress.Location{}
 
+	loc.RateLimit.ID = "abc"
+	loc.RateLimit.StatusCode = 429
+
 	loc.RateLimit.Connections.Name = "con"
 	loc.RateLimit.Connections.Limit = 1
 
@@ -617,16 +992,83 @@ func TestBuildRateLimit(t *testing.T) {
 		"limit_conn con 1;",
 		"limit_req zone=rps burst=1 nodelay;",
 		"limit_req zone=rpm burst=2 nodelay;",
+		"limit_conn_status 599;",
+		"limit_req_status 599;",
+		"error_page 599 = @ratelimit_abc;",
 		"limit_rate_after 1k;",
 		"limit_rate 1k;",
 	}
 
 	limits := buildRateLimit(loc)
+	if !reflect.DeepEqual(limits, validLimits) {
+		t.Errorf("Expected '%v' but returned '%v'", validLimits, limits)
+	}
 
-	for i, limit := range limits {
-		if limit != validLimits[i] {
-			t.Errorf("Expected '%v' but returned '%v'", validLimits, limits)
-		}
+	loc.RateLimit.DryRun = true
+	validLimits = []string{
+		"mirror /_ratelimit-dry-run-abc;",
+		"mirror_request_body off;",
+		"limit_rate_after 1k;",
+		"limit_rate 1k;",
+	}
+
+	limits = buildRateLimit(loc)
+	if !reflect.DeepEqual(limits, validLimits) {
+		t.Errorf("Expected '%v' but returned '%v'", validLimits, limits)
+	}
+
+	validLimits = []string{
+		"limit_conn con 1;",
+		"limit_req zone=rps burst=1 nodelay;",
+		"limit_req zone=rpm burst=2 nodelay;",
+		"limit_conn_status 599;",
+		"limit_req_status 599;",
+		"error_page 599 = @ratelimit_dry_run_abc;",
+	}
+
+	limits = buildRateLimitDryRun(loc.RateLimit)
+	if !reflect.DeepEqual(limits, validLimits) {
+		t.Errorf("Expected '%v' but returned '%v'", validLimits, limits)
+	}
+}
+
+func TestBuildRateLimitReject(t *testing.T) {
+	rl := ratelimit.Config{Name: "default_foo", StatusCode: 429}
+
+	expected := `require("ratelimit").reject("default_foo", 429, "")`
+	if reject := buildRateLimitReject(rl); reject != expected {
+		t.Errorf("Expected '%v' but returned '%v'", expected, reject)
+	}
+
+	rl.ResponseBody = `{"error":"too many \"requests\""}`
+	expected = `require("ratelimit").reject("default_foo", 429, "eyJlcnJvciI6InRvbyBtYW55IFwicmVxdWVzdHNcIiJ9")`
+	if reject := buildRateLimitReject(rl); reject != expected {
+		t.Errorf("Expected '%v' but returned '%v'", expected, reject)
+	}
+}
+
+func TestBuildRateLimitJWTClaim(t *testing.T) {
+	loc := &ingress.Location{}
+
+	loc.RateLimit.ID = "abc"
+	loc.RateLimit.StatusCode = 503
+	loc.RateLimit.RPS.Name = "rps"
+	loc.RateLimit.RPS.Limit = 1
+	loc.RateLimit.RPS.Burst = 1
+	loc.RateLimit.Key = "$limit_claim_abc"
+	loc.RateLimit.JWTClaim = "tenant"
+
+	validLimits := []string{
+		`set_by_lua_block $limit_claim_abc { return require("jwt").claim(ngx.var.http_authorization, "tenant") }`,
+		"limit_req zone=rps burst=1 nodelay;",
+		"limit_conn_status 599;",
+		"limit_req_status 599;",
+		"error_page 599 = @ratelimit_abc;",
+	}
+
+	limits := buildRateLimit(loc)
+	if !reflect.DeepEqual(limits, validLimits) {
+		t.Errorf("Expected '%v' but returned '%v'", validLimits, limits)
 	}
 }
 
@@ -143,7 +143,34 @@ type Backend struct {
 	// http://nginx.org/en/docs/http/ngx_http_core_module.html#limit_rate_after
 	LimitRateAfter int `json:"limit-rate-after"`
 
+	// LimitReqStatusCode Sets the status code to return in response to rejected requests.
+	// http://nginx.org/en/docs/http/ngx_http_limit_req_module.html#limit_req_status
+	// Default: 503
+	LimitReqStatusCode int `json:"limit-req-status-code"`
+
+	// Enables or disables the use of the nginx geoip module that creates variables with values depending on the client IP
+	// http://nginx.org/en/docs/http/ngx_http_geoip_module.html
+	UseGeoIP bool `json:"use-geoip,omitempty"`
+
 	// Enables or disables buffering of responses from the proxied server.
 	// http://nginx.org/en/docs/http/ngx_http_proxy_module.html#proxy_buffering
 	ProxyBuffering string `json:"proxy-buffering"`
+
+	// GlobalExternalAuthURL is the URL of the external authentication service
+	// used in the locations without the annotation auth-url.
+	// The annotation enable-global-auth: "false" disables it in an Ingress rule.
+	// Default: "" (disabled)
+	GlobalExternalAuthURL string `json:"global-auth-url"`
+
+	// GlobalExternalAuthSigninURL is the location of the error page of the
+	// global external authentication
+	GlobalExternalAuthSigninURL string `json:"global-auth-signin"`
+
+	// GlobalExternalAuthMethod is the HTTP method used in the requests to the
+	// global external authentication service
+	GlobalExternalAuthMethod string `json:"global-auth-method"`
+
+	// GlobalExternalAuthResponseHeaders contains the headers of the response of
+	// the global external authentication service passed to the backend
+	GlobalExternalAuthResponseHeaders []string `json:"global-auth-response-headers,-"`
 }
@@ -37,6 +37,9 @@ type Resolver interface {
 
 	// GetService searches for services contenating the namespace and name using a the character /
 	GetService(string) (*apiv1.Service, error)
+
+	// GetConfigMap searches for configmaps contenating the namespace and name using a the character /
+	GetConfigMap(string) (*apiv1.ConfigMap, error)
 }
 
 // AuthSSLCert contains the necessary information to do certificate based
@@ -48,6 +51,12 @@ type AuthSSLCert struct {
 	CAFileName string `json:"caFilename"`
 	// PemSHA contains the SHA1 hash of the 'ca.crt' or combinations of (tls.crt, tls.key, tls.crt) depending on certs in secret
 	PemSHA string `json:"pemSha"`
+	// PemFileName contains the path to the secrets 'tls.crt' and 'tls.key' combined
+	PemFileName string `json:"pemFilename,omitempty"`
+	// CRLFileName contains the path to the certificate revocation lists ('ca.crl')
+	CRLFileName string `json:"crlFilename,omitempty"`
+	// CRLSHA contains the SHA1 hash of the certificate revocation lists
+	CRLSHA string `json:"crlSha,omitempty"`
 }
 
 // Equal tests for equality between two AuthSSLCert types
@@ -61,6 +70,15 @@ func (asslc1 *AuthSSLCert) Equal(assl2 *AuthSSLCert) bool {
 	if asslc1.PemSHA != assl2.PemSHA {
 		return false
 	}
+	if asslc1.PemFileName != assl2.PemFileName {
+		return false
+	}
+	if asslc1.CRLFileName != assl2.CRLFileName {
+		return false
+	}
+	if asslc1.CRLSHA != assl2.CRLSHA {
+		return false
+	}
 
 	return true
 }
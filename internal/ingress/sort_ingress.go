@@ -42,6 +42,28 @@ type SSLCert struct {
 	CN []string `json:"cn"`
 	// ExpiresTime contains the expiration of this SSL certificate in timestamp format
 	ExpireTime time.Time `json:"expires"`
+	// CRLFileName contains the path to the file with the certificate
+	// revocation lists used in client certificate authentication
+	CRLFileName string `json:"crlFileName,omitempty"`
+	// CRLSHA contains the sha1 of the CRL file
+	CRLSHA string `json:"crlSha,omitempty"`
+	// CRLNextUpdate is the earliest next update of the certificate revocation lists
+	CRLNextUpdate time.Time `json:"crlNextUpdate,omitempty"`
+	// KeyType contains the public key algorithm of the certificate (RSA or ECDSA)
+	KeyType string `json:"keyType,omitempty"`
+	// ChainError contains the reason of the failure of the completion of
+	// the intermediate CA certificates chain (if any)
+	ChainError string `json:"chainError,omitempty"`
+	// OCSPResponseFileName contains the path to the file with the DER encoded
+	// OCSP response of the certificate (used in ssl_stapling_file)
+	OCSPResponseFileName string `json:"ocspResponseFileName,omitempty"`
+	// OCSPResponseSHA contains the sha1 of the OCSP response file
+	OCSPResponseSHA string `json:"ocspResponseSha,omitempty"`
+	// OCSPThisUpdate is the time at which the OCSP response was known to be correct
+	OCSPThisUpdate time.Time `json:"ocspThisUpdate,omitempty"`
+	// OCSPNextUpdate is the time at or before which a new OCSP response
+	// will be available
+	OCSPNextUpdate time.Time `json:"ocspNextUpdate,omitempty"`
 }
 
 // GetObjectKind implements the ObjectKind interface as a noop
@@ -52,6 +52,8 @@ const (
 type Sync interface {
 	Run()
 	Shutdown()
+	// IsLeader checks if the instance is the current leader
+	IsLeader() bool
 }
 
 type ingressLister interface {
@@ -102,6 +104,11 @@ func (s statusSync) Run() {
 	s.elector.Run()
 }
 
+// IsLeader checks if the instance is the leader that updates the status
+func (s statusSync) IsLeader() bool {
+	return s.elector.IsLeader()
+}
+
 // Shutdown stop the sync. In case the instance is the leader it will remove the current IP
 // if there is no other instances running.
 func (s statusSync) Shutdown() {
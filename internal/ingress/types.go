@@ -28,11 +28,16 @@ import (
 	"k8s.io/ingress-nginx/internal/ingress/annotations/authtls"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/connection"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/cors"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/customheaders"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/geoip"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/ipdenylist"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/ipwhitelist"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/jwtauth"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/log"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/proxy"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/ratelimit"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/redirect"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/redirectmap"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/rewrite"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
@@ -80,6 +85,16 @@ type Backend struct {
 	// SecureCACert has the filename and SHA1 of the certificate authorities used to validate
 	// a secured connection to the backend
 	SecureCACert resolver.AuthSSLCert `json:"secureCACert"`
+	// SecureClientCert has the filename and SHA1 of the certificate and key presented
+	// to the backend to authenticate the connection
+	SecureClientCert resolver.AuthSSLCert `json:"secureClientCert"`
+	// SecureServerName overrides the name used to verify the certificate of the backend
+	// and sent in the SNI extension
+	SecureServerName string `json:"secureServerName"`
+	// SecureVerifyDepth sets the verification depth of the certificate chain of the backend
+	SecureVerifyDepth int `json:"secureVerifyDepth"`
+	// SecureProtocols restricts the TLS protocols used to connect to the backend
+	SecureProtocols string `json:"secureProtocols"`
 	// SSLPassthrough indicates that Ingress controller will delegate TLS termination to the endpoints.
 	SSLPassthrough bool `json:"sslPassthrough"`
 	// Endpoints contains the list of endpoints currently running
@@ -150,6 +165,18 @@ type Server struct {
 	// used to  determine if the secret changed without the use of file
 	// system notifications
 	SSLPemChecksum string `json:"sslPemChecksum"`
+	// SSLSecondaryCertificate path to an additional SSL certificate on disk
+	// with a different key type (RSA or ECDSA) than SSLCertificate
+	SSLSecondaryCertificate string `json:"sslSecondaryCertificate,omitempty"`
+	// SSLSecondaryPemChecksum returns the checksum of the additional
+	// certificate file on disk
+	SSLSecondaryPemChecksum string `json:"sslSecondaryPemChecksum,omitempty"`
+	// SSLOCSPResponse path to the file with the OCSP response of the
+	// certificate fetched by the controller
+	SSLOCSPResponse string `json:"sslOCSPResponse,omitempty"`
+	// SSLOCSPResponseChecksum returns the checksum of the OCSP response file
+	// on disk. A new response requires a reload
+	SSLOCSPResponseChecksum string `json:"sslOCSPResponseChecksum,omitempty"`
 	// Locations list of URIs configured in the server.
 	Locations []*Location `json:"locations,omitempty"`
 	// Alias return the alias of the server name
@@ -166,6 +193,9 @@ type Server struct {
 	SSLCiphers string `json:"sslCiphers,omitempty"`
 	// AuthTLSError contains the reason why the access to a server should be denied
 	AuthTLSError string `json:"authTLSError,omitempty"`
+	// ACMEChallenge indicates the ACME HTTP-01 challenges of the server are
+	// answered by the ingress controller
+	ACMEChallenge bool `json:"acmeChallenge,omitempty"`
 }
 
 // Location describes an URI inside a server.
@@ -174,11 +204,15 @@ type Server struct {
 // In some cases when more than one annotations is defined a particular order in the execution
 // is required.
 // The chain in the execution order of annotations should be:
+// - Denylist
 // - Whitelist
+// - GeoIP
 // - RateLimit
 // - BasicDigestAuth
 // - ExternalAuth
+// - JWTAuth
 // - Redirect
+// - RedirectMap
 type Location struct {
 	// Path is an extended POSIX regex as defined by IEEE Std 1003.1,
 	// (i.e this follows the egrep/unix syntax, not the perl syntax)
@@ -214,10 +248,18 @@ type Location struct {
 	// CorsConfig returns the Cors Configuration for the ingress rule
 	// +optional
 	CorsConfig cors.Config `json:"corsConfig,omitempty"`
+	// CustomHeaders contains the headers added, replaced or removed in
+	// the requests to the upstream servers and in the responses
+	// +optional
+	CustomHeaders customheaders.Config `json:"customHeaders,omitempty"`
 	// ExternalAuth indicates the access to this location requires
 	// authentication using an external provider
 	// +optional
 	ExternalAuth authreq.Config `json:"externalAuth,omitempty"`
+	// JWTAuth indicates the access to this location requires a valid
+	// JWT bearer token
+	// +optional
+	JWTAuth jwtauth.Config `json:"jwtAuth,omitempty"`
 	// RateLimit describes a limit in the number of connections per IP
 	// address or connections per second.
 	// The Redirect annotation precedes RateLimit
@@ -226,6 +268,10 @@ type Location struct {
 	// Redirect describes a temporal o permanent redirection this location.
 	// +optional
 	Redirect redirect.Config `json:"redirect,omitempty"`
+	// RedirectMap contains the redirect rules of a ConfigMap applied to
+	// the requests of this location.
+	// +optional
+	RedirectMap redirectmap.Config `json:"redirectMap,omitempty"`
 	// Rewrite describes the redirection this location.
 	// +optional
 	Rewrite rewrite.Config `json:"rewrite,omitempty"`
@@ -233,6 +279,14 @@ type Location struct {
 	// addresses or networks are allowed.
 	// +optional
 	Whitelist ipwhitelist.SourceRange `json:"whitelist,omitempty"`
+	// Denylist indicates connections from certain client addresses
+	// or networks are denied.
+	// +optional
+	Denylist ipdenylist.SourceRange `json:"denylist,omitempty"`
+	// GeoIP indicates the access to the location is allowed or denied
+	// based on the country of the client address.
+	// +optional
+	GeoIP geoip.Config `json:"geoip,omitempty"`
 	// Proxy contains information about timeouts and buffer sizes
 	// to be used in connections against endpoints
 	// +optional
@@ -291,6 +345,28 @@ type L4Service struct {
 	Backend L4Backend `json:"backend"`
 	// Endpoints active endpoints of the service
 	Endpoints []Endpoint `json:"endpoints,omitempty"`
+	// SSLCertificate path to the SSL certificate on disk used to terminate
+	// TLS connections. Only valid for TCP services
+	// +optional
+	SSLCertificate string `json:"sslCertificate,omitempty"`
+	// SSLPemChecksum returns the checksum of the certificate file on disk.
+	// +optional
+	SSLPemChecksum string `json:"sslPemChecksum,omitempty"`
+	// SNI contains the services selected using the server name indicated
+	// by the client in the TLS handshake. Backend is used when there is no match
+	// +optional
+	SNI []L4SNIService `json:"sni,omitempty"`
+}
+
+// L4SNIService describes a service selected by the hostname the client
+// sent in the TLS handshake (SNI) of a TLS terminated L4 service
+type L4SNIService struct {
+	// Hostname server name indicated by the client
+	Hostname string `json:"hostname"`
+	// Backend of the service
+	Backend L4Backend `json:"backend"`
+	// Endpoints active endpoints of the service
+	Endpoints []Endpoint `json:"endpoints,omitempty"`
 }
 
 // L4Backend describes the kubernetes service behind L4 Ingress service
@@ -301,6 +377,19 @@ type L4Backend struct {
 	Protocol  apiv1.Protocol     `json:"protocol"`
 	// +optional
 	ProxyProtocol ProxyProtocol `json:"proxyProtocol"`
+	// ProxyTimeout overrides the global proxy-stream-timeout
+	// +optional
+	ProxyTimeout string `json:"proxyTimeout,omitempty"`
+	// ProxyResponses overrides the global proxy-stream-responses (UDP only)
+	// +optional
+	ProxyResponses int `json:"proxyResponses,omitempty"`
+	// WhitelistSourceRange list of CIDRs allowed to connect to the service
+	// +optional
+	WhitelistSourceRange []string `json:"whitelistSourceRange,omitempty"`
+	// LimitConnections number of concurrent connections allowed from
+	// a single IP address
+	// +optional
+	LimitConnections int `json:"limitConnections,omitempty"`
 }
 
 // ProxyProtocol describes the proxy protocol configuration
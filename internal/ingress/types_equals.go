@@ -143,6 +143,18 @@ func (b1 *Backend) Equal(b2 *Backend) bool {
 	if !(&b1.SecureCACert).Equal(&b2.SecureCACert) {
 		return false
 	}
+	if !(&b1.SecureClientCert).Equal(&b2.SecureClientCert) {
+		return false
+	}
+	if b1.SecureServerName != b2.SecureServerName {
+		return false
+	}
+	if b1.SecureVerifyDepth != b2.SecureVerifyDepth {
+		return false
+	}
+	if b1.SecureProtocols != b2.SecureProtocols {
+		return false
+	}
 	if b1.SSLPassthrough != b2.SSLPassthrough {
 		return false
 	}
@@ -277,9 +289,24 @@ func (s1 *Server) Equal(s2 *Server) bool {
 	if s1.SSLFullChainCertificate != s2.SSLFullChainCertificate {
 		return false
 	}
+	if s1.SSLSecondaryCertificate != s2.SSLSecondaryCertificate {
+		return false
+	}
+	if s1.SSLSecondaryPemChecksum != s2.SSLSecondaryPemChecksum {
+		return false
+	}
+	if s1.SSLOCSPResponse != s2.SSLOCSPResponse {
+		return false
+	}
+	if s1.SSLOCSPResponseChecksum != s2.SSLOCSPResponseChecksum {
+		return false
+	}
 	if s1.RedirectFromToWWW != s2.RedirectFromToWWW {
 		return false
 	}
+	if s1.ACMEChallenge != s2.ACMEChallenge {
+		return false
+	}
 
 	if len(s1.Locations) != len(s2.Locations) {
 		return false
@@ -343,21 +370,36 @@ func (l1 *Location) Equal(l2 *Location) bool {
 	if !(&l1.CorsConfig).Equal(&l2.CorsConfig) {
 		return false
 	}
+	if !(&l1.CustomHeaders).Equal(&l2.CustomHeaders) {
+		return false
+	}
 	if !(&l1.ExternalAuth).Equal(&l2.ExternalAuth) {
 		return false
 	}
+	if !(&l1.JWTAuth).Equal(&l2.JWTAuth) {
+		return false
+	}
 	if !(&l1.RateLimit).Equal(&l2.RateLimit) {
 		return false
 	}
 	if !(&l1.Redirect).Equal(&l2.Redirect) {
 		return false
 	}
+	if !(&l1.RedirectMap).Equal(&l2.RedirectMap) {
+		return false
+	}
 	if !(&l1.Rewrite).Equal(&l2.Rewrite) {
 		return false
 	}
 	if !(&l1.Whitelist).Equal(&l2.Whitelist) {
 		return false
 	}
+	if !(&l1.Denylist).Equal(&l2.Denylist) {
+		return false
+	}
+	if !(&l1.GeoIP).Equal(&l2.GeoIP) {
+		return false
+	}
 	if !(&l1.Proxy).Equal(&l2.Proxy) {
 		return false
 	}
@@ -456,6 +498,57 @@ func (e1 *L4Service) Equal(e2 *L4Service) bool {
 		}
 	}
 
+	if e1.SSLCertificate != e2.SSLCertificate {
+		return false
+	}
+	if e1.SSLPemChecksum != e2.SSLPemChecksum {
+		return false
+	}
+	if len(e1.SNI) != len(e2.SNI) {
+		return false
+	}
+
+	// SNI services are sorted
+	for idx, sni1 := range e1.SNI {
+		if !(&sni1).Equal(&e2.SNI[idx]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Equal tests for equality between two L4SNIService types
+func (s1 *L4SNIService) Equal(s2 *L4SNIService) bool {
+	if s1 == s2 {
+		return true
+	}
+	if s1 == nil || s2 == nil {
+		return false
+	}
+	if s1.Hostname != s2.Hostname {
+		return false
+	}
+	if !(&s1.Backend).Equal(&s2.Backend) {
+		return false
+	}
+	if len(s1.Endpoints) != len(s2.Endpoints) {
+		return false
+	}
+
+	for _, ep1 := range s1.Endpoints {
+		found := false
+		for _, ep2 := range s2.Endpoints {
+			if (&ep1).Equal(&ep2) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
 	return true
 }
 
@@ -479,6 +572,26 @@ func (l4b1 *L4Backend) Equal(l4b2 *L4Backend) bool {
 	if l4b1.Protocol != l4b2.Protocol {
 		return false
 	}
+	if l4b1.ProxyProtocol != l4b2.ProxyProtocol {
+		return false
+	}
+	if l4b1.ProxyTimeout != l4b2.ProxyTimeout {
+		return false
+	}
+	if l4b1.ProxyResponses != l4b2.ProxyResponses {
+		return false
+	}
+	if l4b1.LimitConnections != l4b2.LimitConnections {
+		return false
+	}
+	if len(l4b1.WhitelistSourceRange) != len(l4b2.WhitelistSourceRange) {
+		return false
+	}
+	for idx, cidr := range l4b1.WhitelistSourceRange {
+		if cidr != l4b2.WhitelistSourceRange[idx] {
+			return false
+		}
+	}
 
 	return true
 }
@@ -497,6 +610,9 @@ func (s1 *SSLCert) Equal(s2 *SSLCert) bool {
 	if s1.PemSHA != s2.PemSHA {
 		return false
 	}
+	if s1.CRLSHA != s2.CRLSHA {
+		return false
+	}
 	if !s1.ExpireTime.Equal(s2.ExpireTime) {
 		return false
 	}
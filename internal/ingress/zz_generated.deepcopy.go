@@ -38,6 +38,7 @@ func (in *Backend) DeepCopyInto(out *Backend) {
 	}
 	out.Port = in.Port
 	out.SecureCACert = in.SecureCACert
+	out.SecureClientCert = in.SecureClientCert
 	if in.Endpoints != nil {
 		in, out := &in.Endpoints, &out.Endpoints
 		*out = make([]Endpoint, len(*in))
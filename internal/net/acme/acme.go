@@ -0,0 +1,636 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package acme implements the subset of the ACME protocol (RFC 8555)
+// required to issue certificates using HTTP-01 challenges.
+package acme
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	statusPending    = "pending"
+	statusProcessing = "processing"
+	statusReady      = "ready"
+	statusValid      = "valid"
+	statusInvalid    = "invalid"
+
+	challengeHTTP01 = "http-01"
+
+	errBadNonce = "urn:ietf:params:acme:error:badNonce"
+
+	// maxBadNonceRetries number of times a request is retried when the
+	// server rejects the nonce
+	maxBadNonceRetries = 3
+)
+
+// Directory contains the URLs of the resources exposed by an ACME server
+type Directory struct {
+	NewNonce   string `json:"newNonce"`
+	NewAccount string `json:"newAccount"`
+	NewOrder   string `json:"newOrder"`
+}
+
+// Error is a problem document returned by the ACME server (RFC 7807)
+type Error struct {
+	StatusCode int    `json:"-"`
+	Type       string `json:"type"`
+	Detail     string `json:"detail"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("acme: %v (%v): %v", e.Type, e.StatusCode, e.Detail)
+}
+
+type identifier struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type order struct {
+	Status         string       `json:"status"`
+	Identifiers    []identifier `json:"identifiers"`
+	Authorizations []string     `json:"authorizations"`
+	Finalize       string       `json:"finalize"`
+	Certificate    string       `json:"certificate,omitempty"`
+	Error          *Error       `json:"error,omitempty"`
+}
+
+type authorization struct {
+	Status     string      `json:"status"`
+	Identifier identifier  `json:"identifier"`
+	Challenges []challenge `json:"challenges"`
+}
+
+type challenge struct {
+	Type   string `json:"type"`
+	URL    string `json:"url"`
+	Token  string `json:"token"`
+	Status string `json:"status"`
+	Error  *Error `json:"error,omitempty"`
+}
+
+// Solver makes the key authorization of a HTTP-01 challenge available
+// under http://<domain>/.well-known/acme-challenge/<token>
+type Solver interface {
+	Present(token, keyAuth string) error
+	CleanUp(token string)
+}
+
+// Client issues certificates from an ACME server
+type Client struct {
+	// DirectoryURL is the URL of the directory of the ACME server
+	DirectoryURL string
+	// Key is the private key of the ACME account
+	Key *ecdsa.PrivateKey
+	// Email is the contact address of the ACME account (optional)
+	Email string
+	// HTTPClient is the client used to contact the ACME server.
+	// http.DefaultClient is used if nil
+	HTTPClient *http.Client
+	// PollInterval is the time to wait between checks of the status of
+	// authorizations and orders. Defaults to one second
+	PollInterval time.Duration
+	// PollTimeout is the maximum time to wait until an authorization or an
+	// order is valid. Defaults to two minutes
+	PollTimeout time.Duration
+
+	mu         sync.Mutex
+	dir        *Directory
+	accountURL string
+	nonces     []string
+}
+
+// GenerateAccountKey creates a new private key for an ACME account
+func GenerateAccountKey() (*ecdsa.PrivateKey, error) {
+	return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+}
+
+// EncodeAccountKey encodes an account key using PEM
+func EncodeAccountKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
+
+// DecodeAccountKey decodes a PEM encoded account key
+func DecodeAccountKey(data []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "EC PRIVATE KEY" {
+		return nil, fmt.Errorf("no EC private key found")
+	}
+
+	return x509.ParseECPrivateKey(block.Bytes)
+}
+
+// ObtainCertificate requests a certificate valid for the hosts using
+// solver to satisfy the HTTP-01 challenges. It returns the PEM encoded
+// certificate chain and private key.
+func (c *Client) ObtainCertificate(hosts []string, solver Solver) ([]byte, []byte, error) {
+	if len(hosts) == 0 {
+		return nil, nil, fmt.Errorf("at least one host is required")
+	}
+
+	err := c.register()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ids := []identifier{}
+	for _, host := range hosts {
+		ids = append(ids, identifier{Type: "dns", Value: host})
+	}
+
+	o := &order{}
+	resp, err := c.post(c.dir.NewOrder, map[string]interface{}{"identifiers": ids}, o)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating order: %v", err)
+	}
+	orderURL := resp.Header.Get("Location")
+
+	for _, authzURL := range o.Authorizations {
+		err = c.authorize(authzURL, solver)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: hosts[0]},
+		DNSNames: hosts,
+	}, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	o, err = c.waitOrder(orderURL, statusReady)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_, err = c.post(o.Finalize, map[string]string{"csr": encode(csr)}, o)
+	if err != nil {
+		return nil, nil, fmt.Errorf("finalizing order: %v", err)
+	}
+
+	if o.Status != statusValid {
+		o, err = c.waitOrder(orderURL, statusValid)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	resp, err = c.post(o.Certificate, nil, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("downloading certificate: %v", err)
+	}
+	defer resp.Body.Close()
+
+	chain, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	block, _ := pem.Decode(chain)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, nil, fmt.Errorf("the ACME server returned an invalid certificate chain")
+	}
+
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return chain, keyPEM, nil
+}
+
+// register reads the directory and creates (or finds) the account
+func (c *Client) register() error {
+	c.mu.Lock()
+	registered := c.accountURL != ""
+	c.mu.Unlock()
+	if registered {
+		return nil
+	}
+
+	if c.Key == nil {
+		return fmt.Errorf("an account key is required")
+	}
+
+	dir := &Directory{}
+	resp, err := c.httpClient().Get(c.DirectoryURL)
+	if err != nil {
+		return fmt.Errorf("reading ACME directory: %v", err)
+	}
+	err = decodeResponse(resp, dir)
+	if err != nil {
+		return fmt.Errorf("reading ACME directory: %v", err)
+	}
+
+	c.mu.Lock()
+	c.dir = dir
+	c.mu.Unlock()
+
+	account := map[string]interface{}{"termsOfServiceAgreed": true}
+	if c.Email != "" {
+		account["contact"] = []string{"mailto:" + c.Email}
+	}
+
+	resp, err = c.post(dir.NewAccount, account, nil)
+	if err != nil {
+		return fmt.Errorf("registering ACME account: %v", err)
+	}
+	resp.Body.Close()
+
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return fmt.Errorf("registering ACME account: the server did not return the account URL")
+	}
+
+	c.mu.Lock()
+	c.accountURL = location
+	c.mu.Unlock()
+
+	return nil
+}
+
+// authorize completes the HTTP-01 challenge of an authorization
+func (c *Client) authorize(authzURL string, solver Solver) error {
+	authz := &authorization{}
+	_, err := c.post(authzURL, nil, authz)
+	if err != nil {
+		return fmt.Errorf("reading authorization: %v", err)
+	}
+
+	if authz.Status == statusValid {
+		return nil
+	}
+
+	var chal *challenge
+	for i := range authz.Challenges {
+		if authz.Challenges[i].Type == challengeHTTP01 {
+			chal = &authz.Challenges[i]
+			break
+		}
+	}
+	if chal == nil {
+		return fmt.Errorf("the ACME server does not offer a %v challenge for %v", challengeHTTP01, authz.Identifier.Value)
+	}
+
+	keyAuth, err := c.keyAuthorization(chal.Token)
+	if err != nil {
+		return err
+	}
+
+	err = solver.Present(chal.Token, keyAuth)
+	if err != nil {
+		return fmt.Errorf("presenting challenge for %v: %v", authz.Identifier.Value, err)
+	}
+	defer solver.CleanUp(chal.Token)
+
+	resp, err := c.post(chal.URL, struct{}{}, nil)
+	if err != nil {
+		return fmt.Errorf("accepting challenge for %v: %v", authz.Identifier.Value, err)
+	}
+	resp.Body.Close()
+
+	return c.poll(func() (bool, error) {
+		a := &authorization{}
+		_, err := c.post(authzURL, nil, a)
+		if err != nil {
+			return false, err
+		}
+
+		switch a.Status {
+		case statusValid:
+			return true, nil
+		case statusPending, statusProcessing:
+			return false, nil
+		}
+
+		for _, ch := range a.Challenges {
+			if ch.Error != nil {
+				return false, fmt.Errorf("authorization for %v is %v: %v", a.Identifier.Value, a.Status, ch.Error.Detail)
+			}
+		}
+		return false, fmt.Errorf("authorization for %v is %v", a.Identifier.Value, a.Status)
+	})
+}
+
+// waitOrder polls the order until it reaches the expected status
+func (c *Client) waitOrder(orderURL, status string) (*order, error) {
+	o := &order{}
+	err := c.poll(func() (bool, error) {
+		_, err := c.post(orderURL, nil, o)
+		if err != nil {
+			return false, err
+		}
+
+		if o.Status == status || o.Status == statusValid {
+			return true, nil
+		}
+
+		if o.Status == statusInvalid {
+			if o.Error != nil {
+				return false, fmt.Errorf("order is invalid: %v", o.Error.Detail)
+			}
+			return false, fmt.Errorf("order is invalid")
+		}
+
+		return false, nil
+	})
+
+	return o, err
+}
+
+func (c *Client) poll(condition func() (bool, error)) error {
+	interval := c.PollInterval
+	if interval == 0 {
+		interval = time.Second
+	}
+	timeout := c.PollTimeout
+	if timeout == 0 {
+		timeout = 2 * time.Minute
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		done, err := condition()
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %v", timeout)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// keyAuthorization returns the content expected by the ACME server in the
+// response of a HTTP-01 challenge (RFC 8555, section 8.1)
+func (c *Client) keyAuthorization(token string) (string, error) {
+	thumbprint, err := JWKThumbprint(&c.Key.PublicKey)
+	if err != nil {
+		return "", err
+	}
+
+	return token + "." + thumbprint, nil
+}
+
+// post sends a request signed with the account key. A nil payload sends a
+// POST-as-GET request. If v is not nil the body of the response is decoded
+// in it and closed.
+func (c *Client) post(url string, payload interface{}, v interface{}) (*http.Response, error) {
+	var err error
+	var resp *http.Response
+	for i := 0; i < maxBadNonceRetries; i++ {
+		resp, err = c.postOnce(url, payload)
+		if err == nil {
+			break
+		}
+
+		if e, ok := err.(*Error); !ok || e.Type != errBadNonce {
+			return nil, err
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if v != nil {
+		err = decodeResponse(resp, v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+func (c *Client) postOnce(url string, payload interface{}) (*http.Response, error) {
+	nonce, err := c.nonce()
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := c.sign(url, nonce, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/jose+json")
+	req.Header.Set("Accept", "application/json, application/pem-certificate-chain")
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	c.addNonce(resp.Header.Get("Replay-Nonce"))
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, responseError(resp)
+	}
+
+	return resp, nil
+}
+
+// sign creates a JWS using the flattened JSON serialization
+func (c *Client) sign(url, nonce string, payload interface{}) ([]byte, error) {
+	protected := map[string]interface{}{
+		"alg":   "ES256",
+		"nonce": nonce,
+		"url":   url,
+	}
+
+	c.mu.Lock()
+	kid := c.accountURL
+	c.mu.Unlock()
+
+	if kid != "" {
+		protected["kid"] = kid
+	} else {
+		protected["jwk"] = jwk(&c.Key.PublicKey)
+	}
+
+	header, err := json.Marshal(protected)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	if payload != nil {
+		data, err = json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	signingInput := encode(header) + "." + encode(data)
+	hash := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, c.Key, hash[:])
+	if err != nil {
+		return nil, err
+	}
+
+	// ES256 signatures are the concatenation of R and S (RFC 7518, section 3.4)
+	size := (c.Key.Curve.Params().BitSize + 7) / 8
+	sig := make([]byte, 2*size)
+	copy(sig[size-len(r.Bytes()):size], r.Bytes())
+	copy(sig[2*size-len(s.Bytes()):], s.Bytes())
+
+	return json.Marshal(map[string]string{
+		"protected": encode(header),
+		"payload":   encode(data),
+		"signature": encode(sig),
+	})
+}
+
+func (c *Client) nonce() (string, error) {
+	c.mu.Lock()
+	if len(c.nonces) > 0 {
+		nonce := c.nonces[len(c.nonces)-1]
+		c.nonces = c.nonces[:len(c.nonces)-1]
+		c.mu.Unlock()
+		return nonce, nil
+	}
+	newNonceURL := c.dir.NewNonce
+	c.mu.Unlock()
+
+	resp, err := c.httpClient().Head(newNonceURL)
+	if err != nil {
+		return "", fmt.Errorf("requesting nonce: %v", err)
+	}
+	resp.Body.Close()
+
+	nonce := resp.Header.Get("Replay-Nonce")
+	if nonce == "" {
+		return "", fmt.Errorf("requesting nonce: the server did not return a nonce")
+	}
+
+	return nonce, nil
+}
+
+func (c *Client) addNonce(nonce string) {
+	if nonce == "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nonces = append(c.nonces, nonce)
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// JWKThumbprint returns the base64url encoded SHA-256 thumbprint of the
+// JSON Web Key of a public key (RFC 7638)
+func JWKThumbprint(key crypto.PublicKey) (string, error) {
+	pub, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return "", fmt.Errorf("unsupported key type %T", key)
+	}
+
+	k := jwk(pub)
+	// the members must be in lexicographic order and without whitespace
+	input := fmt.Sprintf(`{"crv":"%v","kty":"%v","x":"%v","y":"%v"}`, k["crv"], k["kty"], k["x"], k["y"])
+	hash := sha256.Sum256([]byte(input))
+
+	return encode(hash[:]), nil
+}
+
+func jwk(pub *ecdsa.PublicKey) map[string]string {
+	size := (pub.Curve.Params().BitSize + 7) / 8
+	return map[string]string{
+		"crv": pub.Curve.Params().Name,
+		"kty": "EC",
+		"x":   encode(padded(pub.X, size)),
+		"y":   encode(padded(pub.Y, size)),
+	}
+}
+
+func padded(n *big.Int, size int) []byte {
+	b := n.Bytes()
+	if len(b) >= size {
+		return b
+	}
+
+	p := make([]byte, size)
+	copy(p[size-len(b):], b)
+	return p
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeResponse(resp *http.Response, v interface{}) error {
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return responseError(resp)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func responseError(resp *http.Response) error {
+	defer resp.Body.Close()
+
+	e := &Error{StatusCode: resp.StatusCode}
+	body, _ := ioutil.ReadAll(resp.Body)
+	if err := json.Unmarshal(body, e); err != nil || e.Type == "" {
+		e.Type = "unknown"
+		e.Detail = string(body)
+	}
+
+	return e
+}
@@ -0,0 +1,409 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package acme
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer is a minimal ACME server that validates HTTP-01 challenges
+// requesting the key authorization from a challenge handler
+type fakeServer struct {
+	t *testing.T
+
+	srv       *httptest.Server
+	challenge http.Handler
+
+	caCert *x509.Certificate
+	caKey  *ecdsa.PrivateKey
+
+	mu          sync.Mutex
+	nonce       int
+	badNonces   int
+	accountKey  *ecdsa.PublicKey
+	hosts       []string
+	validated   map[string]bool
+	certificate []byte
+}
+
+func newFakeServer(t *testing.T, challenge http.Handler) *fakeServer {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fake ACME CA"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	caCert, _ := x509.ParseCertificate(der)
+
+	f := &fakeServer{
+		t:         t,
+		challenge: challenge,
+		caCert:    caCert,
+		caKey:     caKey,
+		validated: map[string]bool{},
+	}
+	f.srv = httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	return f
+}
+
+func (f *fakeServer) url(path string) string {
+	return f.srv.URL + path
+}
+
+func (f *fakeServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.nonce++
+	w.Header().Set("Replay-Nonce", fmt.Sprintf("nonce-%v", f.nonce))
+
+	if r.URL.Path == "/directory" {
+		json.NewEncoder(w).Encode(Directory{
+			NewNonce:   f.url("/new-nonce"),
+			NewAccount: f.url("/new-account"),
+			NewOrder:   f.url("/new-order"),
+		})
+		return
+	}
+
+	if r.URL.Path == "/new-nonce" {
+		return
+	}
+
+	payload, err := f.verify(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Error{Type: "urn:ietf:params:acme:error:malformed", Detail: err.Error()})
+		return
+	}
+
+	if f.badNonces > 0 {
+		f.badNonces--
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Error{Type: errBadNonce, Detail: "stale nonce"})
+		return
+	}
+
+	switch r.URL.Path {
+	case "/new-account":
+		w.Header().Set("Location", f.url("/account/1"))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"status":"valid"}`))
+	case "/new-order":
+		req := struct {
+			Identifiers []identifier `json:"identifiers"`
+		}{}
+		json.Unmarshal(payload, &req)
+
+		f.hosts = nil
+		for _, id := range req.Identifiers {
+			f.hosts = append(f.hosts, id.Value)
+		}
+
+		w.Header().Set("Location", f.url("/order/1"))
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(f.order())
+	case "/order/1":
+		json.NewEncoder(w).Encode(f.order())
+	case "/finalize/1":
+		req := struct {
+			CSR string `json:"csr"`
+		}{}
+		json.Unmarshal(payload, &req)
+		der, _ := base64.RawURLEncoding.DecodeString(req.CSR)
+		csr, err := x509.ParseCertificateRequest(der)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(Error{Type: "urn:ietf:params:acme:error:badCSR", Detail: err.Error()})
+			return
+		}
+
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(2),
+			Subject:      csr.Subject,
+			DNSNames:     csr.DNSNames,
+			NotBefore:    time.Now(),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		}
+		cert, err := x509.CreateCertificate(rand.Reader, tmpl, f.caCert, csr.PublicKey, f.caKey)
+		if err != nil {
+			f.t.Fatalf("unexpected error: %v", err)
+		}
+		f.certificate = append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}),
+			pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: f.caCert.Raw})...)
+
+		json.NewEncoder(w).Encode(f.order())
+	case "/certificate/1":
+		w.Header().Set("Content-Type", "application/pem-certificate-chain")
+		w.Write(f.certificate)
+	default:
+		if strings.HasPrefix(r.URL.Path, "/authz/") {
+			host := strings.TrimPrefix(r.URL.Path, "/authz/")
+			json.NewEncoder(w).Encode(f.authorization(host))
+			return
+		}
+
+		if strings.HasPrefix(r.URL.Path, "/challenge/") {
+			host := strings.TrimPrefix(r.URL.Path, "/challenge/")
+			f.validate(host)
+			json.NewEncoder(w).Encode(f.authorization(host).Challenges[0])
+			return
+		}
+
+		http.NotFound(w, r)
+	}
+}
+
+// verify checks the signature of the JWS and returns the payload
+func (f *fakeServer) verify(r *http.Request) ([]byte, error) {
+	jws := map[string]string{}
+	err := json.NewDecoder(r.Body).Decode(&jws)
+	if err != nil {
+		return nil, err
+	}
+
+	header, _ := base64.RawURLEncoding.DecodeString(jws["protected"])
+	protected := struct {
+		Alg string            `json:"alg"`
+		URL string            `json:"url"`
+		Kid string            `json:"kid"`
+		JWK map[string]string `json:"jwk"`
+	}{}
+	err = json.Unmarshal(header, &protected)
+	if err != nil {
+		return nil, err
+	}
+
+	if protected.URL != f.url(r.URL.Path) {
+		return nil, fmt.Errorf("unexpected url %v", protected.URL)
+	}
+
+	if r.URL.Path == "/new-account" {
+		x, _ := base64.RawURLEncoding.DecodeString(protected.JWK["x"])
+		y, _ := base64.RawURLEncoding.DecodeString(protected.JWK["y"])
+		f.accountKey = &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+	} else if protected.Kid != f.url("/account/1") {
+		return nil, fmt.Errorf("unexpected kid %v", protected.Kid)
+	}
+
+	sig, _ := base64.RawURLEncoding.DecodeString(jws["signature"])
+	if len(sig) != 64 {
+		return nil, fmt.Errorf("invalid signature length %v", len(sig))
+	}
+	hash := sha256.Sum256([]byte(jws["protected"] + "." + jws["payload"]))
+	if !ecdsa.Verify(f.accountKey, hash[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])) {
+		return nil, fmt.Errorf("invalid signature")
+	}
+
+	return base64.RawURLEncoding.DecodeString(jws["payload"])
+}
+
+func (f *fakeServer) validate(host string) {
+	token := "token-" + host
+	req := httptest.NewRequest("GET", "http://"+host+ChallengePath+token, nil)
+	w := httptest.NewRecorder()
+	f.challenge.ServeHTTP(w, req)
+
+	thumbprint, _ := JWKThumbprint(f.accountKey)
+	f.validated[host] = w.Code == http.StatusOK && w.Body.String() == token+"."+thumbprint
+}
+
+func (f *fakeServer) authorization(host string) authorization {
+	status := statusPending
+	if v, ok := f.validated[host]; ok {
+		status = statusInvalid
+		if v {
+			status = statusValid
+		}
+	}
+
+	return authorization{
+		Status:     status,
+		Identifier: identifier{Type: "dns", Value: host},
+		Challenges: []challenge{
+			{Type: challengeHTTP01, URL: f.url("/challenge/" + host), Token: "token-" + host, Status: status},
+		},
+	}
+}
+
+func (f *fakeServer) order() order {
+	o := order{
+		Status:   statusReady,
+		Finalize: f.url("/finalize/1"),
+	}
+
+	for _, host := range f.hosts {
+		o.Authorizations = append(o.Authorizations, f.url("/authz/"+host))
+		if !f.validated[host] {
+			o.Status = statusPending
+		}
+	}
+
+	if f.certificate != nil {
+		o.Status = statusValid
+		o.Certificate = f.url("/certificate/1")
+	}
+
+	return o
+}
+
+func newTestClient(t *testing.T, url string) *Client {
+	key, err := GenerateAccountKey()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return &Client{
+		DirectoryURL: url,
+		Key:          key,
+		Email:        "admin@example.com",
+		PollInterval: 10 * time.Millisecond,
+		PollTimeout:  time.Second,
+	}
+}
+
+func TestObtainCertificate(t *testing.T) {
+	solver := NewHTTP01Solver()
+	f := newFakeServer(t, solver)
+	defer f.srv.Close()
+
+	// the first request must be retried
+	f.badNonces = 1
+
+	c := newTestClient(t, f.url("/directory"))
+	hosts := []string{"foo.bar", "www.foo.bar"}
+	certPEM, keyPEM, err := c.ObtainCertificate(hosts, solver)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pair.Certificate) != 2 {
+		t.Errorf("expected a chain with 2 certificates but %v returned", len(pair.Certificate))
+	}
+
+	cert, _ := x509.ParseCertificate(pair.Certificate[0])
+	for _, host := range hosts {
+		if err := cert.VerifyHostname(host); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	if len(solver.tokens) != 0 {
+		t.Errorf("expected no pending tokens but %v returned", len(solver.tokens))
+	}
+}
+
+func TestObtainCertificateInvalidChallenge(t *testing.T) {
+	solver := NewHTTP01Solver()
+	// the ACME server cannot reach the solver
+	f := newFakeServer(t, http.NotFoundHandler())
+	defer f.srv.Close()
+
+	c := newTestClient(t, f.url("/directory"))
+	_, _, err := c.ObtainCertificate([]string{"foo.bar"}, solver)
+	if err == nil {
+		t.Fatalf("expected an error but none returned")
+	}
+}
+
+func TestAccountKey(t *testing.T) {
+	key, err := GenerateAccountKey()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := EncodeAccountKey(key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	decoded, err := DecodeAccountKey(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if decoded.D.Cmp(key.D) != 0 {
+		t.Errorf("expected the same key after decoding")
+	}
+
+	_, err = DecodeAccountKey([]byte("invalid"))
+	if err == nil {
+		t.Errorf("expected an error but none returned")
+	}
+}
+
+func TestHTTP01Solver(t *testing.T) {
+	solver := NewHTTP01Solver()
+	if err := solver.Present("abc", "abc.xyz"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	solver.ServeHTTP(w, httptest.NewRequest("GET", ChallengePath+"abc", nil))
+	body, _ := ioutil.ReadAll(w.Body)
+	if w.Code != http.StatusOK || string(body) != "abc.xyz" {
+		t.Errorf("expected 200 and abc.xyz but %v and %v returned", w.Code, string(body))
+	}
+
+	solver.CleanUp("abc")
+
+	w = httptest.NewRecorder()
+	solver.ServeHTTP(w, httptest.NewRequest("GET", ChallengePath+"abc", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 but %v returned", w.Code)
+	}
+}
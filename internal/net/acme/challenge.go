@@ -0,0 +1,77 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package acme
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ChallengePath is the path where the ACME server checks HTTP-01 challenges
+const ChallengePath = "/.well-known/acme-challenge/"
+
+// HTTP01Solver is a Solver that serves the key authorizations of the
+// pending challenges from memory
+type HTTP01Solver struct {
+	mu     sync.RWMutex
+	tokens map[string]string
+}
+
+// NewHTTP01Solver creates a new HTTP-01 challenge solver
+func NewHTTP01Solver() *HTTP01Solver {
+	return &HTTP01Solver{
+		tokens: map[string]string{},
+	}
+}
+
+// Present makes the key authorization of a token available
+func (s *HTTP01Solver) Present(token, keyAuth string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[token] = keyAuth
+	return nil
+}
+
+// CleanUp removes a token
+func (s *HTTP01Solver) CleanUp(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens, token)
+}
+
+// ServeHTTP returns the key authorization of the token in the request path
+func (s *HTTP01Solver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, ChallengePath) {
+		http.NotFound(w, r)
+		return
+	}
+
+	token := strings.TrimPrefix(r.URL.Path, ChallengePath)
+
+	s.mu.RLock()
+	keyAuth, ok := s.tokens[token]
+	s.mu.RUnlock()
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Write([]byte(keyAuth))
+}
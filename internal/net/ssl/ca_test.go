@@ -0,0 +1,77 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssl
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+)
+
+func TestCreateHostCertificate(t *testing.T) {
+	caPEM, caKeyPEM, err := GenerateCA("test local CA")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ca, caKey, err := ParseCA(caPEM, caKeyPEM)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	certPEM, keyPEM, err := CreateHostCertificate("*.apps.example.com", ca, caKey, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	block, rest := pem.Decode(certPEM)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if chain, _ := pem.Decode(rest); chain == nil {
+		t.Errorf("expected the CA certificate in the chain")
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	_, err = cert.Verify(x509.VerifyOptions{DNSName: "foo.apps.example.com", Roots: roots})
+	if err != nil {
+		t.Errorf("unexpected error verifying the certificate: %v", err)
+	}
+
+	if cert.NotAfter.After(time.Now().Add(25 * time.Hour)) {
+		t.Errorf("unexpected expiration %v", cert.NotAfter)
+	}
+
+	_, _, err = ParseCA(certPEM, keyPEM)
+	if err == nil {
+		t.Errorf("expected an error using a certificate that is not a CA")
+	}
+
+	ipPEM, _, err := CreateHostCertificate("10.0.0.1", ca, caKey, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	block, _ = pem.Decode(ipPEM)
+	ipCert, _ := x509.ParseCertificate(block.Bytes)
+	if len(ipCert.IPAddresses) != 1 || len(ipCert.DNSNames) != 0 {
+		t.Errorf("expected an IP SAN but %v and %v returned", ipCert.IPAddresses, ipCert.DNSNames)
+	}
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssl
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/golang/glog"
+
+	"k8s.io/ingress-nginx/internal/file"
+	"k8s.io/ingress-nginx/internal/ingress"
+)
+
+// AddCRL writes the certificate revocation lists (PEM or DER encoded) to a
+// file to be used in the ssl_crl directive, updating the CRL information of
+// the certificate. If the CA certificates are present, each CRL must be
+// signed by one of them.
+func AddCRL(name string, crl, ca []byte, sslCert *ingress.SSLCert, fs file.Filesystem) error {
+	crlFileName := fmt.Sprintf("%v/ca-%v.crl", file.DefaultSSLDirectory, name)
+
+	lists, err := parseCRLs(crl)
+	if err != nil {
+		return fmt.Errorf("invalid certificate revocation list in %v: %v", name, err)
+	}
+
+	cas := ParseCertificates(ca)
+
+	var data []byte
+	for _, rl := range lists {
+		if len(cas) > 0 && !crlSignedBy(rl, cas) {
+			return fmt.Errorf("the certificate revocation list of %v is not signed by the CA certificate", rl.issuer())
+		}
+
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: rl.der})...)
+
+		nextUpdate := rl.list.TBSCertList.NextUpdate
+		if sslCert.CRLNextUpdate.IsZero() || nextUpdate.Before(sslCert.CRLNextUpdate) {
+			sslCert.CRLNextUpdate = nextUpdate
+		}
+	}
+
+	crlFile, err := fs.Create(crlFileName)
+	if err != nil {
+		return fmt.Errorf("could not write CRL file %v: %v", crlFileName, err)
+	}
+	defer crlFile.Close()
+
+	_, err = crlFile.Write(data)
+	if err != nil {
+		return fmt.Errorf("could not write CRL file %v: %v", crlFileName, err)
+	}
+
+	glog.V(3).Infof("Created certificate revocation list for Authentication: %v", crlFileName)
+	sslCert.CRLFileName = crlFileName
+	sslCert.CRLSHA = file.SHA1(crlFileName)
+	return nil
+}
+
+// crl is a parsed certificate revocation list and its DER encoding
+type crl struct {
+	list *pkix.CertificateList
+	der  []byte
+}
+
+// parseCRLs parses one DER encoded or several PEM encoded certificate
+// revocation lists
+func parseCRLs(data []byte) ([]*crl, error) {
+	if !bytes.Contains(data, []byte("-----BEGIN")) {
+		rl, err := x509.ParseDERCRL(data)
+		if err != nil {
+			return nil, err
+		}
+		return []*crl{{list: rl, der: data}}, nil
+	}
+
+	var lists []*crl
+	for len(data) > 0 {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "X509 CRL" {
+			continue
+		}
+
+		rl, err := x509.ParseDERCRL(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, &crl{list: rl, der: block.Bytes})
+	}
+
+	if len(lists) == 0 {
+		return nil, fmt.Errorf("no valid PEM formatted block found")
+	}
+
+	return lists, nil
+}
+
+// issuer returns the common name of the issuer of the list
+func (c *crl) issuer() string {
+	var name pkix.Name
+	name.FillFromRDNSequence(&c.list.TBSCertList.Issuer)
+	return name.CommonName
+}
+
+func crlSignedBy(c *crl, cas []*x509.Certificate) bool {
+	for _, ca := range cas {
+		if ca.CheckCRLSignature(c.list) == nil {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssl
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"k8s.io/ingress-nginx/internal/file"
+)
+
+func newTestCRL(t *testing.T, issuer *testCertificate, nextUpdate time.Time) []byte {
+	revoked := []pkix.RevokedCertificate{
+		{SerialNumber: big.NewInt(10), RevocationTime: time.Now().Add(-time.Hour)},
+	}
+
+	der, err := issuer.cert.CreateCRL(rand.Reader, issuer.key, revoked, time.Now().Add(-time.Hour), nextUpdate)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return der
+}
+
+func TestAddCertAuthWithCRL(t *testing.T) {
+	newCA := func(cn string) *testCertificate {
+		return newTestCertificate(t, &x509.Certificate{
+			SerialNumber:          big.NewInt(1),
+			Subject:               pkix.Name{CommonName: cn},
+			IsCA:                  true,
+			BasicConstraintsValid: true,
+			KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		}, nil)
+	}
+
+	ca := newCA("client CA")
+	other := newCA("other CA")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw})
+
+	nextUpdate := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	der := newTestCRL(t, ca, nextUpdate)
+	crlPEM := pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der})
+
+	fs := newFS(t)
+
+	for _, crl := range [][]byte{der, crlPEM} {
+		sslCert, err := AddCertAuth("default-client-ca", caPEM, crl, fs)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if sslCert.CRLFileName != file.DefaultSSLDirectory+"/ca-default-client-ca.crl" {
+			t.Errorf("unexpected CRL file name %v", sslCert.CRLFileName)
+		}
+		if !sslCert.CRLNextUpdate.Equal(nextUpdate) {
+			t.Errorf("expected next update %v but %v returned", nextUpdate, sslCert.CRLNextUpdate)
+		}
+
+		data, err := fs.ReadFile(sslCert.CRLFileName)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if block, _ := pem.Decode(data); block == nil || block.Type != "X509 CRL" {
+			t.Errorf("expected a PEM encoded CRL")
+		}
+	}
+
+	_, err := AddCertAuth("default-client-ca", caPEM, newTestCRL(t, other, nextUpdate), fs)
+	if err == nil {
+		t.Errorf("expected an error using a CRL not signed by the CA")
+	}
+
+	_, err = AddCertAuth("default-client-ca", caPEM, []byte("invalid"), fs)
+	if err == nil {
+		t.Errorf("expected an error using an invalid CRL")
+	}
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssl
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"sync"
+)
+
+// MinDHParamSize is the minimum size in bits of the prime of valid DH parameters
+const MinDHParamSize = 1024
+
+// maxDHParamCacheSize is the number of validation results kept in memory
+const maxDHParamCacheSize = 16
+
+// dhParameters is the ASN.1 structure of the PKCS #3 DH parameters.
+// The private value length is set by 'openssl dhparam -dsaparam'
+type dhParameters struct {
+	P                  *big.Int
+	G                  *big.Int
+	PrivateValueLength int `asn1:"optional"`
+}
+
+// dhParamResult is the result of the validation of DH parameters
+type dhParamResult struct {
+	bits int
+	err  error
+}
+
+var (
+	bigOne = big.NewInt(1)
+	bigTwo = big.NewInt(2)
+
+	// dhParamCache contains the validation results indexed by the SHA256
+	// of the content and the minimum size. The parameters are validated
+	// on every update of the configuration
+	dhParamCache   = map[string]dhParamResult{}
+	dhParamCacheMu sync.Mutex
+)
+
+// GenerateDHParam returns PEM encoded DH parameters with a safe prime of
+// the specified size and generator 2, like 'openssl dhparam <bits>'.
+// The generation of large primes can take several minutes.
+func GenerateDHParam(bits int) ([]byte, error) {
+	if bits < 64 {
+		return nil, fmt.Errorf("invalid DH parameters size %v", bits)
+	}
+
+	twelve := big.NewInt(12)
+	five := big.NewInt(5)
+	q := new(big.Int)
+	p := new(big.Int)
+
+	for {
+		var err error
+		q, err = rand.Prime(rand.Reader, bits-1)
+		if err != nil {
+			return nil, err
+		}
+
+		// q ≡ 5 (mod 12) implies p ≡ 11 (mod 24), which is required by
+		// OpenSSL to consider 2 a suitable generator
+		if new(big.Int).Mod(q, twelve).Cmp(five) != 0 {
+			continue
+		}
+
+		p.Lsh(q, 1)
+		p.Add(p, bigOne)
+		if p.ProbablyPrime(20) {
+			break
+		}
+	}
+
+	der, err := asn1.Marshal(dhParameters{P: p, G: bigTwo})
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "DH PARAMETERS", Bytes: der}), nil
+}
+
+// ValidateDHParam checks the content of a dhparam.pem file: it must contain
+// PEM encoded DH parameters with a prime of at least minBits bits and a
+// generator in the range (1, p-1). Safe primes are not required to accept
+// the parameters generated with 'openssl dhparam -dsaparam'. Returns the
+// size of the prime.
+func ValidateDHParam(data []byte, minBits int) (int, error) {
+	key := fmt.Sprintf("%x-%v", sha256.Sum256(data), minBits)
+
+	dhParamCacheMu.Lock()
+	defer dhParamCacheMu.Unlock()
+
+	if r, ok := dhParamCache[key]; ok {
+		return r.bits, r.err
+	}
+
+	bits, err := validateDHParam(data, minBits)
+
+	if len(dhParamCache) >= maxDHParamCacheSize {
+		dhParamCache = map[string]dhParamResult{}
+	}
+	dhParamCache[key] = dhParamResult{bits, err}
+
+	return bits, err
+}
+
+func validateDHParam(data []byte, minBits int) (int, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return 0, fmt.Errorf("no valid PEM formatted block found")
+	}
+
+	// If the file does not start with 'BEGIN DH PARAMETERS' it's invalid and must not be used.
+	if block.Type != "DH PARAMETERS" {
+		return 0, fmt.Errorf("unexpected PEM block type %v", block.Type)
+	}
+
+	params := &dhParameters{}
+	_, err := asn1.Unmarshal(block.Bytes, params)
+	if err != nil {
+		return 0, fmt.Errorf("invalid DH parameters: %v", err)
+	}
+
+	if params.P == nil || params.G == nil {
+		return 0, fmt.Errorf("invalid DH parameters")
+	}
+
+	bits := params.P.BitLen()
+	if bits < minBits {
+		return bits, fmt.Errorf("DH parameters size %v is lower than the minimum (%v)", bits, minBits)
+	}
+
+	pMinusOne := new(big.Int).Sub(params.P, bigOne)
+	if params.G.Cmp(bigOne) <= 0 || params.G.Cmp(pMinusOne) >= 0 {
+		return bits, fmt.Errorf("invalid DH generator")
+	}
+
+	if !params.P.ProbablyPrime(1) {
+		return bits, fmt.Errorf("DH parameters do not contain a prime")
+	}
+
+	return bits, nil
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssl
+
+import (
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"testing"
+)
+
+func TestGenerateDHParam(t *testing.T) {
+	dh, err := GenerateDHParam(256)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	bits, err := ValidateDHParam(dh, 256)
+	if err != nil {
+		t.Fatalf("unexpected error validating generated DH parameters: %v", err)
+	}
+	if bits != 256 {
+		t.Errorf("expected DH parameters of 256 bits but returned %v", bits)
+	}
+
+	_, err = ValidateDHParam(dh, 512)
+	if err == nil {
+		t.Errorf("expected error validating DH parameters smaller than the minimum size")
+	}
+}
+
+func TestValidateDHParam(t *testing.T) {
+	// 23 = 2*11+1 is a safe prime, 29 = 2*14+1 is a prime but not a safe
+	// prime and 21 is not a prime
+	safe, _ := asn1.Marshal(dhParameters{P: big.NewInt(23), G: big.NewInt(2)})
+	dsa, _ := asn1.Marshal(dhParameters{P: big.NewInt(29), G: big.NewInt(4), PrivateValueLength: 3})
+	notPrime, _ := asn1.Marshal(dhParameters{P: big.NewInt(21), G: big.NewInt(2)})
+	badGenerator, _ := asn1.Marshal(dhParameters{P: big.NewInt(23), G: big.NewInt(22)})
+
+	testCases := map[string]struct {
+		data  []byte
+		valid bool
+	}{
+		"valid parameters":  {pem.EncodeToMemory(&pem.Block{Type: "DH PARAMETERS", Bytes: safe}), true},
+		"DSA parameters":    {pem.EncodeToMemory(&pem.Block{Type: "DH PARAMETERS", Bytes: dsa}), true},
+		"not a prime":       {pem.EncodeToMemory(&pem.Block{Type: "DH PARAMETERS", Bytes: notPrime}), false},
+		"invalid generator": {pem.EncodeToMemory(&pem.Block{Type: "DH PARAMETERS", Bytes: badGenerator}), false},
+		"invalid type":      {pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: safe}), false},
+		"invalid asn1":      {pem.EncodeToMemory(&pem.Block{Type: "DH PARAMETERS", Bytes: []byte("dh")}), false},
+		"not PEM encoded":   {[]byte("dhparam"), false},
+	}
+
+	for name, tc := range testCases {
+		// the second validation returns the cached result
+		for i := 0; i < 2; i++ {
+			_, err := ValidateDHParam(tc.data, 0)
+			if tc.valid && err != nil {
+				t.Errorf("%v: unexpected error: %v", name, err)
+			}
+			if !tc.valid && err == nil {
+				t.Errorf("%v: expected an error", name)
+			}
+		}
+	}
+}
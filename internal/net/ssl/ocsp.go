@@ -0,0 +1,358 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssl
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+// OCSP certificate status (RFC 6960, section 4.2.1)
+const (
+	OCSPGood    = 0
+	OCSPRevoked = 1
+	OCSPUnknown = 2
+)
+
+// maxOCSPResponseSize limits the size of the responses read from OCSP responders
+const maxOCSPResponseSize = 1 << 20
+
+var (
+	oidSHA1               = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidOCSPBasicResponse  = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 1}
+	oidSignatureAlgorithm = map[string]x509.SignatureAlgorithm{
+		"1.2.840.113549.1.1.5":  x509.SHA1WithRSA,
+		"1.2.840.113549.1.1.11": x509.SHA256WithRSA,
+		"1.2.840.113549.1.1.12": x509.SHA384WithRSA,
+		"1.2.840.113549.1.1.13": x509.SHA512WithRSA,
+		"1.2.840.10045.4.1":     x509.ECDSAWithSHA1,
+		"1.2.840.10045.4.3.2":   x509.ECDSAWithSHA256,
+		"1.2.840.10045.4.3.3":   x509.ECDSAWithSHA384,
+		"1.2.840.10045.4.3.4":   x509.ECDSAWithSHA512,
+	}
+
+	// oidHashAlgorithm contains the hash algorithms that can be used in the
+	// CertID of the responses
+	oidHashAlgorithm = map[string]crypto.Hash{
+		"1.3.14.3.2.26":          crypto.SHA1,
+		"2.16.840.1.101.3.4.2.1": crypto.SHA256,
+		"2.16.840.1.101.3.4.2.2": crypto.SHA384,
+		"2.16.840.1.101.3.4.2.3": crypto.SHA512,
+	}
+)
+
+// ASN.1 structures of OCSP requests and responses (RFC 6960, section 4)
+
+type ocspCertID struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	NameHash      []byte
+	IssuerKeyHash []byte
+	SerialNumber  *big.Int
+}
+
+type ocspRequest struct {
+	TBSRequest ocspTBSRequest
+}
+
+type ocspTBSRequest struct {
+	Version     int `asn1:"explicit,tag:0,default:0,optional"`
+	RequestList []ocspSingleRequest
+}
+
+type ocspSingleRequest struct {
+	Cert ocspCertID
+}
+
+type ocspResponseASN1 struct {
+	Status   asn1.Enumerated
+	Response ocspResponseBytes `asn1:"explicit,tag:0,optional"`
+}
+
+type ocspResponseBytes struct {
+	ResponseType asn1.ObjectIdentifier
+	Response     []byte
+}
+
+type ocspBasicResponse struct {
+	TBSResponseData    ocspResponseData
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          asn1.BitString
+	Certificates       []asn1.RawValue `asn1:"explicit,tag:0,optional"`
+}
+
+type ocspResponseData struct {
+	Raw            asn1.RawContent
+	Version        int `asn1:"optional,default:0,explicit,tag:0"`
+	RawResponderID asn1.RawValue
+	ProducedAt     time.Time `asn1:"generalized"`
+	Responses      []ocspSingleResponse
+}
+
+type ocspSingleResponse struct {
+	CertID     ocspCertID
+	Good       asn1.Flag       `asn1:"tag:0,optional"`
+	Revoked    ocspRevokedInfo `asn1:"tag:1,optional"`
+	Unknown    asn1.Flag       `asn1:"tag:2,optional"`
+	ThisUpdate time.Time       `asn1:"generalized"`
+	NextUpdate time.Time       `asn1:"generalized,explicit,tag:0,optional"`
+}
+
+type ocspRevokedInfo struct {
+	RevocationTime time.Time       `asn1:"generalized"`
+	Reason         asn1.Enumerated `asn1:"explicit,tag:0,optional"`
+}
+
+// OCSPResponse contains the status of a certificate returned by an OCSP responder
+type OCSPResponse struct {
+	// Status of the certificate (OCSPGood, OCSPRevoked or OCSPUnknown)
+	Status int
+	// ThisUpdate is the time at which the status was known to be correct
+	ThisUpdate time.Time
+	// NextUpdate is the time at or before which newer information will be
+	// available. A zero value indicates that newer information is always available
+	NextUpdate time.Time
+	// RevokedAt is the time of the revocation of the certificate
+	RevokedAt time.Time
+	// Raw contains the DER encoded response, the format expected by ssl_stapling_file
+	Raw []byte
+}
+
+// CreateOCSPRequest returns a DER encoded OCSP request for the status of a
+// certificate
+func CreateOCSPRequest(cert, issuer *x509.Certificate) ([]byte, error) {
+	id, err := newOCSPCertID(cert, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(ocspRequest{
+		TBSRequest: ocspTBSRequest{
+			RequestList: []ocspSingleRequest{{Cert: *id}},
+		},
+	})
+}
+
+// ParseOCSPResponse parses a DER encoded OCSP response for the certificate,
+// checking it is signed by the issuer or by a responder authorized by it
+func ParseOCSPResponse(data []byte, cert, issuer *x509.Certificate) (*OCSPResponse, error) {
+	resp := &ocspResponseASN1{}
+	rest, err := asn1.Unmarshal(data, resp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OCSP response: %v", err)
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("invalid OCSP response: trailing data")
+	}
+
+	if resp.Status != 0 {
+		return nil, fmt.Errorf("OCSP responder returned an error status (%v)", resp.Status)
+	}
+
+	if !resp.Response.ResponseType.Equal(oidOCSPBasicResponse) {
+		return nil, fmt.Errorf("unsupported OCSP response type %v", resp.Response.ResponseType)
+	}
+
+	basic := &ocspBasicResponse{}
+	_, err = asn1.Unmarshal(resp.Response.Response, basic)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OCSP basic response: %v", err)
+	}
+
+	signer, err := ocspSigner(basic, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	algorithm, ok := oidSignatureAlgorithm[basic.SignatureAlgorithm.Algorithm.String()]
+	if !ok {
+		return nil, fmt.Errorf("unsupported OCSP signature algorithm %v", basic.SignatureAlgorithm.Algorithm)
+	}
+
+	err = signer.CheckSignature(algorithm, basic.TBSResponseData.Raw, basic.Signature.RightAlign())
+	if err != nil {
+		return nil, fmt.Errorf("invalid OCSP response signature: %v", err)
+	}
+
+	for _, r := range basic.TBSResponseData.Responses {
+		if !r.CertID.matches(cert, issuer) {
+			continue
+		}
+
+		res := &OCSPResponse{
+			ThisUpdate: r.ThisUpdate,
+			NextUpdate: r.NextUpdate,
+			Raw:        data,
+		}
+
+		switch {
+		case bool(r.Good):
+			res.Status = OCSPGood
+		case bool(r.Unknown):
+			res.Status = OCSPUnknown
+		default:
+			res.Status = OCSPRevoked
+			res.RevokedAt = r.Revoked.RevocationTime
+		}
+
+		return res, nil
+	}
+
+	return nil, fmt.Errorf("the OCSP response does not contain the status of certificate %v", cert.SerialNumber)
+}
+
+// FetchOCSPResponse requests the status of a certificate to the OCSP
+// responder listed in the certificate
+func FetchOCSPResponse(cert, issuer *x509.Certificate, client *http.Client) (*OCSPResponse, error) {
+	if len(cert.OCSPServer) == 0 {
+		return nil, fmt.Errorf("certificate does not contain an OCSP responder")
+	}
+
+	req, err := CreateOCSPRequest(cert, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Post(cert.OCSPServer[0], "application/ocsp-request", bytes.NewReader(req))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OCSP responder %v returned status code %v", cert.OCSPServer[0], resp.StatusCode)
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxOCSPResponseSize))
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseOCSPResponse(data, cert, issuer)
+}
+
+// FindIssuer returns the certificate in the PEM encoded data that issued cert
+func FindIssuer(cert *x509.Certificate, data []byte) *x509.Certificate {
+	return findIssuer(cert, ParseCertificates(data))
+}
+
+// ocspSigner returns the certificate that signed the response, the issuer
+// or a delegated responder (RFC 6960, section 4.2.2.2)
+func ocspSigner(basic *ocspBasicResponse, issuer *x509.Certificate) (*x509.Certificate, error) {
+	if len(basic.Certificates) == 0 {
+		return issuer, nil
+	}
+
+	responder, err := x509.ParseCertificate(basic.Certificates[0].FullBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OCSP responder certificate: %v", err)
+	}
+
+	if responder.Equal(issuer) {
+		return issuer, nil
+	}
+
+	err = responder.CheckSignatureFrom(issuer)
+	if err != nil {
+		return nil, fmt.Errorf("OCSP responder certificate is not signed by the issuer: %v", err)
+	}
+
+	for _, usage := range responder.ExtKeyUsage {
+		if usage == x509.ExtKeyUsageOCSPSigning {
+			return responder, nil
+		}
+	}
+
+	return nil, fmt.Errorf("OCSP responder certificate is not authorized to sign OCSP responses")
+}
+
+// matches returns true if the ID identifies the certificate issued by
+// issuer. The hashes of the name and the public key of the issuer are
+// computed using the hash algorithm of the ID
+func (id *ocspCertID) matches(cert, issuer *x509.Certificate) bool {
+	if id.SerialNumber == nil || id.SerialNumber.Cmp(cert.SerialNumber) != 0 {
+		return false
+	}
+
+	hash, ok := oidHashAlgorithm[id.HashAlgorithm.Algorithm.String()]
+	if !ok || !hash.Available() {
+		return false
+	}
+
+	key, err := issuerPublicKey(issuer)
+	if err != nil {
+		return false
+	}
+
+	h := hash.New()
+	h.Write(issuer.RawSubject)
+	nameHash := h.Sum(nil)
+
+	h = hash.New()
+	h.Write(key)
+	keyHash := h.Sum(nil)
+
+	return bytes.Equal(id.NameHash, nameHash) && bytes.Equal(id.IssuerKeyHash, keyHash)
+}
+
+// issuerPublicKey returns the public key of the issuer (the value of the
+// BIT STRING subjectPublicKey) used in the CertID
+func issuerPublicKey(issuer *x509.Certificate) ([]byte, error) {
+	var spki struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	_, err := asn1.Unmarshal(issuer.RawSubjectPublicKeyInfo, &spki)
+	if err != nil {
+		return nil, fmt.Errorf("invalid issuer public key: %v", err)
+	}
+
+	return spki.PublicKey.RightAlign(), nil
+}
+
+func newOCSPCertID(cert, issuer *x509.Certificate) (*ocspCertID, error) {
+	key, err := issuerPublicKey(issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	nameHash := sha1.Sum(issuer.RawSubject)
+	keyHash := sha1.Sum(key)
+
+	return &ocspCertID{
+		HashAlgorithm: pkix.AlgorithmIdentifier{
+			Algorithm:  oidSHA1,
+			Parameters: asn1.RawValue{Tag: asn1.TagNull},
+		},
+		NameHash:      nameHash[:],
+		IssuerKeyHash: keyHash[:],
+		SerialNumber:  cert.SerialNumber,
+	}, nil
+}
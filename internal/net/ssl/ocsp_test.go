@@ -0,0 +1,311 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssl
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var oidECDSAWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+
+type testCertificate struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCertificate(t *testing.T, tmpl *x509.Certificate, parent *testCertificate) *testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+
+	parentCert, parentKey := tmpl, key
+	if parent != nil {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parentCert, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cert, _ := x509.ParseCertificate(der)
+	return &testCertificate{cert: cert, key: key}
+}
+
+// newOCSPResponse creates a response for the certificate ID of the request
+// signed by signer (including the signer certificate when it is not the issuer)
+func newOCSPResponse(t *testing.T, id ocspCertID, status int, signer *testCertificate, includeSigner bool) []byte {
+	single := ocspSingleResponse{
+		CertID:     id,
+		ThisUpdate: time.Now().Add(-time.Minute).UTC().Truncate(time.Second),
+		NextUpdate: time.Now().Add(time.Hour).UTC().Truncate(time.Second),
+	}
+	switch status {
+	case OCSPGood:
+		single.Good = true
+	case OCSPUnknown:
+		single.Unknown = true
+	default:
+		single.Revoked = ocspRevokedInfo{RevocationTime: time.Now().Add(-time.Minute).UTC().Truncate(time.Second)}
+	}
+
+	keyHash := sha256.Sum256(signer.cert.RawSubjectPublicKeyInfo)
+	responderID, _ := asn1.Marshal(keyHash[:20])
+
+	tbs, err := asn1.Marshal(ocspResponseData{
+		RawResponderID: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 2, IsCompound: true, Bytes: responderID},
+		ProducedAt:     time.Now().UTC().Truncate(time.Second),
+		Responses:      []ocspSingleResponse{single},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	hash := sha256.Sum256(tbs)
+	signature, err := signer.key.Sign(rand.Reader, hash[:], crypto.SHA256)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	basic := ocspBasicResponse{
+		TBSResponseData:    ocspResponseData{Raw: tbs},
+		SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidECDSAWithSHA256},
+		Signature:          asn1.BitString{Bytes: signature, BitLength: 8 * len(signature)},
+	}
+	if includeSigner {
+		basic.Certificates = []asn1.RawValue{{FullBytes: signer.cert.Raw}}
+	}
+
+	basicDER, err := asn1.Marshal(basic)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resp, err := asn1.Marshal(ocspResponseASN1{
+		Response: ocspResponseBytes{ResponseType: oidOCSPBasicResponse, Response: basicDER},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return resp
+}
+
+func TestFetchOCSPResponse(t *testing.T) {
+	ca := newTestCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil)
+
+	responder := newTestCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "test OCSP responder"},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning},
+	}, ca)
+
+	unauthorized := newTestCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "test server"},
+	}, ca)
+
+	var status int
+	var signer *testCertificate
+	var includeSigner bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		req := &ocspRequest{}
+		_, err := asn1.Unmarshal(body, req)
+		if err != nil || r.Header.Get("Content-Type") != "application/ocsp-request" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.Write(newOCSPResponse(t, req.TBSRequest.RequestList[0].Cert, status, signer, includeSigner))
+	}))
+	defer srv.Close()
+
+	leaf := newTestCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(4),
+		Subject:      pkix.Name{CommonName: "foo.bar"},
+		DNSNames:     []string{"foo.bar"},
+		OCSPServer:   []string{srv.URL},
+	}, ca)
+
+	testCases := []struct {
+		title         string
+		status        int
+		signer        *testCertificate
+		includeSigner bool
+		err           bool
+	}{
+		{"good status signed by the issuer", OCSPGood, ca, false, false},
+		{"revoked status signed by the issuer", OCSPRevoked, ca, true, false},
+		{"unknown status signed by a delegated responder", OCSPUnknown, responder, true, false},
+		{"response signed by an unauthorized certificate", OCSPGood, unauthorized, true, true},
+		{"response signed by an unknown key", OCSPGood, responder, false, true},
+	}
+
+	for _, tc := range testCases {
+		status, signer, includeSigner = tc.status, tc.signer, tc.includeSigner
+
+		resp, err := FetchOCSPResponse(leaf.cert, ca.cert, nil)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%v: expected an error but none returned", tc.title)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tc.title, err)
+			continue
+		}
+
+		if resp.Status != tc.status {
+			t.Errorf("%v: expected status %v but %v returned", tc.title, tc.status, resp.Status)
+		}
+		if resp.NextUpdate.Before(resp.ThisUpdate) || resp.ThisUpdate.IsZero() {
+			t.Errorf("%v: unexpected validity %v - %v", tc.title, resp.ThisUpdate, resp.NextUpdate)
+		}
+		if tc.status == OCSPRevoked && resp.RevokedAt.IsZero() {
+			t.Errorf("%v: expected a revocation time", tc.title)
+		}
+
+		// the raw response must be parsed again (ssl_stapling_file)
+		_, err = ParseOCSPResponse(resp.Raw, leaf.cert, ca.cert)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tc.title, err)
+		}
+	}
+
+	// the response does not contain the status of other certificates
+	status, signer, includeSigner = OCSPGood, ca, false
+	resp, _ := FetchOCSPResponse(leaf.cert, ca.cert, nil)
+	_, err := ParseOCSPResponse(resp.Raw, unauthorized.cert, ca.cert)
+	if err == nil {
+		t.Errorf("expected an error but none returned")
+	}
+
+	_, err = FetchOCSPResponse(ca.cert, ca.cert, nil)
+	if err == nil {
+		t.Errorf("expected an error for a certificate without OCSP responder but none returned")
+	}
+}
+
+func TestFindIssuer(t *testing.T) {
+	ca := newTestCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil)
+
+	leaf := newTestCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "foo.bar"},
+	}, ca)
+
+	leafPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf.cert.Raw})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: []byte("key")})
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw})
+
+	if FindIssuer(leaf.cert, append(leafPEM, keyPEM...)) != nil {
+		t.Errorf("expected no issuer")
+	}
+
+	issuer := FindIssuer(leaf.cert, append(append(leafPEM, keyPEM...), caPEM...))
+	if issuer == nil || !issuer.Equal(ca.cert) {
+		t.Errorf("expected the CA as issuer but %v returned", issuer)
+	}
+}
+
+func TestOCSPCertIDMatches(t *testing.T) {
+	newCA := func(name string) *testCertificate {
+		return newTestCertificate(t, &x509.Certificate{
+			SerialNumber:          big.NewInt(1),
+			Subject:               pkix.Name{CommonName: name},
+			IsCA:                  true,
+			BasicConstraintsValid: true,
+			KeyUsage:              x509.KeyUsageCertSign,
+		}, nil)
+	}
+	ca, other := newCA("test CA"), newCA("other CA")
+
+	// both certificates use the same serial number
+	leaf := newTestCertificate(t, &x509.Certificate{SerialNumber: big.NewInt(2)}, ca)
+	otherLeaf := newTestCertificate(t, &x509.Certificate{SerialNumber: big.NewInt(2)}, other)
+
+	id, err := newOCSPCertID(leaf.cert, ca.cert)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	key, _ := issuerPublicKey(ca.cert)
+	nameHash := sha256.Sum256(ca.cert.RawSubject)
+	keyHash := sha256.Sum256(key)
+	sha256ID := &ocspCertID{
+		HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}},
+		NameHash:      nameHash[:],
+		IssuerKeyHash: keyHash[:],
+		SerialNumber:  leaf.cert.SerialNumber,
+	}
+
+	unknownID := *sha256ID
+	unknownID.HashAlgorithm = pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 3}}
+
+	testCases := []struct {
+		title   string
+		id      *ocspCertID
+		cert    *x509.Certificate
+		issuer  *x509.Certificate
+		matches bool
+	}{
+		{"SHA-1 ID of the certificate", id, leaf.cert, ca.cert, true},
+		{"SHA-1 ID of a different issuer", id, otherLeaf.cert, other.cert, false},
+		{"SHA-256 ID of the certificate", sha256ID, leaf.cert, ca.cert, true},
+		{"SHA-256 ID of a different issuer", sha256ID, otherLeaf.cert, other.cert, false},
+		{"unknown hash algorithm", &unknownID, leaf.cert, ca.cert, false},
+	}
+
+	for _, tc := range testCases {
+		if tc.id.matches(tc.cert, tc.issuer) != tc.matches {
+			t.Errorf("%v: expected %v", tc.title, tc.matches)
+		}
+	}
+}
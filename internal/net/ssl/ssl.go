@@ -17,6 +17,7 @@ limitations under the License.
 package ssl
 
 import (
+	"bytes"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/tls"
@@ -169,6 +170,7 @@ func AddOrUpdateCertAndKey(name string, cert, key, ca []byte,
 			PemSHA:      file.SHA1(pemFileName),
 			CN:          cn.List(),
 			ExpireTime:  pemCert.NotAfter,
+			KeyType:     pemCert.PublicKeyAlgorithm.String(),
 		}, nil
 	}
 
@@ -178,6 +180,7 @@ func AddOrUpdateCertAndKey(name string, cert, key, ca []byte,
 		PemSHA:      file.SHA1(pemFileName),
 		CN:          cn.List(),
 		ExpireTime:  pemCert.NotAfter,
+		KeyType:     pemCert.PublicKeyAlgorithm.String(),
 	}
 
 	return s, nil
@@ -250,8 +253,9 @@ func parseSANExtension(value []byte) (dnsNames, emailAddresses []string, ipAddre
 }
 
 // AddCertAuth creates a .pem file with the specified CAs to be used in Cert Authentication
+// and a .crl file with the certificate revocation lists (if any).
 // If it's already exists, it's clobbered.
-func AddCertAuth(name string, ca []byte, fs file.Filesystem) (*ingress.SSLCert, error) {
+func AddCertAuth(name string, ca, crl []byte, fs file.Filesystem) (*ingress.SSLCert, error) {
 
 	caName := fmt.Sprintf("ca-%v.pem", name)
 	caFileName := fmt.Sprintf("%v/%v", file.DefaultSSLDirectory, caName)
@@ -282,14 +286,24 @@ func AddCertAuth(name string, ca []byte, fs file.Filesystem) (*ingress.SSLCert,
 	}
 
 	glog.V(3).Infof("Created CA Certificate for Authentication: %v", caFileName)
-	return &ingress.SSLCert{
+	sslCert := &ingress.SSLCert{
 		CAFileName:  caFileName,
 		PemFileName: caFileName,
 		PemSHA:      file.SHA1(caFileName),
-	}, nil
+	}
+
+	if len(crl) > 0 {
+		err = AddCRL(name, crl, ca, sslCert, fs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sslCert, nil
 }
 
 // AddOrUpdateDHParam creates a dh parameters file with the specified name
+// after checking the parameters are valid
 func AddOrUpdateDHParam(name string, dh []byte, fs file.Filesystem) (string, error) {
 	pemName := fmt.Sprintf("%v.pem", name)
 	pemFileName := fmt.Sprintf("%v/%v", file.DefaultSSLDirectory, pemName)
@@ -318,14 +332,9 @@ func AddOrUpdateDHParam(name string, dh []byte, fs file.Filesystem) (string, err
 		return "", err
 	}
 
-	pemBlock, _ := pem.Decode(pemCerts)
-	if pemBlock == nil {
-		return "", fmt.Errorf("no valid PEM formatted block found")
-	}
-
-	// If the file does not start with 'BEGIN DH PARAMETERS' it's invalid and must not be used.
-	if pemBlock.Type != "DH PARAMETERS" {
-		return "", fmt.Errorf("certificate %v contains invalid data", name)
+	_, err = ValidateDHParam(pemCerts, MinDHParamSize)
+	if err != nil {
+		return "", fmt.Errorf("DH parameters %v contain invalid data: %v", name, err)
 	}
 
 	err = fs.Rename(tempPemFile.Name(), pemFileName)
@@ -386,10 +395,16 @@ func GetFakeSSLCert() ([]byte, []byte) {
 	return cert, key
 }
 
+// maxChainLength is the maximum number of certificates in a chain built
+// using an intermediate bundle
+const maxChainLength = 10
+
 // FullChainCert checks if a certificate file contains issues in the intermediate CA chain
 // Returns a new certificate with the intermediate certificates.
+// The chain is built using the certificates of the bundle (if any) before
+// fetching the missing intermediate certificates using the AIA extension.
 // If the certificate does not contains issues with the chain it return an empty byte array
-func FullChainCert(in string, fs file.Filesystem) ([]byte, error) {
+func FullChainCert(in string, bundle []*x509.Certificate, fs file.Filesystem) ([]byte, error) {
 	data, err := fs.ReadFile(in)
 	if err != nil {
 		return nil, err
@@ -410,8 +425,20 @@ func FullChainCert(in string, fs file.Filesystem) ([]byte, error) {
 		return nil, nil
 	}
 
+	var bundleErr error
+	if len(bundle) > 0 {
+		var chain []*x509.Certificate
+		chain, bundleErr = chainFromBundle(cert, bundle)
+		if bundleErr == nil {
+			return certUtil.EncodeCertificates(chain), nil
+		}
+	}
+
 	certs, err := certUtil.FetchCertificateChain(cert)
 	if err != nil {
+		if bundleErr != nil {
+			return nil, fmt.Errorf("%v (fetching the intermediate certificates failed: %v)", bundleErr, err)
+		}
 		return nil, err
 	}
 
@@ -422,3 +449,81 @@ func FullChainCert(in string, fs file.Filesystem) ([]byte, error) {
 
 	return certUtil.EncodeCertificates(certs), nil
 }
+
+// chainFromBundle returns the chain of a certificate using the issuers
+// found in the bundle. The chain is complete when it ends with a self signed
+// certificate of the bundle or a certificate issued by a trusted root CA.
+func chainFromBundle(cert *x509.Certificate, bundle []*x509.Certificate) ([]*x509.Certificate, error) {
+	chain := []*x509.Certificate{cert}
+	intermediates := x509.NewCertPool()
+
+	current := cert
+	for len(chain) <= maxChainLength {
+		if isSelfSigned(current) {
+			return chain, nil
+		}
+
+		issuer := findIssuer(current, bundle)
+		if issuer == nil {
+			_, err := cert.Verify(x509.VerifyOptions{
+				Intermediates: intermediates,
+			})
+			if err == nil {
+				return chain, nil
+			}
+
+			return nil, fmt.Errorf("the issuer (%v) of the certificate %v is not present in the intermediate bundle",
+				current.Issuer.CommonName, current.Subject.CommonName)
+		}
+
+		chain = append(chain, issuer)
+		intermediates.AddCert(issuer)
+		current = issuer
+	}
+
+	return nil, fmt.Errorf("the chain of the certificate %v contains more than %v certificates", cert.Subject.CommonName, maxChainLength)
+}
+
+func findIssuer(cert *x509.Certificate, certs []*x509.Certificate) *x509.Certificate {
+	for _, c := range certs {
+		if c.Equal(cert) {
+			continue
+		}
+
+		if bytes.Equal(c.RawSubject, cert.RawIssuer) && cert.CheckSignatureFrom(c) == nil {
+			return c
+		}
+	}
+
+	return nil
+}
+
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawSubject, cert.RawIssuer) && cert.CheckSignatureFrom(cert) == nil
+}
+
+// ParseCertificates returns the certificates contained in PEM encoded data.
+// Other PEM blocks and invalid certificates are ignored.
+func ParseCertificates(data []byte) []*x509.Certificate {
+	var certs []*x509.Certificate
+	for len(data) > 0 {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			continue
+		}
+
+		certs = append(certs, c)
+	}
+
+	return certs
+}
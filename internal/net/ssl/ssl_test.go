@@ -18,7 +18,11 @@ package ssl
 
 import (
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"fmt"
+	"math/big"
+	"strings"
 	"testing"
 	"time"
 
@@ -131,7 +135,7 @@ func TestAddCertAuth(t *testing.T) {
 		t.Fatalf("unexpected error creating SSL certificate: %v", err)
 	}
 	c := certutil.EncodeCertPEM(ca.Cert)
-	ic, err := AddCertAuth(cn, c, fs)
+	ic, err := AddCertAuth(cn, c, nil, fs)
 	if err != nil {
 		t.Fatalf("unexpected error creating SSL certificate: %v", err)
 	}
@@ -147,3 +151,63 @@ func newFS(t *testing.T) file.Filesystem {
 	}
 	return fs
 }
+
+func TestFullChainCertWithBundle(t *testing.T) {
+	root := newTestCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "offline root CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil)
+
+	intermediate := newTestCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: "offline intermediate CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, root)
+
+	leaf := newTestCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "foo.bar"},
+		DNSNames:     []string{"foo.bar"},
+	}, intermediate)
+
+	encode := func(certs ...*testCertificate) []byte {
+		var data []byte
+		for _, c := range certs {
+			data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw})...)
+		}
+		return data
+	}
+
+	fs := newFS(t)
+	f, err := fs.Create("/etc/ingress-controller/ssl/default-foo.pem")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f.Write(encode(leaf))
+	f.Close()
+
+	bundle := ParseCertificates(append(encode(intermediate, root), []byte("invalid")...))
+	if len(bundle) != 2 {
+		t.Fatalf("expected 2 certificates in the bundle but %v returned", len(bundle))
+	}
+
+	data, err := FullChainCert("/etc/ingress-controller/ssl/default-foo.pem", bundle, fs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	chain := ParseCertificates(data)
+	if len(chain) != 3 || !chain[0].Equal(leaf.cert) || !chain[1].Equal(intermediate.cert) || !chain[2].Equal(root.cert) {
+		t.Errorf("unexpected chain %v", chain)
+	}
+
+	_, err = chainFromBundle(leaf.cert, []*x509.Certificate{root.cert})
+	if err == nil || !strings.Contains(err.Error(), "offline intermediate CA") {
+		t.Errorf("expected an error about the missing intermediate certificate but %v returned", err)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"path"
+	"sort"
+	"sync"
+	"time"
+)
+
+// debouncer groups the changes of the files of a directory received
+// before a period without changes
+type debouncer struct {
+	mu    sync.Mutex
+	delay time.Duration
+	timer *time.Timer
+	files map[string]bool
+	// onEvent callback to be invoked with the changed files
+	onEvent func(files []string)
+}
+
+// add registers a changed file and restarts the period without changes
+func (d *debouncer) add(file string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.files[file] = true
+	if d.timer == nil {
+		d.timer = time.AfterFunc(d.delay, d.flush)
+		return
+	}
+	d.timer.Reset(d.delay)
+}
+
+// flush invokes the callback with the files changed since the last call
+func (d *debouncer) flush() {
+	d.mu.Lock()
+	files := []string{}
+	for file := range d.files {
+		files = append(files, file)
+	}
+	d.files = map[string]bool{}
+	d.timer = nil
+	d.mu.Unlock()
+
+	if len(files) == 0 {
+		return
+	}
+
+	sort.Strings(files)
+	d.onEvent(files)
+}
+
+// NewDirectoryWatcher creates a new FileWatcher of the files of a
+// directory. Files created or replaced in the directory after the watcher
+// is created are also watched. The callback is invoked with the names of
+// the changed files once no changes are received during delay
+func NewDirectoryWatcher(dir string, delay time.Duration, onEvent func(files []string)) (FileWatcher, error) {
+	d := &debouncer{
+		delay:   delay,
+		files:   map[string]bool{},
+		onEvent: onEvent,
+	}
+
+	fw := OSFileWatcher{
+		// an empty file name matches all the files of the directory
+		file:        path.Clean(dir) + "/",
+		onFileEvent: d.add,
+	}
+	err := fw.watch()
+	return fw, err
+}
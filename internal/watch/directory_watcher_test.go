@@ -0,0 +1,73 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDirectoryWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dw")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	events := make(chan []string, 10)
+	fw, err := NewDirectoryWatcher(dir, 100*time.Millisecond, func(files []string) {
+		events <- files
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer fw.Close()
+
+	timeoutChan := prepareTimeout()
+	select {
+	case <-events:
+		t.Fatalf("expected no events before writing a file")
+	case <-timeoutChan:
+	}
+
+	// the changes of new files are grouped in a single event
+	ioutil.WriteFile(filepath.Join(dir, "b.dat"), []byte{}, 0644)
+	ioutil.WriteFile(filepath.Join(dir, "a.dat"), []byte{}, 0644)
+	ioutil.WriteFile(filepath.Join(dir, "b.dat"), []byte{1}, 0644)
+
+	expected := []string{filepath.Join(dir, "a.dat"), filepath.Join(dir, "b.dat")}
+	timeoutChan = prepareTimeout()
+	select {
+	case files := <-events:
+		if !reflect.DeepEqual(files, expected) {
+			t.Fatalf("expected %v but returned %v", expected, files)
+		}
+	case <-timeoutChan:
+		t.Fatalf("expected an event shortly after writing the files")
+	}
+
+	timeoutChan = prepareTimeout()
+	select {
+	case files := <-events:
+		t.Fatalf("expected a single event but returned %v", files)
+	case <-timeoutChan:
+	}
+}
@@ -35,6 +35,9 @@ type OSFileWatcher struct {
 	watcher *fsnotify.Watcher
 	// onEvent callback to be invoked after the file being watched changes
 	onEvent func()
+	// onFileEvent callback to be invoked with the name of the changed file.
+	// When set it is used instead of onEvent
+	onFileEvent func(name string)
 }
 
 // NewFileWatcher creates a new FileWatcher
@@ -64,13 +67,23 @@ func (f *OSFileWatcher) watch() error {
 	go func(file string) {
 		for {
 			select {
-			case event := <-watcher.Events:
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
 				if (event.Op&fsnotify.Write == fsnotify.Write ||
 					event.Op&fsnotify.Create == fsnotify.Create) &&
 					strings.HasSuffix(event.Name, file) {
-					f.onEvent()
+					if f.onFileEvent != nil {
+						f.onFileEvent(event.Name)
+					} else {
+						f.onEvent()
+					}
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
 				}
-			case err := <-watcher.Errors:
 				if err != nil {
 					log.Printf("error watching file: %v\n", err)
 				}
@@ -3,10 +3,22 @@ local configuration_data = ngx.shared.configuration_data
 
 local _M = {}
 
+-- keys of the shared dictionary indexed by the path of the endpoints
+local endpoints = {
+  ["/configuration/backends"] = "backends",
+  ["/configuration/jwt-keys"] = "jwt_keys",
+}
+
 function _M.get_backends_data()
   return configuration_data:get("backends")
 end
 
+-- returns the public keys used to validate JWT bearer tokens indexed by the
+-- name of the Secret or ConfigMap that contains them (JSON encoded)
+function _M.get_jwt_keys_data()
+  return configuration_data:get("jwt_keys")
+end
+
 function _M.call()
   if ngx.var.request_method ~= "POST" and ngx.var.request_method ~= "GET" then
     ngx.status = ngx.HTTP_BAD_REQUEST
@@ -14,7 +26,8 @@ function _M.call()
     return
   end
 
-  if ngx.var.request_uri ~= "/configuration/backends" then
+  local key = endpoints[ngx.var.request_uri]
+  if not key then
     ngx.status = ngx.HTTP_NOT_FOUND
     ngx.print("Not found!")
     return
@@ -22,13 +35,13 @@ function _M.call()
 
   if ngx.var.request_method == "GET" then
     ngx.status = ngx.HTTP_OK
-    ngx.print(_M.get_backends_data())
+    ngx.print(configuration_data:get(key))
     return
   end
 
   ngx.req.read_body()
 
-  local success, err = configuration_data:set("backends", ngx.req.get_body_data())
+  local success, err = configuration_data:set(key, ngx.req.get_body_data())
   if not success then
     ngx.log(ngx.ERR, "error while saving configuration: " .. tostring(err))
     ngx.status = ngx.HTTP_BAD_REQUEST
@@ -0,0 +1,57 @@
+local json = require("cjson")
+
+local stats = ngx.shared.deny_stats
+
+local _M = {}
+
+-- names of the rules indexed by the values of the variables of the deny
+-- lists. Variables with values not present in the table use the value
+-- as name of the rule
+local rules = {}
+
+-- sets the names of the rules of the global deny lists. The names are a
+-- base64 encoded JSON object
+function _M.init(encoded)
+  local decoded = ngx.decode_base64(encoded)
+  if not decoded then
+    return
+  end
+
+  local ok, names = pcall(json.decode, decoded)
+  if ok and type(names) == "table" then
+    rules = names
+  end
+end
+
+-- denies the request when the value of one of the variables of the deny
+-- lists is not empty. The denied requests are counted by rule
+function _M.check(...)
+  for i = 1, select("#", ...) do
+    local value = select(i, ...)
+    if value and value ~= "" then
+      local rule = rules[value] or value
+
+      local _, err = stats:incr(rule, 1, 0)
+      if err then
+        ngx.log(ngx.ERR, "error counting request denied by rule ", rule, ": ", err)
+      end
+      ngx.log(ngx.INFO, "request from ", ngx.var.remote_addr, " denied by rule ", rule)
+
+      return ngx.exit(ngx.HTTP_FORBIDDEN)
+    end
+  end
+end
+
+-- returns the number of denied requests of each rule in JSON format:
+-- { "<rule>": <count> }
+function _M.status()
+  local counters = {}
+  for _, key in ipairs(stats:get_keys(0)) do
+    counters[key] = stats:get(key) or 0
+  end
+
+  ngx.header.content_type = "application/json"
+  ngx.print(json.encode(counters))
+end
+
+return _M
@@ -0,0 +1,454 @@
+local ffi = require("ffi")
+local json = require("cjson")
+local configuration = require("configuration")
+
+local C = ffi.C
+
+ffi.cdef[[
+typedef struct bio_st BIO;
+typedef struct engine_st ENGINE;
+typedef struct evp_md_st EVP_MD;
+typedef struct evp_md_ctx_st EVP_MD_CTX;
+typedef struct evp_pkey_st EVP_PKEY;
+typedef struct evp_pkey_ctx_st EVP_PKEY_CTX;
+
+BIO *BIO_new_mem_buf(const void *buf, int len);
+int BIO_free(BIO *a);
+
+EVP_PKEY *PEM_read_bio_PUBKEY(BIO *bp, EVP_PKEY **x, void *cb, void *u);
+void EVP_PKEY_free(EVP_PKEY *pkey);
+
+const EVP_MD *EVP_sha256(void);
+const EVP_MD *EVP_sha384(void);
+const EVP_MD *EVP_sha512(void);
+
+EVP_MD_CTX *EVP_MD_CTX_new(void);
+void EVP_MD_CTX_free(EVP_MD_CTX *ctx);
+EVP_MD_CTX *EVP_MD_CTX_create(void);
+void EVP_MD_CTX_destroy(EVP_MD_CTX *ctx);
+
+int EVP_DigestVerifyInit(EVP_MD_CTX *ctx, EVP_PKEY_CTX **pctx, const EVP_MD *type, ENGINE *e, EVP_PKEY *pkey);
+int EVP_DigestUpdate(EVP_MD_CTX *ctx, const void *d, size_t cnt);
+int EVP_DigestVerifyFinal(EVP_MD_CTX *ctx, const unsigned char *sig, size_t siglen);
+int EVP_PKEY_CTX_ctrl(EVP_PKEY_CTX *ctx, int keytype, int optype, int cmd, int p1, void *p2);
+]]
+
+-- OpenSSL constants used to configure the RSASSA-PSS signatures
+local EVP_PKEY_RSA = 6
+local EVP_PKEY_OP_SIGN_VERIFY = 24
+local EVP_PKEY_CTRL_RSA_PADDING = 0x1001
+local EVP_PKEY_CTRL_RSA_PSS_SALTLEN = 0x1002
+local RSA_PKCS1_PSS_PADDING = 6
+local RSA_PSS_SALTLEN_DIGEST = -1
+
+-- EVP_MD_CTX_new replaces EVP_MD_CTX_create in OpenSSL 1.1
+local md_ctx_new, md_ctx_free
+if pcall(function() return C.EVP_MD_CTX_new end) then
+  md_ctx_new, md_ctx_free = C.EVP_MD_CTX_new, C.EVP_MD_CTX_free
+else
+  md_ctx_new, md_ctx_free = C.EVP_MD_CTX_create, C.EVP_MD_CTX_destroy
+end
+
+-- accepted signature algorithms. Unsigned tokens and HMAC signatures are
+-- rejected. size is the length of the integers of ECDSA signatures
+local algorithms = {
+  RS256 = { kty = "RSA", md = C.EVP_sha256 },
+  RS384 = { kty = "RSA", md = C.EVP_sha384 },
+  RS512 = { kty = "RSA", md = C.EVP_sha512 },
+  PS256 = { kty = "RSA", md = C.EVP_sha256, pss = true },
+  PS384 = { kty = "RSA", md = C.EVP_sha384, pss = true },
+  PS512 = { kty = "RSA", md = C.EVP_sha512, pss = true },
+  ES256 = { kty = "EC", md = C.EVP_sha256, size = 32 },
+  ES384 = { kty = "EC", md = C.EVP_sha384, size = 48 },
+  ES512 = { kty = "EC", md = C.EVP_sha512, size = 66 },
+}
+
+local _M = {}
+
+-- public keys of the workers indexed by the name of the Secret or ConfigMap
+-- and the JSON encoded keys used to build them
+local keys = {}
+local keys_data
+
+-- decoded configurations of the locations indexed by the encoded value
+local configurations = {}
+
+-- decodes a base64url encoded segment of a token
+local function decode_segment(segment)
+  segment = segment:gsub("%-", "+"):gsub("_", "/")
+  local padding = #segment % 4
+  if padding > 0 then
+    segment = segment .. string.rep("=", 4 - padding)
+  end
+
+  return ngx.decode_base64(segment)
+end
+
+-- returns the token of an Authorization header (RFC 6750) or nil
+local function bearer_token(authorization)
+  if not authorization then
+    return nil
+  end
+
+  return authorization:match("^%s*[Bb][Ee][Aa][Rr][Ee][Rr]%s+([^%s]+)%s*$")
+end
+
+-- decodes a segment of a token that contains a JSON object
+local function decode_object(segment)
+  local decoded = decode_segment(segment)
+  if not decoded then
+    return nil
+  end
+
+  local ok, object = pcall(json.decode, decoded)
+  if not ok or type(object) ~= "table" then
+    return nil
+  end
+
+  return object
+end
+
+-- loads a PEM encoded public key
+local function load_key(pem)
+  local bio = C.BIO_new_mem_buf(pem, #pem)
+  if bio == nil then
+    return nil
+  end
+
+  local pkey = C.PEM_read_bio_PUBKEY(bio, nil, nil, nil)
+  C.BIO_free(bio)
+  if pkey == nil then
+    return nil
+  end
+
+  return ffi.gc(pkey, C.EVP_PKEY_free)
+end
+
+-- returns the public keys of a Secret or ConfigMap. The keys are loaded
+-- again when the controller sends new keys
+local function get_keys(name)
+  local data = configuration.get_jwt_keys_data()
+  if data ~= keys_data then
+    local ok, decoded = pcall(json.decode, data or "{}")
+    if not ok or type(decoded) ~= "table" then
+      ngx.log(ngx.ERR, "invalid JWT keys: ", tostring(decoded))
+      decoded = {}
+    end
+
+    keys = {}
+    for secret, list in pairs(decoded) do
+      keys[secret] = {}
+      for _, k in ipairs(list) do
+        local pkey = load_key(k.pem)
+        if pkey then
+          table.insert(keys[secret], { kid = k.kid, kty = k.kty, pkey = pkey })
+        else
+          ngx.log(ngx.ERR, "invalid JWT key ", k.kid, " in ", secret)
+        end
+      end
+    end
+
+    keys_data = data
+  end
+
+  return keys[name]
+end
+
+-- encodes the length of an ASN.1 element
+local function der_length(length)
+  if length < 128 then
+    return string.char(length)
+  end
+
+  return string.char(0x81, length)
+end
+
+-- encodes an unsigned big-endian integer as ASN.1 INTEGER
+local function der_integer(bytes)
+  bytes = bytes:gsub("^%z+", "")
+  if bytes == "" or bytes:byte(1) >= 0x80 then
+    bytes = "\0" .. bytes
+  end
+
+  return "\2" .. der_length(#bytes) .. bytes
+end
+
+-- converts the signature of ECDSA tokens (R || S) to the DER encoding
+-- required by OpenSSL
+local function ecdsa_signature(signature, size)
+  if #signature ~= 2 * size then
+    return nil
+  end
+
+  local sequence = der_integer(signature:sub(1, size)) .. der_integer(signature:sub(size + 1))
+  return "\48" .. der_length(#sequence) .. sequence
+end
+
+-- verifies the signature of the input using a public key
+local function verify(alg, pkey, input, signature)
+  local ctx = md_ctx_new()
+  if ctx == nil then
+    return false
+  end
+
+  local pctx = ffi.new("EVP_PKEY_CTX*[1]")
+  local ok = C.EVP_DigestVerifyInit(ctx, pctx, alg.md(), nil, pkey) == 1
+  if ok and alg.pss then
+    ok = C.EVP_PKEY_CTX_ctrl(pctx[0], EVP_PKEY_RSA, -1,
+      EVP_PKEY_CTRL_RSA_PADDING, RSA_PKCS1_PSS_PADDING, nil) > 0 and
+      C.EVP_PKEY_CTX_ctrl(pctx[0], EVP_PKEY_RSA, EVP_PKEY_OP_SIGN_VERIFY,
+      EVP_PKEY_CTRL_RSA_PSS_SALTLEN, RSA_PSS_SALTLEN_DIGEST, nil) > 0
+  end
+  if ok then
+    ok = C.EVP_DigestUpdate(ctx, input, #input) == 1
+  end
+  if ok then
+    ok = C.EVP_DigestVerifyFinal(ctx, signature, #signature) == 1
+  end
+
+  md_ctx_free(ctx)
+  return ok
+end
+
+-- returns the keys that can verify a token signed with the algorithm alg.
+-- When the token contains a key ID only the key with that ID is returned
+-- if it exists in the set
+local function candidates(set, kid, alg)
+  for _, k in ipairs(set) do
+    if kid and k.kid == kid and k.kty == alg.kty then
+      return { k }
+    end
+  end
+
+  local result = {}
+  for _, k in ipairs(set) do
+    if k.kty == alg.kty then
+      table.insert(result, k)
+    end
+  end
+
+  return result
+end
+
+-- checks the claim aud, a string or an array of strings, contains one of
+-- the accepted audiences
+local function verify_audience(aud, audience)
+  local values = aud
+  if type(aud) ~= "table" then
+    values = { aud }
+  end
+
+  for _, v in ipairs(values) do
+    for _, a in ipairs(audience) do
+      if v == a then
+        return true
+      end
+    end
+  end
+
+  return false
+end
+
+-- verifies the signature of a token and checks the claims exp, nbf, iss and
+-- aud. Returns the claims of valid tokens or nil and the error
+local function validate_token(token, cfg, set)
+  local header_segment, payload_segment, signature_segment = token:match("^([^.]+)%.([^.]+)%.([^.]+)$")
+  if not header_segment then
+    return nil, "malformed token"
+  end
+
+  local header = decode_object(header_segment)
+  if not header then
+    return nil, "malformed token header"
+  end
+
+  local alg = algorithms[header.alg]
+  if not alg then
+    return nil, "unsupported signature algorithm"
+  end
+
+  local signature = decode_segment(signature_segment)
+  if signature and alg.size then
+    signature = ecdsa_signature(signature, alg.size)
+  end
+  if not signature then
+    return nil, "invalid signature"
+  end
+
+  local kid = header.kid
+  if type(kid) ~= "string" then
+    kid = nil
+  end
+
+  local keys_found = candidates(set, kid, alg)
+  if #keys_found == 0 then
+    return nil, "no key found to verify the signature"
+  end
+
+  local input = header_segment .. "." .. payload_segment
+  local valid = false
+  for _, k in ipairs(keys_found) do
+    if verify(alg, k.pkey, input, signature) then
+      valid = true
+      break
+    end
+  end
+  if not valid then
+    return nil, "invalid signature"
+  end
+
+  local claims = decode_object(payload_segment)
+  if not claims then
+    return nil, "malformed token claims"
+  end
+
+  local now = ngx.time()
+  if type(claims.exp) ~= "number" or now > claims.exp then
+    return nil, "the token is expired or does not contain exp"
+  end
+  if claims.nbf ~= nil and (type(claims.nbf) ~= "number" or now < claims.nbf) then
+    return nil, "the token is not valid yet"
+  end
+
+  if cfg.issuer and cfg.issuer ~= "" and claims.iss ~= cfg.issuer then
+    return nil, "invalid issuer"
+  end
+
+  if cfg.audience and #cfg.audience > 0 and not verify_audience(claims.aud, cfg.audience) then
+    return nil, "invalid audience"
+  end
+
+  return claims
+end
+
+-- returns the value of a claim formatted to be sent in a header. Strings
+-- are returned as is and other types JSON encoded
+local function claim_value(value)
+  local t = type(value)
+  if t == "string" then
+    return value
+  elseif t == "number" or t == "boolean" then
+    return tostring(value)
+  elseif t == "table" then
+    local ok, encoded = pcall(json.encode, value)
+    if ok then
+      return encoded
+    end
+  end
+
+  return nil
+end
+
+-- returns the value of the WWW-Authenticate header of rejected requests
+-- (RFC 6750). Requests without token do not contain an error
+local function challenge(realm, err)
+  local quote = function(s)
+    return (s:gsub("[\\\"]", "\\%0"))
+  end
+
+  local params = {}
+  if realm and realm ~= "" then
+    table.insert(params, 'realm="' .. quote(realm) .. '"')
+  end
+  if err then
+    table.insert(params, 'error="invalid_token"')
+    table.insert(params, 'error_description="' .. quote(err) .. '"')
+  end
+
+  if #params == 0 then
+    return "Bearer"
+  end
+
+  return "Bearer " .. table.concat(params, ", ")
+end
+
+-- returns the decoded configuration of a location
+local function get_configuration(encoded)
+  local cfg = configurations[encoded]
+  if cfg then
+    return cfg
+  end
+
+  local decoded = ngx.decode_base64(encoded)
+  if not decoded then
+    return nil
+  end
+
+  local ok, result = pcall(json.decode, decoded)
+  if not ok or type(result) ~= "table" then
+    return nil
+  end
+
+  configurations[encoded] = result
+  return result
+end
+
+-- rejects the requests without a valid JWT bearer token with the status
+-- code 401. The claims of valid tokens are sent to the backend in the
+-- headers of the configuration, replacing the headers sent by the client.
+-- The configuration of the location is a base64 encoded JSON object
+function _M.validate(encoded)
+  local cfg = get_configuration(encoded)
+  if not cfg then
+    ngx.log(ngx.ERR, "invalid JWT configuration")
+    return ngx.exit(ngx.HTTP_INTERNAL_SERVER_ERROR)
+  end
+
+  local set = get_keys(cfg.keys)
+  if not set or #set == 0 then
+    ngx.log(ngx.ERR, "there are no JWT keys for ", cfg.keys)
+    return ngx.exit(ngx.HTTP_INTERNAL_SERVER_ERROR)
+  end
+
+  local claims, err
+  local token = bearer_token(ngx.var.http_authorization)
+  if token then
+    claims, err = validate_token(token, cfg, set)
+  end
+
+  if not claims then
+    if err then
+      ngx.log(ngx.INFO, "rejecting JWT bearer token: ", err)
+    end
+    ngx.header["WWW-Authenticate"] = challenge(cfg.realm, err)
+    return ngx.exit(ngx.HTTP_UNAUTHORIZED)
+  end
+
+  for _, ch in ipairs(cfg.claimHeaders or {}) do
+    ngx.req.clear_header(ch.header)
+    local value = claim_value(claims[ch.claim])
+    if value then
+      ngx.req.set_header(ch.header, value)
+    end
+  end
+end
+
+-- returns the value of a claim of the bearer token of an Authorization
+-- header or an empty string. The signature of the token is NOT verified:
+-- the value can only be used when the access is not granted based on it,
+-- i.e. as key of rate limits
+function _M.claim(authorization, name)
+  local token = bearer_token(authorization)
+  if not token then
+    return ""
+  end
+
+  local payload = token:match("^[^.]+%.([^.]+)%.[^.]*$")
+  if not payload then
+    return ""
+  end
+
+  local claims = decode_object(payload)
+  if not claims then
+    return ""
+  end
+
+  local value = claims[name]
+  if type(value) == "string" or type(value) == "number" then
+    return tostring(value)
+  end
+
+  return ""
+end
+
+return _M
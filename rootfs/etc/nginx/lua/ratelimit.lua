@@ -0,0 +1,60 @@
+local json = require("cjson")
+
+local stats = ngx.shared.ratelimit_stats
+
+local _M = {}
+
+local REJECTED = "rejected"
+local DRY_RUN = "dryRun"
+
+-- counts a request over the limits of a zone. The keys of the dictionary
+-- are <zone>|<rejected or dryRun>
+local function count(zone, mode)
+  local _, err = stats:incr(zone .. "|" .. mode, 1, 0)
+  if err then
+    ngx.log(ngx.ERR, "error counting limited request of zone ", zone, ": ", err)
+  end
+end
+
+-- returns the response to a request rejected by the limits of a zone. The
+-- body is base64 encoded and an empty body uses the nginx error page
+function _M.reject(zone, status, body)
+  count(zone, REJECTED)
+
+  body = ngx.decode_base64(body)
+  if not body or body == "" then
+    return ngx.exit(status)
+  end
+
+  ngx.status = status
+  ngx.print(body)
+  return ngx.exit(ngx.HTTP_OK)
+end
+
+-- logs and counts a mirror of a request that would be rejected by the
+-- limits of a zone in dry-run mode
+function _M.dry_run(zone)
+  count(zone, DRY_RUN)
+  ngx.log(ngx.WARN, "rate limit dry-run: request ", ngx.var.request_uri,
+    " from ", ngx.var.remote_addr, " would be rejected by zone ", zone)
+
+  return ngx.exit(ngx.HTTP_NO_CONTENT)
+end
+
+-- returns the number of limited requests of each zone in JSON format:
+-- { "<zone>": { "rejected": <count>, "dryRun": <count> } }
+function _M.status()
+  local zones = {}
+  for _, key in ipairs(stats:get_keys(0)) do
+    local zone, mode = key:match("^(.+)|([^|]+)$")
+    if zone then
+      zones[zone] = zones[zone] or {}
+      zones[zone][mode] = stats:get(key) or 0
+    end
+  end
+
+  ngx.header.content_type = "application/json"
+  ngx.print(json.encode(zones))
+end
+
+return _M
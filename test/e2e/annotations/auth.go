@@ -245,6 +245,97 @@ var _ = framework.IngressNginxDescribe("Annotations - Alias", func() {
 		Expect(len(errs)).Should(BeNumerically("==", 0))
 		Expect(resp.StatusCode).Should(Equal(http.StatusInternalServerError))
 	})
+
+	It("should return status code 500 when authentication is configured with an unsupported password hash and Authorization header is sent", func() {
+		host := "auth"
+
+		s, err := f.EnsureSecret(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: f.Namespace.Name,
+				},
+				Data: map[string][]byte{
+					// unsalted SHA-1 hash of the password bar
+					"auth": []byte("foo:{SHA}Ys23Ag/5IOWqZCw9QGaVDdHwH00="),
+				},
+				Type: corev1.SecretTypeOpaque,
+			},
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s).NotTo(BeNil())
+		Expect(s.ObjectMeta).NotTo(BeNil())
+
+		bi := buildIngress(host, f.Namespace.Name)
+		bi.Annotations["nginx.ingress.kubernetes.io/auth-type"] = "basic"
+		bi.Annotations["nginx.ingress.kubernetes.io/auth-secret"] = s.Name
+		bi.Annotations["nginx.ingress.kubernetes.io/auth-realm"] = "test auth"
+
+		ing, err := f.EnsureIngress(bi)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ing).NotTo(BeNil())
+
+		err = f.WaitForNginxServer(host,
+			func(server string) bool {
+				return Expect(server).Should(ContainSubstring("server_name auth")) &&
+					Expect(server).ShouldNot(ContainSubstring("return 503"))
+			})
+		Expect(err).NotTo(HaveOccurred())
+
+		resp, _, errs := gorequest.New().
+			Get(f.NginxHTTPURL).
+			Set("Host", host).
+			SetBasicAuth("foo", "bar").
+			End()
+
+		Expect(len(errs)).Should(BeNumerically("==", 0))
+		Expect(resp.StatusCode).Should(Equal(http.StatusInternalServerError))
+	})
+
+	It("should return status code 503 when authentication is configured with a secret without users", func() {
+		host := "auth"
+
+		s, err := f.EnsureSecret(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: f.Namespace.Name,
+				},
+				Data: map[string][]byte{
+					"auth": []byte("# no users\ninvalid\n"),
+				},
+				Type: corev1.SecretTypeOpaque,
+			},
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s).NotTo(BeNil())
+		Expect(s.ObjectMeta).NotTo(BeNil())
+
+		bi := buildIngress(host, f.Namespace.Name)
+		bi.Annotations["nginx.ingress.kubernetes.io/auth-type"] = "basic"
+		bi.Annotations["nginx.ingress.kubernetes.io/auth-secret"] = s.Name
+		bi.Annotations["nginx.ingress.kubernetes.io/auth-realm"] = "test auth"
+
+		ing, err := f.EnsureIngress(bi)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ing).NotTo(BeNil())
+
+		err = f.WaitForNginxServer(host,
+			func(server string) bool {
+				return Expect(server).Should(ContainSubstring("server_name auth")) &&
+					Expect(server).Should(ContainSubstring("return 503"))
+			})
+		Expect(err).NotTo(HaveOccurred())
+
+		resp, _, errs := gorequest.New().
+			Get(f.NginxHTTPURL).
+			Set("Host", host).
+			SetBasicAuth("foo", "bar").
+			End()
+
+		Expect(len(errs)).Should(BeNumerically("==", 0))
+		Expect(resp.StatusCode).Should(Equal(http.StatusServiceUnavailable))
+	})
 })
 
 // TODO: test Digest Auth